@@ -2,7 +2,9 @@ package ophis
 
 import (
 	"log/slog"
+	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/njayp/ophis/internal/bridge"
 	"github.com/njayp/ophis/tools"
@@ -27,9 +29,16 @@ type Config struct {
 	// RootCmd allows the user to decide which command should be the root command for generating tools.
 	// This allows the mcp command to be nested under another command while still generating tools from
 	// the entire tree. For example: "<command> alpha mcp" could still generate the tools from the root
-	// "<command>". If omitted, it will use mcp's parent command.
+	// "<command>". If omitted, it will use mcp's parent command. It can also be a subcommand, to only
+	// expose its subtree.
 	RootCmd *cobra.Command
 
+	// RootCmds, if set, are the commands whose trees are exposed as tools instead of the one of
+	// RootCmd, e.g. only the "db" and "cache" subtrees of the CLI. Tools are named relative to
+	// the command they were found under. RootCmd still names the server. Commands of different
+	// trees can only be run with tools.WithInProcessExecution.
+	RootCmds []*cobra.Command
+
 	// Generator controls how Cobra commands are converted to MCP tools.
 	// Optional: If nil, a default generator will be used that:
 	//   - Excludes hidden commands
@@ -43,8 +52,26 @@ type Config struct {
 	//   )
 	Generator *tools.Generator
 
+	// GeneratorOptions configure the default generator, which also logs to Logger. They are
+	// applied before the tool settings of the --config file of the start command.
+	// Optional: They are ignored if Generator is set.
+	//
+	// Example:
+	//   config.GeneratorOptions = []tools.GeneratorOption{tools.WithTimeout(time.Minute)}
+	GeneratorOptions []tools.GeneratorOption
+
+	// Logger receives the logs of the MCP server and of the default generator's tools.
+	// Optional: If nil, a text logger writing to stderr with SloggerOptions is used.
+	// The global slog logger is never replaced. A custom Generator logs nothing unless it
+	// was created with tools.WithLogger.
+	//
+	// Example:
+	//   config.Logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	Logger *slog.Logger
+
 	// SloggerOptions configures the structured logger used by the MCP server.
 	// Optional: If nil, default options will be used.
+	// It is ignored if Logger is set.
 	// The logger always writes to stderr to avoid interfering with stdio transport.
 	//
 	// Example:
@@ -53,19 +80,92 @@ type Config struct {
 	//   }
 	SloggerOptions *slog.HandlerOptions
 
+	// ClientLogging sends the logs of tool calls to the MCP client as logging notifications,
+	// in addition to Logger, so that users can see them in the client, e.g. why a command
+	// failed. Clients change the minimum level with logging/setLevel while connected.
+	// The logs of a custom Generator are not sent, and server.WithHooks in ServerOptions must
+	// not be used, since it replaces the hook that applies ClientLogLevel.
+	ClientLogging bool
+
+	// ClientLogLevel is the minimum level of the logs sent to clients until they choose one
+	// with logging/setLevel.
+	// Optional: If zero, info.
+	ClientLogLevel slog.Level
+
 	// ServerOptions provides additional options for the underlying MCP server.
 	// Optional: These are passed directly to the mark3labs/mcp-go server.
 	// The bridge always adds server.WithRecovery() to handle panics gracefully.
 	//
 	// Consult the mark3labs/mcp-go documentation for available server options.
 	ServerOptions []server.ServerOption
+
+	// CustomTools are hand-written tools served next to the generated ones, e.g. a tool
+	// returning in-process state. Use RegisterTool to add them. Starting the server fails if
+	// a name collides with another tool.
+	CustomTools []server.ServerTool
+
+	// ListCommandsTool adds a built-in tool named tools.ListCommandsToolName that returns the
+	// exposed commands with their short descriptions, flags, and whether they are read-only or
+	// destructive, so that agents can orient themselves in a large CLI before calling a tool.
+	// It lists the same commands as the generated tools. Starting the server fails if a custom
+	// tool has the same name.
+	ListCommandsTool bool
+
+	// ExamplePrompts turns the Example of every exposed command into an MCP prompt named after
+	// its tool, which asks the model to run the examples with the tool arguments pre-filled.
+	// Clients offer prompts in their prompt picker. Commands without an Example get no prompt.
+	ExamplePrompts bool
+
+	// Middleware wraps the handler of every tool, generated and custom, e.g. to add timing,
+	// authentication, rate limiting or retries. The first middleware is the outermost, so
+	// middlewares run in order. Each can modify the request, short-circuit by not calling
+	// the next handler, or wrap the result. The tool name is in request.Params.Name.
+	Middleware []server.ToolHandlerMiddleware
+
+	// SSEOptions provides additional options for the mark3labs/mcp-go SSE server used by
+	// "mcp start --transport sse", e.g. server.WithBaseURL when running behind a proxy.
+	// Optional: They are ignored by the stdio transport.
+	SSEOptions []server.SSEOption
+
+	// HTTPOptions provides additional options for the mark3labs/mcp-go streamable HTTP server
+	// used by "mcp start --transport http".
+	// Optional: They are ignored by the other transports.
+	HTTPOptions []server.StreamableHTTPOption
+
+	// DrainTimeout is how long the server waits for in-flight tool calls to finish on SIGINT or
+	// SIGTERM, before killing their commands and exiting with an error. New tool calls are
+	// rejected while draining. The --drain-timeout flag of the start command takes precedence.
+	// Optional: If zero, 20 seconds.
+	DrainTimeout time.Duration
 }
 
 func (c *Config) bridgeConfig(rootCmd *cobra.Command) *bridge.Config {
 	return &bridge.Config{
-		RootCmd:        rootCmd,
-		Generator:      c.Generator,
-		SloggerOptions: c.SloggerOptions,
-		ServerOptions:  c.ServerOptions,
+		RootCmd:          rootCmd,
+		RootCmds:         c.RootCmds,
+		Generator:        c.Generator,
+		GeneratorOptions: c.GeneratorOptions,
+		Logger:           c.Logger,
+		SloggerOptions:   c.SloggerOptions,
+		ClientLogging:    c.ClientLogging,
+		ClientLogLevel:   c.ClientLogLevel,
+		ServerOptions:    c.ServerOptions,
+		CustomTools:      c.CustomTools,
+		ListCommandsTool: c.ListCommandsTool,
+		ExamplePrompts:   c.ExamplePrompts,
+		Middleware:       c.Middleware,
+		DrainTimeout:     c.DrainTimeout,
 	}
 }
+
+// RegisterTool adds a hand-written tool that is not backed by a cobra command. It is listed
+// and dispatched like the generated tools.
+//
+// Example:
+//
+//	config.RegisterTool(mcp.NewTool("status"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+//		return mcp.NewToolResultText("ok"), nil
+//	})
+func (c *Config) RegisterTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	c.CustomTools = append(c.CustomTools, server.ServerTool{Tool: tool, Handler: handler})
+}
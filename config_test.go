@@ -1,8 +1,17 @@
 package ophis
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"log/slog"
+	"os"
+	"strings"
 	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/njayp/ophis/tools"
+	"github.com/spf13/cobra"
 )
 
 func TestParseLogLevel(t *testing.T) {
@@ -31,3 +40,75 @@ func TestParseLogLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestStartCommandTransport(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(Command(nil))
+	root.SetArgs([]string{"mcp", "start", "--transport", "websocket"})
+	root.SilenceUsage = true
+	root.SilenceErrors = true
+
+	err := root.Execute()
+	if err == nil || !strings.Contains(err.Error(), `unsupported transport "websocket"`) {
+		t.Errorf("Expected unsupported transport error, got %v", err)
+	}
+}
+
+func TestCommandNames(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(&cobra.Command{Use: "mcp", Run: func(*cobra.Command, []string) {}})
+	root.AddCommand(Command(nil, WithCommandName("serve-mcp", "smcp"), WithStartCommandName("run", "serve")))
+	root.SetArgs([]string{"smcp", "serve", "--transport", "websocket"})
+	root.SilenceUsage = true
+	root.SilenceErrors = true
+
+	err := root.Execute()
+	if err == nil || !strings.Contains(err.Error(), `unsupported transport "websocket"`) {
+		t.Errorf("Expected unsupported transport error, got %v", err)
+	}
+
+	for _, tool := range tools.NewGenerator().FromRootCmd(root) {
+		if strings.Contains(tool.Tool.Name, "serve-mcp") {
+			t.Errorf("Expected the MCP command not to be exposed, got tool %s", tool.Tool.Name)
+		}
+	}
+}
+
+func TestRegisterTool(t *testing.T) {
+	config := &Config{}
+	config.RegisterTool(mcp.NewTool("status"), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	custom := config.bridgeConfig(&cobra.Command{Use: "cli"}).CustomTools
+	if len(custom) != 1 || custom[0].Tool.Name != "status" || custom[0].Handler == nil {
+		t.Errorf("Expected the status tool to be passed to the bridge, got %+v", custom)
+	}
+}
+
+func TestToolCommandLazySchemas(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}}
+	get.Flags().String("output", "", "Output format")
+	root.AddCommand(get, Command(&Config{GeneratorOptions: []tools.GeneratorOption{tools.WithLazySchemas()}}))
+	root.SetArgs([]string{"mcp", "tools"})
+	root.SetOut(io.Discard)
+
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Expected tools to be exported, got %v", err)
+	}
+
+	data, err := os.ReadFile("mcp-tools.json")
+	if err != nil {
+		t.Fatalf("Expected mcp-tools.json, got %v", err)
+	}
+	var exported []mcp.Tool
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Expected exported tools, got %v", err)
+	}
+	if len(exported) != 1 || exported[0].InputSchema.Properties[tools.FlagsParam] == nil {
+		t.Errorf("Expected the full input schema of cli_get, got %s", data)
+	}
+}
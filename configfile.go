@@ -0,0 +1,232 @@
+package ophis
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/njayp/ophis/tools"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile holds the server options read from the --config file of the start command.
+// Its keys match the flags of the start command, which override them, followed by the tool
+// settings of the default generator.
+type configFile struct {
+	LogLevel     string   `json:"log-level" yaml:"log-level"`
+	Transport    string   `json:"transport" yaml:"transport"`
+	Addr         string   `json:"addr" yaml:"addr"`
+	Path         string   `json:"path" yaml:"path"`
+	DrainTimeout duration `json:"drain-timeout" yaml:"drain-timeout"`
+
+	// Timeout limits the run time of every command
+	Timeout duration `json:"timeout" yaml:"timeout"`
+	// MaxOutputBytes limits the output returned by every command
+	MaxOutputBytes int `json:"max-output-bytes" yaml:"max-output-bytes"`
+	// MaxConcurrent and MaxQueue limit the commands running at once, and those waiting to run
+	MaxConcurrent int `json:"max-concurrent" yaml:"max-concurrent"`
+	MaxQueue      int `json:"max-queue" yaml:"max-queue"`
+	// Allow only exposes the commands at or below these command paths
+	Allow []string `json:"allow" yaml:"allow"`
+	// Exclude hides the commands at or below these command paths
+	Exclude []string `json:"exclude" yaml:"exclude"`
+	// Env is the environment policy of every command
+	Env *envConfig `json:"env" yaml:"env"`
+	// Tools overrides settings for single tools, by command path
+	Tools map[string]toolConfig `json:"tools" yaml:"tools"`
+}
+
+// envConfig holds the environment policy of a config file.
+type envConfig struct {
+	Inherit     bool              `json:"inherit" yaml:"inherit"`
+	Passthrough []string          `json:"passthrough" yaml:"passthrough"`
+	Set         map[string]string `json:"set" yaml:"set"`
+}
+
+// toolConfig holds the settings of a config file for a single tool.
+type toolConfig struct {
+	// Env sets environment variables of the command
+	Env map[string]string `json:"env" yaml:"env"`
+	// Flags are injected into every call, see tools.WithInjectedFlags
+	Flags map[string]string `json:"flags" yaml:"flags"`
+	// Stream sends the output of the command while it runs, see tools.WithStreaming
+	Stream bool `json:"stream" yaml:"stream"`
+}
+
+// duration is a time.Duration written as a string, e.g. "30s".
+type duration time.Duration
+
+// UnmarshalText parses a non-negative duration.
+func (d *duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("negative duration %q", text)
+	}
+
+	*d = duration(parsed)
+	return nil
+}
+
+// loadConfigFile reads and validates a config file. Files ending in .json are read as JSON,
+// and other files as YAML. Unknown keys are rejected, so that typos do not go unnoticed.
+func loadConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	file := &configFile{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(file)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(file)
+	}
+	// An empty file sets nothing
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	if err := file.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// validate reports the values that the flags and options would reject or silently ignore.
+func (f *configFile) validate() error {
+	switch strings.ToLower(f.LogLevel) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log-level %q must be one of debug, info, warn, error", f.LogLevel)
+	}
+
+	switch f.Transport {
+	case "", TransportStdio, TransportSSE, TransportHTTP:
+	default:
+		return fmt.Errorf("transport %q must be one of %s, %s, %s", f.Transport, TransportStdio, TransportSSE, TransportHTTP)
+	}
+
+	for _, limit := range []struct {
+		key   string
+		value int
+	}{
+		{"max-output-bytes", f.MaxOutputBytes},
+		{"max-concurrent", f.MaxConcurrent},
+		{"max-queue", f.MaxQueue},
+	} {
+		if limit.value < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", limit.key, limit.value)
+		}
+	}
+
+	for path := range f.Tools {
+		if strings.TrimSpace(path) == "" {
+			return errors.New("tools must be keyed by command path, e.g. \"kubectl get\"")
+		}
+	}
+
+	return nil
+}
+
+// applyFlags sets the flags of the start command that were not set on the command line.
+func (f *configFile) applyFlags(flags *pflag.FlagSet) error {
+	values := map[string]string{
+		"log-level": f.LogLevel,
+		"transport": f.Transport,
+		"addr":      f.Addr,
+		"path":      f.Path,
+	}
+	if f.DrainTimeout != 0 {
+		values["drain-timeout"] = time.Duration(f.DrainTimeout).String()
+	}
+
+	for name, value := range values {
+		if value == "" || flags.Changed(name) {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s in config file: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// hasToolSettings reports whether the file configures the generator.
+func (f *configFile) hasToolSettings() bool {
+	return len(f.generatorOptions()) > 0
+}
+
+// generatorOptions returns the options of the default generator set by the file.
+func (f *configFile) generatorOptions() []tools.GeneratorOption {
+	var opts []tools.GeneratorOption
+	if f.Timeout != 0 {
+		opts = append(opts, tools.WithTimeout(time.Duration(f.Timeout)))
+	}
+	if f.MaxOutputBytes != 0 {
+		opts = append(opts, tools.WithMaxOutputBytes(f.MaxOutputBytes))
+	}
+	if f.MaxConcurrent != 0 {
+		opts = append(opts, tools.WithMaxConcurrent(f.MaxConcurrent))
+	}
+	if f.MaxQueue != 0 {
+		opts = append(opts, tools.WithMaxQueue(f.MaxQueue))
+	}
+	if len(f.Allow) > 0 {
+		opts = append(opts, tools.WithAllowedPaths(f.Allow...))
+	}
+	if len(f.Exclude) > 0 {
+		opts = append(opts, tools.AddFilter(tools.ExcludePaths(f.Exclude...)))
+	}
+
+	if env := f.Env; env != nil {
+		if env.Inherit {
+			opts = append(opts, tools.WithInheritedEnv())
+		}
+		if len(env.Passthrough) > 0 {
+			opts = append(opts, tools.WithEnvPassthrough(env.Passthrough...))
+		}
+		if len(env.Set) > 0 {
+			opts = append(opts, tools.WithEnv(nil, env.Set))
+		}
+	}
+
+	var streamed []string
+	for _, path := range slices.Sorted(maps.Keys(f.Tools)) {
+		tool := f.Tools[path]
+		// Selects exactly the command at path
+		selector := tools.Not(tools.ExcludePaths(path))
+		if len(tool.Env) > 0 {
+			opts = append(opts, tools.WithEnv(selector, tool.Env))
+		}
+		if len(tool.Flags) > 0 {
+			opts = append(opts, tools.WithInjectedFlags(selector, tool.Flags))
+		}
+		if tool.Stream {
+			streamed = append(streamed, path)
+		}
+	}
+	// WithStreaming takes a single selector
+	if len(streamed) > 0 {
+		opts = append(opts, tools.WithStreaming(tools.Not(tools.ExcludePaths(streamed...))))
+	}
+
+	return opts
+}
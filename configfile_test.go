@@ -0,0 +1,129 @@
+package ophis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/njayp/ophis/tools"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		file, err := loadConfigFile(writeConfigFile(t, "mcp.yaml", `
+transport: http
+drain-timeout: 45s
+timeout: 1m
+max-concurrent: 4
+exclude: ["cli delete"]
+env:
+  passthrough: [KUBECONFIG]
+tools:
+  cli get:
+    flags: {output: json}
+    stream: true
+`))
+		require.NoError(t, err)
+		assert.Equal(t, TransportHTTP, file.Transport)
+		assert.Equal(t, duration(45*time.Second), file.DrainTimeout)
+		assert.Equal(t, duration(time.Minute), file.Timeout)
+		assert.Equal(t, 4, file.MaxConcurrent)
+		assert.Equal(t, []string{"KUBECONFIG"}, file.Env.Passthrough)
+		assert.Equal(t, map[string]string{"output": "json"}, file.Tools["cli get"].Flags)
+		assert.True(t, file.Tools["cli get"].Stream)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		file, err := loadConfigFile(writeConfigFile(t, "mcp.json", `{"addr": ":9000", "max-queue": 8}`))
+		require.NoError(t, err)
+		assert.Equal(t, ":9000", file.Addr)
+		assert.Equal(t, 8, file.MaxQueue)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		file, err := loadConfigFile(writeConfigFile(t, "mcp.yaml", ""))
+		require.NoError(t, err)
+		assert.False(t, file.hasToolSettings())
+	})
+
+	for name, test := range map[string]struct {
+		file    string
+		content string
+		err     string
+	}{
+		"unknown yaml key":  {"mcp.yaml", "timout: 1m", "field timout not found"},
+		"unknown nested":    {"mcp.yaml", "tools:\n  cli get:\n    flag: {}", "field flag not found"},
+		"unknown json key":  {"mcp.json", `{"timout": "1m"}`, `unknown field "timout"`},
+		"invalid duration":  {"mcp.yaml", "timeout: soon", `invalid duration "soon"`},
+		"negative duration": {"mcp.yaml", "drain-timeout: -1s", "negative duration"},
+		"invalid transport": {"mcp.yaml", "transport: websocket", `transport "websocket" must be one of`},
+		"invalid log level": {"mcp.yaml", "log-level: loud", `log-level "loud" must be one of`},
+		"negative limit":    {"mcp.json", `{"max-concurrent": -1}`, "max-concurrent must not be negative"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t, test.file, test.content)
+			_, err := loadConfigFile(path)
+			require.Error(t, err)
+			assert.ErrorContains(t, err, "invalid config file "+path)
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := loadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.ErrorContains(t, err, "failed to read config file")
+	})
+}
+
+func TestConfigFileFlags(t *testing.T) {
+	file := &configFile{Transport: TransportHTTP, Addr: ":9000", DrainTimeout: duration(time.Minute)}
+	cmd := startCommand(nil)
+	require.NoError(t, cmd.Flags().Set("addr", ":7000"))
+
+	require.NoError(t, file.applyFlags(cmd.Flags()))
+	flags := cmd.Flags()
+	assert.Equal(t, TransportHTTP, flags.Lookup("transport").Value.String())
+	assert.Equal(t, ":7000", flags.Lookup("addr").Value.String(), "flags override the file")
+	assert.Equal(t, "1m0s", flags.Lookup("drain-timeout").Value.String())
+	assert.False(t, flags.Changed("path"), "unset values keep the flag default")
+}
+
+func TestConfigFileToolSettings(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(
+		&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "delete", Run: func(*cobra.Command, []string) {}},
+	)
+
+	file := &configFile{Exclude: []string{"cli delete"}, Timeout: duration(time.Minute)}
+	generated := tools.NewGenerator(file.generatorOptions()...).FromRootCmd(root)
+	require.Len(t, generated, 1)
+	assert.Equal(t, "cli_get", generated[0].Tool.Name)
+	assert.Equal(t, time.Minute, generated[0].Timeout)
+
+	t.Run("require the default generator", func(t *testing.T) {
+		path := writeConfigFile(t, "mcp.yaml", "timeout: 1m")
+		root := &cobra.Command{Use: "cli"}
+		root.AddCommand(Command(&Config{Generator: tools.NewGenerator()}))
+		root.SetArgs([]string{"mcp", "start", "--config", path})
+		root.SilenceUsage = true
+		root.SilenceErrors = true
+
+		err := root.Execute()
+		if err == nil || !strings.Contains(err.Error(), "use Config.GeneratorOptions instead of Config.Generator") {
+			t.Errorf("Expected generator error, got %v", err)
+		}
+	})
+}
@@ -28,11 +28,13 @@
 //	}
 //
 // This adds the following subcommands to your CLI:
-//   - mcp start: Start the MCP server
+//   - mcp start: Start the MCP server over stdio, or over HTTP with --transport http or sse
 //   - mcp tools: List available tools
 //   - mcp claude enable/disable/list: Manage Claude Desktop integration
 //   - mcp vscode enable/disable/list: Manage VSCode integration
 //
+// The mcp and start commands can be renamed with WithCommandName and WithStartCommandName.
+//
 // # Configuration
 //
 // You can customize the MCP server behavior using the Config struct:
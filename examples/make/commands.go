@@ -6,6 +6,7 @@ import (
 	"os/exec"
 
 	"github.com/njayp/ophis"
+	"github.com/njayp/ophis/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -17,7 +18,10 @@ func createMakeCommands() *cobra.Command {
 		Long:  `Execute make targets and build commands`,
 	}
 
-	mcpCmd := ophis.Command(nil)
+	// make is looked up on PATH, and commands otherwise start with an empty environment
+	mcpCmd := ophis.Command(&ophis.Config{
+		Generator: tools.NewGenerator(tools.WithEnvPassthrough("PATH", "HOME")),
+	})
 
 	// Add some common flags that make commands might use as persistent flags
 	// These will be available to all subcommands
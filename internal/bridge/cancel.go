@@ -0,0 +1,118 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// methodCancelled is the notification a client sends to cancel a request it made.
+const methodCancelled = "notifications/cancelled"
+
+// metaRequestID is the key of the request metadata in which the JSON-RPC id of a tool call is
+// passed from the server hooks to the tool handler, which is not given the id.
+const metaRequestID = "ophis/requestId"
+
+// canceller tracks the in-flight tool calls by client session and request id, so that a
+// cancellation notification of the client cancels the context of the matching call, which
+// terminates its command.
+type canceller struct {
+	mu    sync.Mutex
+	calls map[callKey]context.CancelFunc
+}
+
+// callKey identifies a request of a client session. Ids are compared by type and value, since
+// the requests 1 and "1" differ.
+type callKey struct {
+	session string
+	id      string
+}
+
+func newCanceller() *canceller {
+	return &canceller{calls: map[callKey]context.CancelFunc{}}
+}
+
+// addHooks adds the server hooks and the notification handler that track and cancel tool calls.
+// The server calls the hooks it was created with, so they can be added afterwards.
+func (c *canceller) addHooks(hooks *server.Hooks, s *server.MCPServer) {
+	hooks.AddBeforeCallTool(func(_ context.Context, id any, request *mcp.CallToolRequest) {
+		if id == nil {
+			return
+		}
+		if request.Params.Meta == nil {
+			request.Params.Meta = &mcp.Meta{}
+		}
+		if request.Params.Meta.AdditionalFields == nil {
+			request.Params.Meta.AdditionalFields = map[string]any{}
+		}
+		request.Params.Meta.AdditionalFields[metaRequestID] = id
+	})
+
+	s.AddNotificationHandler(methodCancelled, func(ctx context.Context, notification mcp.JSONRPCNotification) {
+		id, ok := notification.Params.AdditionalFields["requestId"]
+		if !ok || id == nil {
+			return
+		}
+		c.cancel(newCallKey(ctx, id))
+	})
+}
+
+// begin registers a tool call, returning its context and a function to call when it returns.
+// The id set by the hooks is removed from the request, so that tools do not see it. Calls
+// without an id, e.g. made without the hooks, cannot be cancelled by the client.
+func (c *canceller) begin(ctx context.Context, request *mcp.CallToolRequest) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if request.Params.Meta == nil {
+		return ctx, cancel
+	}
+
+	id, ok := request.Params.Meta.AdditionalFields[metaRequestID]
+	if !ok {
+		return ctx, cancel
+	}
+	fields := make(map[string]any, len(request.Params.Meta.AdditionalFields))
+	for key, value := range request.Params.Meta.AdditionalFields {
+		if key != metaRequestID {
+			fields[key] = value
+		}
+	}
+	meta := *request.Params.Meta
+	meta.AdditionalFields = fields
+	request.Params.Meta = &meta
+
+	key := newCallKey(ctx, id)
+	c.mu.Lock()
+	c.calls[key] = cancel
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel cancels the call of key, if it is still running.
+func (c *canceller) cancel(key callKey) {
+	c.mu.Lock()
+	cancel, ok := c.calls[key]
+	c.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// newCallKey returns the key of the request id of the client session of ctx.
+func newCallKey(ctx context.Context, id any) callKey {
+	key := callKey{id: fmt.Sprintf("%T:%v", id, id)}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		key.session = session.SessionID()
+	}
+
+	return key
+}
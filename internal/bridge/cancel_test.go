@@ -0,0 +1,75 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCancelledNotification tests that a cancellation notification cancels the context of the
+// tool call with the same request id, and no other
+func TestCancelledNotification(t *testing.T) {
+	started := make(chan *mcp.Meta, 2)
+	wait := server.ServerTool{
+		Tool: mcp.NewTool("wait"),
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			started <- request.Params.Meta
+			select {
+			case <-ctx.Done():
+				return mcp.NewToolResultError("cancelled"), nil
+			case <-time.After(200 * time.Millisecond):
+				return mcp.NewToolResultText("done"), nil
+			}
+		},
+	}
+
+	manager, err := NewManager(&Config{
+		RootCmd:     &cobra.Command{Use: "test"},
+		Logger:      slog.New(slog.DiscardHandler),
+		CustomTools: []server.ServerTool{wait},
+	})
+	require.NoError(t, err)
+
+	call := func(id string) <-chan mcp.CallToolResult {
+		results := make(chan mcp.CallToolResult, 1)
+		go func() {
+			message := `{"jsonrpc":"2.0","id":` + id + `,"method":"tools/call","params":{"name":"wait","_meta":{"trace":"x"}}}`
+			response := manager.server.HandleMessage(context.Background(), json.RawMessage(message))
+			resp, ok := response.(mcp.JSONRPCResponse)
+			if assert.True(t, ok, "unexpected response: %#v", response) {
+				results <- resp.Result.(mcp.CallToolResult)
+			}
+		}()
+		return results
+	}
+
+	cancelled, other := call("1"), call(`"1"`)
+	for range 2 {
+		meta := <-started
+		require.NotNil(t, meta)
+		assert.NotContains(t, meta.AdditionalFields, metaRequestID)
+		assert.Equal(t, "x", meta.AdditionalFields["trace"])
+	}
+
+	notification := `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1,"reason":"user abort"}}`
+	assert.Nil(t, manager.server.HandleMessage(context.Background(), json.RawMessage(notification)))
+
+	select {
+	case result := <-cancelled:
+		assert.True(t, result.IsError)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("the cancelled call is still running")
+	}
+	assert.False(t, (<-other).IsError)
+
+	// Cancelling a finished call is ignored
+	assert.Nil(t, manager.server.HandleMessage(context.Background(), json.RawMessage(notification)))
+}
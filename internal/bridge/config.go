@@ -3,6 +3,7 @@ package bridge
 import (
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/njayp/ophis/tools"
@@ -30,6 +31,12 @@ type Config struct {
 	// Only commands with Run or RunE functions will be converted to tools.
 	RootCmd *cobra.Command
 
+	// RootCmds, if set, are the commands whose trees are exposed as MCP tools instead of the
+	// one of RootCmd, which still names the server.
+	// Optional: Each of them can be the root of a CLI or one of its subcommands, of different
+	// trees only with tools.WithInProcessExecution.
+	RootCmds []*cobra.Command
+
 	// Generator controls how Cobra commands are converted to MCP tools.
 	// Optional: If nil, a default generator will be used that:
 	//   - Excludes hidden commands
@@ -43,8 +50,22 @@ type Config struct {
 	//   )
 	Generator *tools.Generator
 
+	// GeneratorOptions configure the default generator, after the logger.
+	// Optional: They are ignored if Generator is set.
+	GeneratorOptions []tools.GeneratorOption
+
+	// Logger receives the logs of the MCP server and of the default generator's tools.
+	// Optional: If nil, a text logger writing to stderr with SloggerOptions is used.
+	// The global slog logger is never replaced. A custom Generator logs nothing unless it
+	// was created with tools.WithLogger.
+	//
+	// Example:
+	//   config.Logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	Logger *slog.Logger
+
 	// SloggerOptions configures the structured logger used by the MCP server.
 	// Optional: If nil, default options will be used.
+	// It is ignored if Logger is set.
 	// The logger always writes to stderr to avoid interfering with stdio transport.
 	//
 	// Example:
@@ -53,35 +74,87 @@ type Config struct {
 	//   }
 	SloggerOptions *slog.HandlerOptions
 
+	// ClientLogging sends the logs of tool calls to the MCP client as logging notifications,
+	// in addition to Logger, so that they are visible in the client. Clients choose the
+	// minimum level with logging/setLevel at any time. The logs of a custom Generator are
+	// not sent. A server.WithHooks in ServerOptions replaces the hook that sets
+	// ClientLogLevel, so that clients receive errors only until they choose a level.
+	ClientLogging bool
+
+	// ClientLogLevel is the minimum level of the logs sent to clients that have not chosen one
+	// with logging/setLevel.
+	// Optional: If zero, info.
+	ClientLogLevel slog.Level
+
 	// ServerOptions provides additional options for the underlying MCP server.
 	// Optional: These are passed directly to the mark3labs/mcp-go server.
 	// The bridge always adds server.WithRecovery() to handle panics gracefully.
 	//
 	// Consult the mark3labs/mcp-go documentation for available server options.
 	ServerOptions []server.ServerOption
+
+	// CustomTools are hand-written tools served next to the generated ones, e.g. a tool
+	// returning in-process state. Their names must not collide with other tools.
+	CustomTools []server.ServerTool
+
+	// ListCommandsTool adds a read-only tool named tools.ListCommandsToolName that lists the
+	// generated tools with their short descriptions and flags, see tools.ListCommandsTool.
+	ListCommandsTool bool
+
+	// ExamplePrompts serves an MCP prompt for every generated tool whose command has an
+	// Example, see tools.ExamplePrompts.
+	ExamplePrompts bool
+
+	// Middleware wraps the handler of every tool, generated and custom, e.g. to add timing,
+	// authentication, rate limiting or retries. The first middleware is the outermost, so
+	// middlewares run in order. Each can modify the request, short-circuit by not calling
+	// the next handler, or wrap the result. The tool name is in request.Params.Name.
+	Middleware []server.ToolHandlerMiddleware
+
+	// DrainTimeout is how long a shutdown waits for in-flight tool calls to finish, before
+	// cancelling them and killing their commands.
+	// Optional: If zero, DefaultDrainTimeout is used.
+	DrainTimeout time.Duration
 }
 
-// Tools returns the list of MCP tools generated from the root command.
+// Tools returns the list of MCP tools generated from the root commands.
 //
 // If a custom Generator is configured, it uses that to convert commands.
 // Otherwise, it falls back to the default generator which:
 //   - Excludes hidden commands
 //   - Excludes "mcp", "help", and "completion" commands
 //   - Returns command output as plain text
-func (c *Config) Tools() []tools.Controller {
+//
+// It returns an error if the tools cannot be generated, e.g. because of a tool name collision.
+func (c *Config) Tools() ([]tools.Controller, error) {
+	return c.generate(c.logger())
+}
+
+// generate returns the tools generated from the root command, passing logger to the default
+// generator.
+func (c *Config) generate(logger *slog.Logger) ([]tools.Controller, error) {
+	roots := c.RootCmds
+	if len(roots) == 0 {
+		roots = []*cobra.Command{c.RootCmd}
+	}
+
 	if c.Generator != nil {
-		return c.Generator.FromRootCmd(c.RootCmd)
+		return c.Generator.GenerateFrom(roots...)
 	}
 
-	return tools.FromRootCmd(c.RootCmd)
+	opts := append([]tools.GeneratorOption{tools.WithLogger(logger)}, c.GeneratorOptions...)
+	return tools.NewGenerator(opts...).GenerateFrom(roots...)
 }
 
-// setupSlogger configures the structured logger for the MCP server.
+// logger returns the logger of the MCP server.
 //
-// The logger always writes to stderr to avoid interfering with the stdio
-// transport used for MCP communication. Writing logs to stdout would corrupt
-// the MCP protocol messages.
-func (c *Config) setupSlogger() {
-	handler := slog.NewTextHandler(os.Stderr, c.SloggerOptions)
-	slog.SetDefault(slog.New(handler))
+// Unless Logger is set, the logger writes to stderr to avoid interfering with
+// the stdio transport used for MCP communication. Writing logs to stdout would
+// corrupt the MCP protocol messages.
+func (c *Config) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+
+	return slog.New(slog.NewTextHandler(os.Stderr, c.SloggerOptions))
 }
@@ -1,12 +1,14 @@
 package bridge
 
 import (
+	"bytes"
 	"log/slog"
 	"testing"
 
 	"github.com/njayp/ophis/tools"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestConfigTools tests the Tools() method of Config
@@ -34,7 +36,8 @@ func TestConfigTools(t *testing.T) {
 			Generator: customGen,
 		}
 
-		tools := config.Tools()
+		tools, err := config.Tools()
+		require.NoError(t, err)
 		assert.Len(t, tools, 1)
 		assert.Equal(t, "test_sub", tools[0].Tool.Name)
 	})
@@ -45,10 +48,23 @@ func TestConfigTools(t *testing.T) {
 			// Generator is nil, should use default
 		}
 
-		tools := config.Tools()
+		tools, err := config.Tools()
+		require.NoError(t, err)
 		assert.Len(t, tools, 1)
 		assert.Equal(t, "test_sub", tools[0].Tool.Name)
 	})
+
+	t.Run("with root commands", func(t *testing.T) {
+		config := &Config{
+			RootCmd:  rootCmd,
+			RootCmds: []*cobra.Command{subCmd},
+		}
+
+		tools, err := config.Tools()
+		require.NoError(t, err)
+		assert.Len(t, tools, 1)
+		assert.Equal(t, "sub", tools[0].Tool.Name)
+	})
 }
 
 // TestConfigValidation tests various config validation scenarios
@@ -122,3 +138,20 @@ func TestConfigDefaults(t *testing.T) {
 	// We can't directly test this without accessing internal fields,
 	// but we can verify the manager was created successfully
 }
+
+// TestConfigLogger tests that a custom logger receives the server logs
+func TestConfigLogger(t *testing.T) {
+	var buf bytes.Buffer
+	root := &cobra.Command{Use: "test"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+	_, err := NewManager(&Config{
+		RootCmd: root,
+		Logger:  slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "creating MCP server")
+	assert.Contains(t, buf.String(), "tool generation completed")
+	assert.Contains(t, buf.String(), "registering MCP tool")
+}
@@ -0,0 +1,89 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultDrainTimeout is how long a shutdown waits for in-flight tool calls by default.
+const DefaultDrainTimeout = 20 * time.Second
+
+// killTimeout is how long a shutdown waits for tool calls to return after cancelling them.
+// Cancelled commands are terminated, and killed after the grace period of their generator.
+const killTimeout = 10 * time.Second
+
+// ErrForcedShutdown is returned by the Start methods of Manager when tool calls were still
+// running after the drain timeout and had to be cancelled, killing their commands.
+var ErrForcedShutdown = errors.New("forced shutdown: in-flight commands were killed")
+
+// errShuttingDown is reported to clients calling a tool during a shutdown.
+var errShuttingDown = errors.New("server is shutting down")
+
+// drainer tracks in-flight tool calls, so that a shutdown can wait for them.
+type drainer struct {
+	mu       sync.Mutex
+	draining bool
+	calls    sync.WaitGroup
+
+	// kill is cancelled to abort the calls that outlive the drain timeout
+	kill       context.Context
+	cancelKill context.CancelFunc
+}
+
+func newDrainer() *drainer {
+	kill, cancelKill := context.WithCancel(context.Background())
+	return &drainer{kill: kill, cancelKill: cancelKill}
+}
+
+// begin registers a tool call, returning its context and a function to call when it returns.
+// It fails once a shutdown has started.
+func (d *drainer) begin(ctx context.Context) (context.Context, func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return nil, nil, errShuttingDown
+	}
+	d.calls.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(d.kill, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		d.calls.Done()
+	}, nil
+}
+
+// drain rejects new tool calls and waits up to timeout for the in-flight ones to return.
+// Calls still running are then cancelled, and ErrForcedShutdown is returned.
+func (d *drainer) drain(timeout time.Duration) error {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.calls.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	}
+
+	d.cancelKill()
+	select {
+	case <-done:
+	case <-time.After(killTimeout):
+	}
+
+	return ErrForcedShutdown
+}
@@ -0,0 +1,64 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDrainer tests that a drain waits for in-flight calls, and cancels those that outlive it
+func TestDrainer(t *testing.T) {
+	t.Run("waits for calls", func(t *testing.T) {
+		d := newDrainer()
+		_, done, err := d.begin(context.Background())
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			done()
+		}()
+
+		assert.NoError(t, d.drain(5*time.Second))
+
+		_, _, err = d.begin(context.Background())
+		assert.ErrorIs(t, err, errShuttingDown)
+	})
+
+	t.Run("cancels calls after the timeout", func(t *testing.T) {
+		d := newDrainer()
+		ctx, done, err := d.begin(context.Background())
+		require.NoError(t, err)
+
+		go func() {
+			<-ctx.Done()
+			done()
+		}()
+
+		assert.ErrorIs(t, d.drain(50*time.Millisecond), ErrForcedShutdown)
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+
+	t.Run("no calls", func(t *testing.T) {
+		assert.NoError(t, newDrainer().drain(time.Millisecond))
+	})
+}
+
+// TestToolCallsRejectedWhileDraining tests that registered tools refuse calls once a shutdown started
+func TestToolCallsRejectedWhileDraining(t *testing.T) {
+	manager := newTestManager(t)
+	require.NoError(t, manager.shutdown())
+
+	message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"test_get"}}`
+	response := manager.server.HandleMessage(context.Background(), json.RawMessage(message))
+
+	resp, ok := response.(mcp.JSONRPCResponse)
+	require.True(t, ok, "unexpected response: %#v", response)
+	result := resp.Result.(mcp.CallToolResult)
+	assert.True(t, result.IsError)
+	assert.Equal(t, errShuttingDown.Error(), result.Content[0].(mcp.TextContent).Text)
+}
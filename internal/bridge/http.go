@@ -0,0 +1,122 @@
+package bridge
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ShutdownTimeout is how long the HTTP transports wait for open connections to close on
+// shutdown, after draining the in-flight tool calls.
+const ShutdownTimeout = 10 * time.Second
+
+// DefaultHTTPPath is the default endpoint of the streamable HTTP transport.
+const DefaultHTTPPath = "/mcp"
+
+// HTTPConfig configures the streamable HTTP transport.
+type HTTPConfig struct {
+	// Addr is the address to listen on, e.g. ":8080".
+	Addr string
+	// Path is the endpoint clients connect to. Defaults to DefaultHTTPPath.
+	Path string
+	// BearerToken, if set, must be sent by clients in an "Authorization: Bearer" header.
+	BearerToken string
+	// Options provides additional options for the mark3labs/mcp-go streamable HTTP server.
+	Options []server.StreamableHTTPOption
+}
+
+// StartSSEServer serves MCP over HTTP Server-Sent Events on addr, e.g. ":8080", so that
+// multiple clients can connect to a long-lived process. Clients connect to the "/sse" endpoint.
+//
+// This method blocks until ctx is cancelled, and then shuts the server down gracefully.
+func (b *Manager) StartSSEServer(ctx context.Context, addr string, opts ...server.SSEOption) error {
+	// Own the HTTP server, so that a shutdown before it starts listening still stops it
+	httpServer := &http.Server{Addr: addr}
+	sseServer := server.NewSSEServer(b.server, append(slices.Clone(opts), server.WithHTTPServer(httpServer))...)
+	httpServer.Handler = sseServer
+
+	b.logger.Info("serving MCP over SSE", "addr", addr)
+	return b.serveHTTP(ctx, httpServer, sseServer.Shutdown)
+}
+
+// StartHTTPServer serves MCP over the streamable HTTP transport, so that clients which no longer
+// support SSE can connect to a long-lived process.
+//
+// This method blocks until ctx is cancelled, and then shuts the server down gracefully.
+func (b *Manager) StartHTTPServer(ctx context.Context, config HTTPConfig) error {
+	path := config.Path
+	if path == "" {
+		path = DefaultHTTPPath
+	}
+
+	httpServer := &http.Server{Addr: config.Addr}
+	opts := append(slices.Clone(config.Options), server.WithEndpointPath(path), server.WithStreamableHTTPServer(httpServer))
+	httpMCPServer := server.NewStreamableHTTPServer(b.server, opts...)
+
+	mux := http.NewServeMux()
+	mux.Handle(path, requireBearerToken(config.BearerToken, httpMCPServer))
+	httpServer.Handler = mux
+
+	b.logger.Info("serving MCP over streamable HTTP", "addr", config.Addr, "path", path, "auth", config.BearerToken != "")
+	return b.serveHTTP(ctx, httpServer, httpMCPServer.Shutdown)
+}
+
+// serveHTTP runs httpServer until ctx is cancelled, drains the in-flight tool calls, and then
+// stops it with shutdown. Open connections get ShutdownTimeout to close before they are closed forcibly.
+func (b *Manager) serveHTTP(ctx context.Context, httpServer *http.Server, shutdown func(context.Context) error) error {
+	defer b.removeOutputs()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("HTTP server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	drainErr := b.shutdown()
+
+	b.logger.Info("shutting down HTTP server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	if err := shutdown(shutdownCtx); err != nil {
+		b.logger.Warn("forcing HTTP server to close", "error", err)
+		if err := httpServer.Close(); err != nil {
+			return errors.Join(drainErr, fmt.Errorf("failed to close HTTP server: %w", err))
+		}
+	}
+
+	if err := <-errs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Join(drainErr, fmt.Errorf("HTTP server failed: %w", err))
+	}
+
+	return drainErr
+}
+
+// requireBearerToken rejects requests without the bearer token, unless token is empty.
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,133 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestManager returns a Manager exposing a single "get" tool.
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	root := &cobra.Command{Use: "test"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+	manager, err := NewManager(&Config{RootCmd: root, Logger: slog.New(slog.DiscardHandler)})
+	require.NoError(t, err)
+	return manager
+}
+
+// freeAddr returns a local address with a port that is free to listen on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return addr
+}
+
+// waitForServer waits until a server accepts connections on addr.
+func waitForServer(t *testing.T, addr string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// TestStartSSEServer tests that clients can connect and that cancelling the context shuts the server down
+func TestStartSSEServer(t *testing.T) {
+	manager := newTestManager(t)
+	addr := freeAddr(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.StartSSEServer(ctx, addr)
+	}()
+
+	waitForServer(t, addr)
+	resp, err := http.Get("http://" + addr + "/sse")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: endpoint", strings.TrimSpace(line))
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(ShutdownTimeout):
+		t.Fatal("server did not shut down")
+	}
+	_ = resp.Body.Close()
+}
+
+// TestStartSSEServerInvalidAddr tests that a listen failure is returned
+func TestStartSSEServerInvalidAddr(t *testing.T) {
+	err := newTestManager(t).StartSSEServer(context.Background(), "invalid:address:here")
+	assert.Error(t, err)
+}
+
+// TestStartHTTPServer tests the streamable HTTP transport with bearer token auth
+func TestStartHTTPServer(t *testing.T) {
+	manager := newTestManager(t)
+	addr := freeAddr(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.StartHTTPServer(ctx, HTTPConfig{Addr: addr, Path: "/rpc", BearerToken: "s3cr3t"})
+	}()
+
+	post := func(token, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/rpc", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	waitForServer(t, addr)
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+	resp := post("", initialize)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, post("wrong", initialize).StatusCode)
+
+	resp = post("s3cr3t", initialize)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"name":"test"`)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(ShutdownTimeout):
+		t.Fatal("server did not shut down")
+	}
+}
@@ -0,0 +1,180 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// clientLogHandler is a slog.Handler that sends log records to the MCP client of the tool
+// call they belong to, as logging notifications. The client is taken from the context of
+// the record, so only records logged with a context of a tool call, e.g. with
+// slog.Logger.WarnContext, are sent. Records below the level of the client session are
+// dropped, see addClientLogHooks.
+type clientLogHandler struct {
+	server *server.MCPServer // sends the notifications
+	name   string            // logger name of the notifications, e.g. the name of the application
+	attrs  []slog.Attr       // attributes added with WithAttrs, with group-qualified keys
+	prefix string            // qualifies the keys of attributes added after WithGroup, e.g. "group."
+}
+
+func newClientLogHandler(srv *server.MCPServer, name string) *clientLogHandler {
+	return &clientLogHandler{server: srv, name: name}
+}
+
+// Enabled reports whether the client of ctx wants records of the level.
+func (h *clientLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithLogging)
+	return ok && session.Initialized() && mcpLevel(level).ShouldSendTo(session.GetLogLevel())
+}
+
+// Handle sends the record to the client of ctx. Records that cannot be sent are dropped,
+// since logging the failure would log to the client again.
+func (h *clientLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	data := make(map[string]any, len(h.attrs)+record.NumAttrs()+1)
+	data["message"] = record.Message
+	for _, attr := range h.attrs {
+		addAttr(data, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(data, h.prefix, attr)
+		return true
+	})
+
+	_ = h.server.SendLogMessageToClient(ctx, mcp.NewLoggingMessageNotification(mcpLevel(record.Level), h.name, data))
+	return nil
+}
+
+func (h *clientLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	clone.attrs = append(clone.attrs, h.attrs...)
+	for _, attr := range attrs {
+		clone.attrs = append(clone.attrs, slog.Attr{Key: h.prefix + attr.Key, Value: attr.Value})
+	}
+	return &clone
+}
+
+func (h *clientLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// addClientLogHooks adds server hooks that start the log level of every client session at
+// level. Sessions store the level, which logging/setLevel requests replace while the client is
+// connected; without the hooks, it is error until a client sets one.
+func addClientLogHooks(hooks *server.Hooks, level mcp.LoggingLevel) {
+	hooks.AddAfterInitialize(func(ctx context.Context, _ any, _ *mcp.InitializeRequest, _ *mcp.InitializeResult) {
+		if session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithLogging); ok {
+			session.SetLogLevel(level)
+		}
+	})
+}
+
+// addAttr adds attr to data, flattening groups into dot-separated keys. Values are converted
+// to ones that encode to JSON as they are printed by slog, e.g. errors to their message.
+func addAttr(data map[string]any, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if attr.Key == "" && value.Kind() != slog.KindGroup {
+		return
+	}
+
+	if value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, groupAttr := range value.Group() {
+			addAttr(data, groupPrefix, groupAttr)
+		}
+		return
+	}
+
+	key := prefix + attr.Key
+	switch value.Kind() {
+	case slog.KindDuration:
+		data[key] = value.Duration().String()
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			data[key] = err.Error()
+		} else {
+			data[key] = value.Any()
+		}
+	default:
+		data[key] = value.Any()
+	}
+}
+
+// mcpLevel maps a slog level to the MCP logging level of the same severity. Levels between
+// the standard slog levels, and above slog.LevelError, map to the additional MCP levels.
+func mcpLevel(level slog.Level) mcp.LoggingLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return mcp.LoggingLevelDebug
+	case level < slog.LevelInfo+2:
+		return mcp.LoggingLevelInfo
+	case level < slog.LevelWarn:
+		return mcp.LoggingLevelNotice
+	case level < slog.LevelError:
+		return mcp.LoggingLevelWarning
+	case level < slog.LevelError+4:
+		return mcp.LoggingLevelError
+	case level < slog.LevelError+8:
+		return mcp.LoggingLevelCritical
+	case level < slog.LevelError+12:
+		return mcp.LoggingLevelAlert
+	default:
+		return mcp.LoggingLevelEmergency
+	}
+}
+
+// teeHandler is a slog.Handler that passes records to several handlers.
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,141 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loggingSession is a client session that buffers the notifications sent to it.
+type loggingSession struct {
+	notifications chan mcp.JSONRPCNotification
+	level         mcp.LoggingLevel
+}
+
+func newLoggingSession() *loggingSession {
+	return &loggingSession{notifications: make(chan mcp.JSONRPCNotification, 100), level: mcp.LoggingLevelError}
+}
+
+func (s *loggingSession) Initialize()                                         {}
+func (s *loggingSession) Initialized() bool                                   { return true }
+func (s *loggingSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *loggingSession) SessionID() string                                   { return "test" }
+func (s *loggingSession) SetLogLevel(level mcp.LoggingLevel)                  { s.level = level }
+func (s *loggingSession) GetLogLevel() mcp.LoggingLevel                       { return s.level }
+
+// drain returns the params of the notifications received so far.
+func (s *loggingSession) drain() []map[string]any {
+	var received []map[string]any
+	for {
+		select {
+		case n := <-s.notifications:
+			received = append(received, n.Params.AdditionalFields)
+		default:
+			return received
+		}
+	}
+}
+
+// TestClientLogHandler tests that records of tool calls are sent to their client
+func TestClientLogHandler(t *testing.T) {
+	srv := server.NewMCPServer("test", "1.0.0", server.WithLogging())
+	session := newLoggingSession()
+	session.level = mcp.LoggingLevelWarning
+	ctx := srv.WithContext(context.Background(), session)
+
+	var stderr bytes.Buffer
+	logger := slog.New(teeHandler{slog.NewTextHandler(&stderr, nil), newClientLogHandler(srv, "cli")})
+
+	logger.InfoContext(ctx, "below the level of the client")
+	logger.Warn("without a tool call")
+	logger.With("tool", "cli_get").WithGroup("exec").WarnContext(ctx, "command not started",
+		"error", errors.New("boom"), "timeout", time.Second, slog.Group("limit", "max", 2))
+
+	assert.Equal(t, []map[string]any{{
+		"level":  mcp.LoggingLevelWarning,
+		"logger": "cli",
+		"data": map[string]any{
+			"message":        "command not started",
+			"tool":           "cli_get",
+			"exec.error":     "boom",
+			"exec.timeout":   "1s",
+			"exec.limit.max": int64(2),
+		},
+	}}, session.drain())
+	assert.Contains(t, stderr.String(), "below the level of the client")
+	assert.Contains(t, stderr.String(), "without a tool call")
+}
+
+// TestMCPLevel tests the mapping of slog levels to MCP logging levels
+func TestMCPLevel(t *testing.T) {
+	tests := map[slog.Level]mcp.LoggingLevel{
+		slog.LevelDebug - 4:  mcp.LoggingLevelDebug,
+		slog.LevelDebug:      mcp.LoggingLevelDebug,
+		slog.LevelInfo:       mcp.LoggingLevelInfo,
+		slog.LevelInfo + 2:   mcp.LoggingLevelNotice,
+		slog.LevelWarn:       mcp.LoggingLevelWarning,
+		slog.LevelError:      mcp.LoggingLevelError,
+		slog.LevelError + 4:  mcp.LoggingLevelCritical,
+		slog.LevelError + 8:  mcp.LoggingLevelAlert,
+		slog.LevelError + 12: mcp.LoggingLevelEmergency,
+	}
+
+	for level, expected := range tests {
+		assert.Equal(t, expected, mcpLevel(level), "level %s", level)
+	}
+}
+
+// TestClientLogging tests that the logs of tool calls reach the client at the level it chose
+func TestClientLogging(t *testing.T) {
+	root := &cobra.Command{Use: "test"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+	manager, err := NewManager(&Config{
+		RootCmd:        root,
+		Logger:         slog.New(slog.DiscardHandler),
+		ClientLogging:  true,
+		ClientLogLevel: slog.LevelWarn,
+	})
+	require.NoError(t, err)
+
+	session := newLoggingSession()
+	ctx := manager.server.WithContext(context.Background(), session)
+	handle := func(message string) mcp.JSONRPCMessage {
+		return manager.server.HandleMessage(ctx, json.RawMessage(message))
+	}
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"test_get","arguments":{"flags":{"bogus":true}}}}`
+
+	response := handle(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"client","version":"1.0.0"},"capabilities":{}}}`)
+	require.IsType(t, mcp.JSONRPCResponse{}, response)
+	assert.Equal(t, mcp.LoggingLevelWarning, session.GetLogLevel(), "sessions start at ClientLogLevel")
+
+	handle(call)
+	received := session.drain()
+	require.Len(t, received, 2, "the info log of the request is below the level")
+	assert.Equal(t, mcp.LoggingLevelWarning, received[0]["level"])
+	assert.Equal(t, "test", received[0]["logger"])
+	data := received[0]["data"].(map[string]any)
+	assert.Equal(t, "invalid tool arguments", data["message"])
+	assert.Equal(t, "test_get", data["tool"])
+	assert.Contains(t, data["error"], "unknown flag --bogus")
+	assert.Equal(t, mcp.LoggingLevelError, received[1]["level"])
+	assert.Equal(t, "command execution failed", received[1]["data"].(map[string]any)["message"])
+
+	response = handle(`{"jsonrpc":"2.0","id":1,"method":"logging/setLevel","params":{"level":"error"}}`)
+	require.IsType(t, mcp.JSONRPCResponse{}, response)
+
+	handle(call)
+	received = session.drain()
+	require.Len(t, received, 1)
+	assert.Equal(t, mcp.LoggingLevelError, received[0]["level"])
+}
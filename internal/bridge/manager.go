@@ -1,10 +1,17 @@
 package bridge
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"log/slog"
+	"os"
+	"slices"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/njayp/ophis/tools"
 )
 
 // Manager manages the bridge between a Cobra CLI application and an MCP server.
@@ -14,12 +21,22 @@ import (
 // direct struct initialization to ensure proper validation and setup.
 type Manager struct {
 	server *server.MCPServer // The underlying MCP server instance
+	logger *slog.Logger      // Logs tool registration and requests
+
+	drainer      *drainer                       // Tracks in-flight tool calls for shutdowns
+	canceller    *canceller                     // Cancels tool calls the client cancelled
+	middleware   []server.ToolHandlerMiddleware // Wraps the handler of every tool
+	drainTimeout time.Duration                  // How long a shutdown waits for in-flight tool calls
+	outputs      *tools.OutputResources         // Serves large tool outputs as resources, nil if none
+	definitions  toolDefinitions                // Generated tools by name, whose schemas are listed
 }
 
 // NewManager creates a new Manager instance from the provided configuration.
 // Returns an error if:
 //   - config is nil
 //   - config.RootCmd is nil
+//   - the tools cannot be generated
+//   - a custom tool is invalid, or its name is taken by another tool
 func NewManager(config *Config) (*Manager, error) {
 	if config == nil {
 		return nil, fmt.Errorf("configuration cannot be nil: must provide a Config struct with a RootCmd")
@@ -29,31 +46,152 @@ func NewManager(config *Config) (*Manager, error) {
 		return nil, fmt.Errorf("root command cannot be nil: Config.RootCmd is required to register tools")
 	}
 
-	config.setupSlogger()
-
+	logger := config.logger()
 	appName := config.RootCmd.Name()
 	version := config.RootCmd.Version
-	slog.Info("creating MCP server", "app_name", appName, "app_version", version)
+	logger.Info("creating MCP server", "app_name", appName, "app_version", version)
+
+	var serverOptions []server.ServerOption
+	hooks := &server.Hooks{}
+	if config.ClientLogging {
+		serverOptions = append(serverOptions, server.WithLogging())
+		addClientLogHooks(hooks, mcpLevel(config.ClientLogLevel))
+	}
+	// Hooks come first, since a server.WithHooks of the caller replaces them
+	serverOptions = append(serverOptions, server.WithHooks(hooks))
+	definitions := toolDefinitions{}
+	serverOptions = append(serverOptions, server.WithToolFilter(definitions.fill))
+	serverOptions = append(serverOptions, config.ServerOptions...)
 
 	server := server.NewMCPServer(
 		appName,
 		version,
-		config.ServerOptions...,
+		serverOptions...,
 	)
+	if config.ClientLogging {
+		logger = slog.New(teeHandler{logger.Handler(), newClientLogHandler(server, appName)})
+	}
+
+	generated, err := config.generate(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tools: %w", err)
+	}
+	outputs := outputResources(generated)
+	if outputs != nil {
+		addOutputHooks(hooks, outputs)
+	}
 
 	b := &Manager{
-		server: server,
+		server:       server,
+		logger:       logger,
+		drainer:      newDrainer(),
+		canceller:    newCanceller(),
+		drainTimeout: config.DrainTimeout,
+		middleware:   config.Middleware,
+		outputs:      outputs,
+		definitions:  definitions,
 	}
+	if b.drainTimeout <= 0 {
+		b.drainTimeout = DefaultDrainTimeout
+	}
+	b.canceller.addHooks(hooks, server)
 
-	b.registerTools(config.Tools())
+	customTools := config.CustomTools
+	if config.ListCommandsTool {
+		customTools = append(slices.Clone(customTools), tools.ListCommandsTool(generated))
+	}
+
+	if err := checkCustomTools(generated, customTools); err != nil {
+		return nil, fmt.Errorf("invalid custom tools: %w", err)
+	}
+
+	b.registerTools(generated)
+	b.registerCustomTools(customTools)
+	if config.ExamplePrompts {
+		b.registerPrompts(tools.ExamplePrompts(generated))
+	}
+	if outputs != nil {
+		b.server.AddResourceTemplates(outputs.ResourceTemplate())
+	}
 	return b, nil
 }
 
 // StartServer starts the MCP server using stdio transport.
 //
-// This method blocks until the server is shut down or encounters an error.
-// The server communicates over stdin/stdout, making it compatible with
+// This method blocks until stdin is closed, the server encounters an error, or ctx is
+// cancelled. The server communicates over stdin/stdout, making it compatible with
 // MCP clients like Claude Desktop.
-func (b *Manager) StartServer() error {
-	return server.ServeStdio(b.server)
+//
+// When ctx is cancelled, new tool calls are rejected and in-flight ones are drained, see shutdown.
+func (b *Manager) StartServer(ctx context.Context) error {
+	defer b.removeOutputs()
+	stdioServer := server.NewStdioServer(b.server)
+	stdioServer.SetErrorLogger(log.New(os.Stderr, "", log.LstdFlags))
+
+	// Tool calls inherit the listen context, so it is only cancelled after draining them
+	listenCtx, cancelListen := context.WithCancel(context.Background())
+	defer cancelListen()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- stdioServer.Listen(listenCtx, os.Stdin, os.Stdout)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	err := b.shutdown()
+	cancelListen()
+	if listenErr := <-errs; listenErr != nil && !errors.Is(listenErr, context.Canceled) {
+		err = errors.Join(err, listenErr)
+	}
+
+	return err
+}
+
+// shutdown rejects new tool calls and waits for the in-flight ones to return. Calls still
+// running after the drain timeout are cancelled, which terminates their commands, and
+// ErrForcedShutdown is returned.
+func (b *Manager) shutdown() error {
+	b.logger.Info("draining in-flight tool calls", "timeout", b.drainTimeout)
+	err := b.drainer.drain(b.drainTimeout)
+	if err != nil {
+		b.logger.Warn("in-flight tool calls did not finish in time and were cancelled", "timeout", b.drainTimeout)
+	}
+
+	return err
+}
+
+// outputResources returns the store of the outputs of the generated tools, or nil if they
+// inline their output. The tools of a Generator share one store.
+func outputResources(generated []tools.Controller) *tools.OutputResources {
+	for i := range generated {
+		if outputs := generated[i].OutputResources(); outputs != nil {
+			return outputs
+		}
+	}
+
+	return nil
+}
+
+// addOutputHooks adds server hooks that remove the stored outputs of every client session when
+// it ends. The server calls the hooks it was created with, so they can be added afterwards.
+func addOutputHooks(hooks *server.Hooks, outputs *tools.OutputResources) {
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		outputs.RemoveSession(session.SessionID())
+	})
+}
+
+// removeOutputs removes the stored outputs of the tools, once the server has stopped.
+func (b *Manager) removeOutputs() {
+	if b.outputs == nil {
+		return
+	}
+
+	if err := b.outputs.Close(); err != nil {
+		b.logger.Warn("failed to remove stored tool outputs", "error", err)
+	}
 }
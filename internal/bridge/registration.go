@@ -2,9 +2,12 @@ package bridge
 
 import (
 	"context"
-	"log/slog"
+	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/njayp/ophis/tools"
 )
 
@@ -16,10 +19,99 @@ func (b *Manager) registerTools(tools []tools.Controller) {
 }
 
 func (b *Manager) registerTool(ctrl tools.Controller) {
-	slog.Debug("registering MCP tool", "tool_name", ctrl.Tool.Name)
-	b.server.AddTool(ctrl.Tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		slog.Info("MCP tool request received", "tool_name", ctrl.Tool.Name, "arguments", request.Params.Arguments)
-		data, err := ctrl.Execute(ctx, request)
-		return ctrl.Handle(ctx, request, data, err)
+	b.definitions[ctrl.Tool.Name] = ctrl.Definition
+	b.addTool(ctrl.Tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		b.logger.InfoContext(ctx, "MCP tool request received", "tool_name", ctrl.Tool.Name, "arguments", ctrl.RedactedArguments(request))
+		result, err := ctrl.Execute(ctx, request)
+		return ctrl.Handle(ctx, request, result, err)
 	})
 }
+
+// registerCustomTools registers hand-written tools next to the generated ones.
+func (b *Manager) registerCustomTools(custom []server.ServerTool) {
+	for _, tool := range custom {
+		b.addTool(tool.Tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			// The arguments of custom tools are not logged, since sensitive ones cannot be told apart
+			b.logger.InfoContext(ctx, "MCP tool request received", "tool_name", tool.Tool.Name)
+			return tool.Handler(ctx, request)
+		})
+	}
+}
+
+// registerPrompts adds prompts to the server.
+func (b *Manager) registerPrompts(prompts []server.ServerPrompt) {
+	for _, prompt := range prompts {
+		b.logger.Debug("registering MCP prompt", "prompt_name", prompt.Prompt.Name)
+	}
+	if len(prompts) > 0 {
+		b.server.AddPrompts(prompts...)
+	}
+}
+
+// addTool adds a tool to the server, wrapped in the middleware. Calls are tracked, so that
+// shutdowns can drain them and clients can cancel them.
+func (b *Manager) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	b.logger.Debug("registering MCP tool", "tool_name", tool.Name)
+	for _, middleware := range slices.Backward(b.middleware) {
+		handler = middleware(handler)
+	}
+
+	b.server.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, done, err := b.drainer.begin(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		defer done()
+
+		ctx, end := b.canceller.begin(ctx, &request)
+		defer end()
+
+		return handler(ctx, request)
+	})
+}
+
+// checkCustomTools reports custom tools that are invalid, or whose names are taken by
+// generated tools or other custom tools.
+func checkCustomTools(generated []tools.Controller, custom []server.ServerTool) error {
+	owners := make(map[string]string, len(generated)+len(custom))
+	for _, ctrl := range generated {
+		owners[ctrl.Tool.Name] = "a generated tool"
+	}
+
+	var errs []error
+	for _, tool := range custom {
+		name := tool.Tool.Name
+		switch {
+		case name == "":
+			errs = append(errs, errors.New("custom tool without a name"))
+			continue
+		case tool.Handler == nil:
+			errs = append(errs, fmt.Errorf("custom tool %q has no handler", name))
+		}
+
+		if owner, taken := owners[name]; taken {
+			errs = append(errs, fmt.Errorf("%w: custom tool %q is also %s", tools.ErrToolNameCollision, name, owner))
+			continue
+		}
+		owners[name] = "a custom tool"
+	}
+
+	return errors.Join(errs...)
+}
+
+// toolDefinitions holds the definitions of the generated tools by name. Tools generated with
+// tools.WithLazySchemas are registered without their input schemas, which are built when the
+// tools are first listed.
+type toolDefinitions map[string]func() mcp.Tool
+
+// fill is a server.ToolFilterFunc that replaces the listed generated tools with their
+// definitions, including the input schemas.
+func (d toolDefinitions) fill(_ context.Context, listed []mcp.Tool) []mcp.Tool {
+	for i, tool := range listed {
+		if definition, ok := d[tool.Name]; ok {
+			listed[i] = definition()
+		}
+	}
+
+	return listed
+}
@@ -0,0 +1,226 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/njayp/ophis/tools"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCustomTools tests that hand-written tools are served next to the generated ones
+func TestCustomTools(t *testing.T) {
+	root := &cobra.Command{Use: "test"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+	status := server.ServerTool{
+		Tool: mcp.NewTool("status"),
+		Handler: func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		},
+	}
+
+	manager, err := NewManager(&Config{
+		RootCmd:     root,
+		Logger:      slog.New(slog.DiscardHandler),
+		CustomTools: []server.ServerTool{status},
+	})
+	require.NoError(t, err)
+
+	response := manager.server.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	resp, ok := response.(mcp.JSONRPCResponse)
+	require.True(t, ok, "unexpected response: %#v", response)
+
+	var listed []string
+	for _, tool := range resp.Result.(mcp.ListToolsResult).Tools {
+		listed = append(listed, tool.Name)
+	}
+	assert.ElementsMatch(t, []string{"test_get", "status"}, listed)
+
+	message := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"status"}}`
+	response = manager.server.HandleMessage(context.Background(), json.RawMessage(message))
+	resp, ok = response.(mcp.JSONRPCResponse)
+	require.True(t, ok, "unexpected response: %#v", response)
+	assert.Equal(t, "ok", resp.Result.(mcp.CallToolResult).Content[0].(mcp.TextContent).Text)
+}
+
+// TestListCommandsTool tests that the list commands tool is served if enabled, and collides with custom tools
+func TestListCommandsTool(t *testing.T) {
+	root := &cobra.Command{Use: "test"}
+	root.AddCommand(&cobra.Command{Use: "get", Short: "Get it", Run: func(*cobra.Command, []string) {}})
+
+	manager, err := NewManager(&Config{RootCmd: root, Logger: slog.New(slog.DiscardHandler), ListCommandsTool: true})
+	require.NoError(t, err)
+
+	message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_commands"}}`
+	response := manager.server.HandleMessage(context.Background(), json.RawMessage(message))
+	resp, ok := response.(mcp.JSONRPCResponse)
+	require.True(t, ok, "unexpected response: %#v", response)
+	assert.JSONEq(t, `{"commands":[{"tool":"test_get","command":"get","description":"Get it","readOnly":false,"destructive":true}]}`,
+		resp.Result.(mcp.CallToolResult).Content[0].(mcp.TextContent).Text)
+
+	handler := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) { return nil, nil }
+	_, err = NewManager(&Config{
+		RootCmd:          root,
+		Logger:           slog.New(slog.DiscardHandler),
+		ListCommandsTool: true,
+		CustomTools:      []server.ServerTool{{Tool: mcp.NewTool(tools.ListCommandsToolName), Handler: handler}},
+	})
+	assert.ErrorIs(t, err, tools.ErrToolNameCollision)
+}
+
+// TestExamplePrompts tests that the examples of commands are served as prompts if enabled
+func TestExamplePrompts(t *testing.T) {
+	root := &cobra.Command{Use: "test"}
+	root.AddCommand(&cobra.Command{Use: "get", Example: "test get pods", Run: func(*cobra.Command, []string) {}})
+
+	for _, enabled := range []bool{true, false} {
+		manager, err := NewManager(&Config{RootCmd: root, Logger: slog.New(slog.DiscardHandler), ExamplePrompts: enabled})
+		require.NoError(t, err)
+
+		response := manager.server.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"test_get"}}`))
+		if !enabled {
+			assert.IsType(t, mcp.JSONRPCError{}, response)
+			continue
+		}
+
+		resp, ok := response.(mcp.JSONRPCResponse)
+		require.True(t, ok, "unexpected response: %#v", response)
+		text := resp.Result.(mcp.GetPromptResult).Messages[0].Content.(mcp.TextContent).Text
+		assert.Contains(t, text, `{"args":["pods"]}`)
+	}
+}
+
+// TestOutputResources tests that the stored outputs of the tools are served as resources
+func TestOutputResources(t *testing.T) {
+	root := &cobra.Command{Use: "test"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+	for _, enabled := range []bool{true, false} {
+		config := &Config{RootCmd: root, Logger: slog.New(slog.DiscardHandler)}
+		if enabled {
+			config.Generator = tools.NewGenerator(tools.WithOutputResources(1024))
+		}
+		manager, err := NewManager(config)
+		require.NoError(t, err)
+		assert.Equal(t, enabled, manager.outputs != nil)
+
+		response := manager.server.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"resources/templates/list"}`))
+		if !enabled {
+			assert.IsType(t, mcp.JSONRPCError{}, response, "resources are not a capability")
+			continue
+		}
+
+		resp, ok := response.(mcp.JSONRPCResponse)
+		require.True(t, ok, "unexpected response: %#v", response)
+		templates := resp.Result.(mcp.ListResourceTemplatesResult).ResourceTemplates
+		require.Len(t, templates, 1)
+		assert.Equal(t, "ophis://output/{id}", templates[0].URITemplate.Raw())
+
+		response = manager.server.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"ophis://output/missing"}}`))
+		assert.IsType(t, mcp.JSONRPCError{}, response)
+	}
+}
+
+// TestLazySchemas tests that tools generated without their schemas are listed with them
+func TestLazySchemas(t *testing.T) {
+	root := &cobra.Command{Use: "test"}
+	get := &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}}
+	get.Flags().String("output", "", "Output format")
+	root.AddCommand(get)
+
+	manager, err := NewManager(&Config{RootCmd: root, Logger: slog.New(slog.DiscardHandler), Generator: tools.NewGenerator(tools.WithLazySchemas())})
+	require.NoError(t, err)
+
+	response := manager.server.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	resp, ok := response.(mcp.JSONRPCResponse)
+	require.True(t, ok, "unexpected response: %#v", response)
+	listed := resp.Result.(mcp.ListToolsResult).Tools
+	require.Len(t, listed, 1)
+	flags := listed[0].InputSchema.Properties[tools.FlagsParam].(map[string]any)
+	assert.Contains(t, flags["properties"], "output")
+}
+
+// TestCheckCustomTools tests that invalid and colliding custom tools are rejected
+func TestCheckCustomTools(t *testing.T) {
+	handler := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) { return nil, nil }
+	generated := []tools.Controller{{Tool: mcp.NewTool("cli_get")}}
+
+	assert.NoError(t, checkCustomTools(generated, []server.ServerTool{{Tool: mcp.NewTool("status"), Handler: handler}}))
+
+	err := checkCustomTools(generated, []server.ServerTool{{Tool: mcp.NewTool("cli_get"), Handler: handler}})
+	assert.ErrorIs(t, err, tools.ErrToolNameCollision)
+
+	err = checkCustomTools(nil, []server.ServerTool{
+		{Tool: mcp.NewTool("status"), Handler: handler},
+		{Tool: mcp.NewTool("status"), Handler: handler},
+	})
+	assert.ErrorIs(t, err, tools.ErrToolNameCollision)
+
+	err = checkCustomTools(nil, []server.ServerTool{{Tool: mcp.NewTool("status")}, {Handler: handler}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `custom tool "status" has no handler`)
+	assert.Contains(t, err.Error(), "custom tool without a name")
+}
+
+// TestMiddleware tests that middleware wraps every tool in order and can short-circuit
+func TestMiddleware(t *testing.T) {
+	var order []string
+	trace := func(name string) server.ToolHandlerMiddleware {
+		return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+			return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				order = append(order, name+":"+request.Params.Name)
+				return next(ctx, request)
+			}
+		}
+	}
+	deny := func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if request.Params.Name == "denied" {
+				return mcp.NewToolResultError("denied by middleware"), nil
+			}
+			request.Params.Arguments = map[string]any{"injected": true}
+			return next(ctx, request)
+		}
+	}
+
+	var arguments map[string]any
+	handler := func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		order = append(order, "handler")
+		arguments = request.GetArguments()
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	manager, err := NewManager(&Config{
+		RootCmd: &cobra.Command{Use: "test"},
+		Logger:  slog.New(slog.DiscardHandler),
+		CustomTools: []server.ServerTool{
+			{Tool: mcp.NewTool("allowed"), Handler: handler},
+			{Tool: mcp.NewTool("denied"), Handler: handler},
+		},
+		Middleware: []server.ToolHandlerMiddleware{trace("first"), trace("second"), deny},
+	})
+	require.NoError(t, err)
+
+	call := func(name string) mcp.CallToolResult {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `"}}`
+		response := manager.server.HandleMessage(context.Background(), json.RawMessage(message))
+		resp, ok := response.(mcp.JSONRPCResponse)
+		require.True(t, ok, "unexpected response: %#v", response)
+		return resp.Result.(mcp.CallToolResult)
+	}
+
+	assert.False(t, call("allowed").IsError)
+	assert.Equal(t, []string{"first:allowed", "second:allowed", "handler"}, order)
+	assert.Equal(t, map[string]any{"injected": true}, arguments)
+
+	order = nil
+	assert.True(t, call("denied").IsError)
+	assert.Equal(t, []string{"first:denied", "second:denied"}, order)
+}
@@ -77,7 +77,7 @@ func GetMCPCommandPath(cmd *cobra.Command) []string {
 	foundMCP := false
 	cur := cmd
 	for {
-		if cur.Name() == tools.MCPCommandName {
+		if isMCPCommand(cur) {
 			foundMCP = true
 		}
 		if foundMCP {
@@ -98,6 +98,28 @@ func GetMCPCommandPath(cmd *cobra.Command) []string {
 	return args[1:]
 }
 
+// GetStartCommandArgs constructs the arguments for starting the MCP server, i.e. the path of the MCP command
+// followed by the name of its start subcommand. For example `alpha mcp start`.
+func GetStartCommandArgs(cmd *cobra.Command) []string {
+	start := tools.StartCommandName
+	for cur := cmd; cur != nil; cur = cur.Parent() {
+		if name, ok := cur.Annotations[tools.AnnotationMCPCommand]; ok && name != "" {
+			start = name
+			break
+		}
+	}
+
+	return append(GetMCPCommandPath(cmd), start)
+}
+
+// isMCPCommand reports whether cmd is the MCP command, whatever it is named.
+func isMCPCommand(cmd *cobra.Command) bool {
+	if _, ok := cmd.Annotations[tools.AnnotationMCPCommand]; ok {
+		return true
+	}
+	return cmd.Name() == tools.MCPCommandName
+}
+
 // BackupConfigFile creates a backup of a configuration file.
 // If the file doesn't exist, it returns nil (no error).
 // The backup is created with a .backup extension.
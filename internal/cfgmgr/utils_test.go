@@ -177,6 +177,19 @@ func TestGetMCPCommandPath(t *testing.T) {
 	})
 }
 
+func TestGetStartCommandArgs(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		cmd := buildCommand("root", tools.MCPCommandName, "claude", "enable")
+		assert.Equal(t, []string{tools.MCPCommandName, tools.StartCommandName}, GetStartCommandArgs(cmd))
+	})
+	t.Run("Renamed", func(t *testing.T) {
+		cmd := buildCommand("root", "pre", "serve-mcp", "claude", "enable")
+		mcp := cmd.Parent().Parent()
+		mcp.Annotations = map[string]string{tools.AnnotationMCPCommand: "run"}
+		assert.Equal(t, []string{"pre", "serve-mcp", "run"}, GetStartCommandArgs(cmd))
+	})
+}
+
 func buildCommand(cmds ...string) *cobra.Command {
 	var parent *cobra.Command
 	for _, cmd := range cmds {
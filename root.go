@@ -7,16 +7,61 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// CommandOption configures the commands created by Command.
+type CommandOption func(*commandNames)
+
+// commandNames holds the names of the commands created by Command.
+type commandNames struct {
+	name         string
+	aliases      []string
+	start        string
+	startAliases []string
+}
+
+// WithCommandName returns a CommandOption that names the command added by Command, e.g.
+// "serve-mcp" for a CLI that already has an "mcp" command. It is "mcp" by default.
+func WithCommandName(name string, aliases ...string) CommandOption {
+	return func(n *commandNames) {
+		n.name = name
+		n.aliases = aliases
+	}
+}
+
+// WithStartCommandName returns a CommandOption that names the subcommand starting the server,
+// which is "start" by default.
+func WithStartCommandName(name string, aliases ...string) CommandOption {
+	return func(n *commandNames) {
+		n.start = name
+		n.startAliases = aliases
+	}
+}
+
 // Command creates a new Cobra command that starts an MCP server
 // This command can be added as a subcommand to any Cobra-based application
-func Command(config *Config) *cobra.Command {
+//
+// It is named "mcp", with a "start" subcommand, unless WithCommandName or WithStartCommandName
+// say otherwise. The command is never exposed as a tool, whatever its name, and the
+// configurations written by its enable subcommands run the server with the chosen names.
+func Command(config *Config, opts ...CommandOption) *cobra.Command {
+	names := commandNames{name: tools.MCPCommandName, start: tools.StartCommandName}
+	for _, opt := range opts {
+		opt(&names)
+	}
+
 	cmd := &cobra.Command{
-		Use:   tools.MCPCommandName,
-		Short: "MCP server management",
-		Long:  `Manage MCP servers for AI assistants and code editors`,
+		Use:     names.name,
+		Aliases: names.aliases,
+		Short:   "MCP server management",
+		Long:    `Manage MCP servers for AI assistants and code editors`,
+		// Marks the command for the tool filters and the enable subcommands
+		Annotations: map[string]string{tools.AnnotationMCPCommand: names.start},
 	}
 
+	start := startCommand(config)
+	start.Use = names.start
+	start.Aliases = names.startAliases
+
 	// Add subcommands
-	cmd.AddCommand(startCommand(config), toolCommand(config), claude.Command(), vscode.Command())
+	cmd.AddCommand(start, toolCommand(config), claude.Command(), vscode.Command())
 	return cmd
 }
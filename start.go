@@ -1,18 +1,44 @@
 package ophis
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/njayp/ophis/internal/bridge"
 	"github.com/njayp/ophis/tools"
 	"github.com/spf13/cobra"
 )
 
+// Transports supported by the start command.
+const (
+	// TransportStdio serves a single client over stdin and stdout. This is the default.
+	TransportStdio = "stdio"
+	// TransportSSE serves any number of clients over HTTP Server-Sent Events.
+	TransportSSE = "sse"
+	// TransportHTTP serves any number of clients over the streamable HTTP transport.
+	TransportHTTP = "http"
+)
+
+// BearerTokenEnv is the environment variable holding the bearer token of the http transport,
+// if the --bearer-token flag is not set. It keeps the token out of process listings.
+const BearerTokenEnv = "OPHIS_BEARER_TOKEN"
+
 // StartCommandFlags holds configuration flags for the start command.
 type StartCommandFlags struct {
-	LogLevel string
+	LogLevel     string
+	Transport    string
+	Addr         string
+	Path         string
+	BearerToken  string
+	DrainTimeout time.Duration
+	ConfigFile   string
 }
 
 // startCommand creates a Cobra command for starting the MCP server.
@@ -27,6 +53,30 @@ func startCommand(config *Config) *cobra.Command {
 				config = &Config{}
 			}
 
+			// The flags set on the command line override the file
+			if mcpFlags.ConfigFile != "" {
+				file, err := loadConfigFile(mcpFlags.ConfigFile)
+				if err != nil {
+					return err
+				}
+				if err := file.applyFlags(cmd.Flags()); err != nil {
+					return err
+				}
+				if file.hasToolSettings() {
+					if config.Generator != nil {
+						return fmt.Errorf("config file %s sets tool options, which only apply to the default generator: use Config.GeneratorOptions instead of Config.Generator", mcpFlags.ConfigFile)
+					}
+					config.GeneratorOptions = append(slices.Clip(config.GeneratorOptions), file.generatorOptions()...)
+				}
+			}
+
+			switch mcpFlags.Transport {
+			case TransportStdio, TransportSSE, TransportHTTP:
+			default:
+				return fmt.Errorf("unsupported transport %q: must be one of %s, %s, %s",
+					mcpFlags.Transport, TransportStdio, TransportSSE, TransportHTTP)
+			}
+
 			if mcpFlags.LogLevel != "" {
 				level := parseLogLevel(mcpFlags.LogLevel)
 				// Ensure SloggerOptions is initialized
@@ -37,26 +87,68 @@ func startCommand(config *Config) *cobra.Command {
 				config.SloggerOptions.Level = level
 			}
 
+			if cmd.Flags().Changed("drain-timeout") {
+				config.DrainTimeout = mcpFlags.DrainTimeout
+			}
+
 			rootCmd := cmd.Parent().Parent()
 			if config.RootCmd != nil {
 				rootCmd = config.RootCmd
 			}
 
 			// Create and start the bridge
-			bridge, err := bridge.NewManager(config.bridgeConfig(rootCmd))
+			manager, err := bridge.NewManager(config.bridgeConfig(rootCmd))
 			if err != nil {
 				return fmt.Errorf("failed to create MCP server bridge: %w", err)
 			}
-			return bridge.StartServer()
+
+			// The first signal starts a graceful shutdown, and restores the default handling
+			// so that a second one exits immediately
+			ctx, stop := signal.NotifyContext(contextOf(cmd), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			context.AfterFunc(ctx, stop)
+
+			switch mcpFlags.Transport {
+			case TransportStdio:
+				return manager.StartServer(ctx)
+			case TransportSSE:
+				return manager.StartSSEServer(ctx, mcpFlags.Addr, config.SSEOptions...)
+			}
+
+			token := mcpFlags.BearerToken
+			if token == "" {
+				token = os.Getenv(BearerTokenEnv)
+			}
+			return manager.StartHTTPServer(ctx, bridge.HTTPConfig{
+				Addr:        mcpFlags.Addr,
+				Path:        mcpFlags.Path,
+				BearerToken: token,
+				Options:     config.HTTPOptions,
+			})
 		},
 	}
 
 	// Add flags
 	flags := cmd.Flags()
 	flags.StringVar(&mcpFlags.LogLevel, "log-level", "", "Log level (debug, info, warn, error)")
+	flags.StringVar(&mcpFlags.Transport, "transport", TransportStdio, "Transport to serve MCP over (stdio, sse, http)")
+	flags.StringVar(&mcpFlags.Addr, "addr", ":8080", "Address to listen on with the sse and http transports")
+	flags.StringVar(&mcpFlags.Path, "path", bridge.DefaultHTTPPath, "Endpoint path of the http transport")
+	flags.StringVar(&mcpFlags.BearerToken, "bearer-token", "", "Bearer token required by the http transport (default $"+BearerTokenEnv+")")
+	flags.StringVar(&mcpFlags.ConfigFile, "config", "", "YAML or JSON file with server options, overridden by flags")
+	flags.DurationVar(&mcpFlags.DrainTimeout, "drain-timeout", bridge.DefaultDrainTimeout, "How long to wait for running commands on shutdown before killing them")
 	return cmd
 }
 
+// contextOf returns the context of a command, which is nil unless it was executed with one.
+func contextOf(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+
+	return context.Background()
+}
+
 // parseLogLevel converts a string log level to slog.Level.
 // Supported levels are: debug, info, warn, error (case-insensitive).
 // Defaults to info for unknown levels.
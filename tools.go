@@ -25,10 +25,18 @@ func toolCommand(config *Config) *cobra.Command {
 				rootCmd = config.RootCmd
 			}
 
-			tools := config.bridgeConfig(rootCmd).Tools()
-			mcpTools := make([]mcp.Tool, len(tools))
-			for i, tool := range tools {
-				mcpTools[i] = tool.Tool
+			tools, err := config.bridgeConfig(rootCmd).Tools()
+			if err != nil {
+				return fmt.Errorf("failed to generate tools: %w", err)
+			}
+
+			mcpTools := make([]mcp.Tool, 0, len(tools)+len(config.CustomTools))
+			for _, tool := range tools {
+				// Definition builds lazy input schemas
+				mcpTools = append(mcpTools, tool.Definition())
+			}
+			for _, tool := range config.CustomTools {
+				mcpTools = append(mcpTools, tool.Tool)
 			}
 
 			file, err := os.OpenFile("mcp-tools.json", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
@@ -48,7 +56,7 @@ func toolCommand(config *Config) *cobra.Command {
 				return fmt.Errorf("failed to encode MCP tools to JSON: %w", err)
 			}
 
-			cmd.Printf("Successfully exported %d tools to mcp-tools.json\n", len(tools))
+			cmd.Printf("Successfully exported %d tools to mcp-tools.json\n", len(mcpTools))
 			return nil
 		},
 	}
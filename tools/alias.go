@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// WithAliases returns a GeneratorOption that also exposes every alias of a command as a tool
+// (e.g. "cli_ls" for a "list" command with the alias "ls"). Alias tools run the canonical
+// command and share its input schema. They are only generated for commands that pass the
+// filters and allowed paths, and come after the canonical tool when resolving name collisions.
+func WithAliases() GeneratorOption {
+	return func(g *Generator) {
+		g.aliases = true
+	}
+}
+
+// aliasTools returns a tool for each alias of cmd, based on the tool of the canonical command.
+// parentPath holds the names of the ancestors of cmd, starting with the root command.
+func (g *Generator) aliasTools(cmd *cobra.Command, parentPath []string, tool Controller) []Controller {
+	// The root command is never invoked by name, so its aliases cannot be told apart
+	if parentPath == nil {
+		return nil
+	}
+
+	var aliases []Controller
+	for _, alias := range cmd.Aliases {
+		if alias == "" || alias == cmd.Name() {
+			continue
+		}
+
+		aliasTool := tool
+		aliasTool.Tool.Name = g.nameFunc(append(slices.Clone(parentPath), alias))
+		aliasTool.Tool.Description = fmt.Sprintf("Alias of %q.\n\n%s", cmd.CommandPath(), tool.Tool.Description)
+		aliasTool.Env = maps.Clone(tool.Env)
+		aliasTool.alias = true
+
+		g.logger.Debug("created alias tool", "tool_name", aliasTool.Tool.Name, "alias_of", tool.Tool.Name)
+		aliases = append(aliases, aliasTool)
+	}
+
+	return aliases
+}
+
+// aliasesLast moves alias tools after all canonical tools, keeping the order otherwise,
+// so that a canonical tool always wins a name collision with an alias.
+func aliasesLast(tools []Controller) []Controller {
+	slices.SortStableFunc(tools, func(a, b Controller) int {
+		switch {
+		case a.alias == b.alias:
+			return 0
+		case a.alias:
+			return 1
+		default:
+			return -1
+		}
+	})
+
+	return tools
+}
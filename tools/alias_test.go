@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithAliases tests that aliases become tools running the canonical command
+func TestWithAliases(t *testing.T) {
+	newTree := func() *cobra.Command {
+		run := func(_ *cobra.Command, _ []string) {}
+		root := &cobra.Command{Use: "cli", Aliases: []string{"c"}, Run: run}
+		list := &cobra.Command{Use: "list", Short: "List items", Aliases: []string{"ls", "l"}, Run: run}
+		list.Flags().String("output", "", "Output format")
+		debug := &cobra.Command{Use: "debug", Aliases: []string{"dbg"}, Hidden: true, Run: run}
+		root.AddCommand(list, debug)
+		return root
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"cli", "cli_list"}, toolNames(NewGenerator().FromRootCmd(newTree())))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		executor := &recordingExecutor{result: &ExecResult{}}
+		tools := NewGenerator(WithAliases(), WithExecutor(executor)).FromRootCmd(newTree())
+		assert.ElementsMatch(t, []string{"cli", "cli_list", "cli_ls", "cli_l"}, toolNames(tools))
+
+		byName := map[string]Controller{}
+		for _, tool := range tools {
+			byName[tool.Tool.Name] = tool
+		}
+
+		ls := byName["cli_ls"]
+		assert.Equal(t, "Alias of \"cli list\".\n\nList items", ls.Tool.Description)
+		assert.Equal(t, byName["cli_list"].Tool.InputSchema, ls.Tool.InputSchema)
+
+		_, err := ls.Execute(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list"}, executor.invocations[0].Args)
+	})
+
+	t.Run("respects filters", func(t *testing.T) {
+		tools := NewGenerator(WithAliases(), AddFilter(Exclude([]string{"list"}))).FromRootCmd(newTree())
+		assert.ElementsMatch(t, []string{"cli"}, toolNames(tools))
+
+		tools = NewGenerator(WithAliases(), WithAllowedPaths("cli list")).FromRootCmd(newTree())
+		assert.ElementsMatch(t, []string{"cli_list", "cli_ls", "cli_l"}, toolNames(tools))
+	})
+
+	t.Run("canonical tool wins collisions", func(t *testing.T) {
+		root := newTree()
+		root.AddCommand(&cobra.Command{Use: "ls", Short: "Real ls", Run: func(_ *cobra.Command, _ []string) {}})
+
+		tools := NewGenerator(WithAliases()).FromRootCmd(root)
+		for _, tool := range tools {
+			if tool.Tool.Name == "cli_ls" {
+				assert.Equal(t, "Real ls", tool.Tool.Description)
+			}
+		}
+	})
+}
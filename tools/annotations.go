@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"strconv"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+)
+
+// Cobra command annotations that set the MCP tool annotations of the generated tool.
+// Hints take a boolean value parsed by strconv.ParseBool, e.g.
+//
+//	cmd.Annotations = map[string]string{tools.AnnotationReadOnly: "true"}
+const (
+	AnnotationTitle       = "ophis.title"
+	AnnotationReadOnly    = "ophis.readOnlyHint"
+	AnnotationDestructive = "ophis.destructiveHint"
+	AnnotationIdempotent  = "ophis.idempotentHint"
+	AnnotationOpenWorld   = "ophis.openWorldHint"
+)
+
+// annotationOverride sets tool annotations for the tools matched by selector.
+type annotationOverride struct {
+	selector   Filter
+	annotation mcp.ToolAnnotation
+}
+
+// WithToolAnnotations returns a GeneratorOption that sets the MCP tool annotations of the tools
+// matched by selector, or of every tool if selector is nil. Only the title and the hints that are
+// set in annotation are applied. They take precedence over cobra command annotations, and later
+// calls win on conflicts.
+//
+// Clients use the hints to decide whether a tool call needs approval. Without them, tools are
+// marked as neither read-only nor idempotent, and as destructive. Marking a tool read-only also
+// marks it as not destructive, unless the destructive hint is set explicitly.
+//
+//	Example: NewGenerator(
+//		WithToolAnnotations(Allow([]string{"get", "list"}), mcp.ToolAnnotation{ReadOnlyHint: mcp.ToBoolPtr(true)}),
+//	)
+func WithToolAnnotations(selector Filter, annotation mcp.ToolAnnotation) GeneratorOption {
+	return func(g *Generator) {
+		g.annotations = append(g.annotations, annotationOverride{selector: selector, annotation: annotation})
+	}
+}
+
+// annotationToolOption returns a ToolOption that applies the annotations of a command.
+func (g *Generator) annotationToolOption(cmd *cobra.Command) mcp.ToolOption {
+	var annotation mcp.ToolAnnotation
+	annotation.Title = cmd.Annotations[AnnotationTitle]
+	for key, hint := range map[string]**bool{
+		AnnotationReadOnly:    &annotation.ReadOnlyHint,
+		AnnotationDestructive: &annotation.DestructiveHint,
+		AnnotationIdempotent:  &annotation.IdempotentHint,
+		AnnotationOpenWorld:   &annotation.OpenWorldHint,
+	} {
+		value, ok := cmd.Annotations[key]
+		if !ok {
+			continue
+		}
+
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			g.logger.Warn("ignoring invalid tool annotation", "command", cmd.CommandPath(), "annotation", key, "value", value)
+			continue
+		}
+		*hint = &parsed
+	}
+
+	for _, override := range g.annotations {
+		if override.selector == nil || override.selector(cmd) {
+			mergeAnnotation(&annotation, override.annotation)
+		}
+	}
+
+	return func(tool *mcp.Tool) {
+		mergeAnnotation(&tool.Annotations, annotation)
+		if annotation.ReadOnlyHint != nil && *annotation.ReadOnlyHint && annotation.DestructiveHint == nil {
+			tool.Annotations.DestructiveHint = mcp.ToBoolPtr(false)
+		}
+	}
+}
+
+// mergeAnnotation copies the title and the hints that are set in src to dst.
+func mergeAnnotation(dst *mcp.ToolAnnotation, src mcp.ToolAnnotation) {
+	if src.Title != "" {
+		dst.Title = src.Title
+	}
+	if src.ReadOnlyHint != nil {
+		dst.ReadOnlyHint = src.ReadOnlyHint
+	}
+	if src.DestructiveHint != nil {
+		dst.DestructiveHint = src.DestructiveHint
+	}
+	if src.IdempotentHint != nil {
+		dst.IdempotentHint = src.IdempotentHint
+	}
+	if src.OpenWorldHint != nil {
+		dst.OpenWorldHint = src.OpenWorldHint
+	}
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestToolAnnotations tests that hints come from cobra annotations and generator options
+func TestToolAnnotations(t *testing.T) {
+	run := func(*cobra.Command, []string) {}
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(
+		&cobra.Command{Use: "get", Run: run},
+		&cobra.Command{Use: "list", Run: run, Annotations: map[string]string{AnnotationDestructive: "true"}},
+		&cobra.Command{Use: "apply", Run: run, Annotations: map[string]string{
+			AnnotationTitle:      "Apply manifests",
+			AnnotationIdempotent: "true",
+			AnnotationOpenWorld:  "maybe",
+		}},
+		&cobra.Command{Use: "delete", Run: run},
+	)
+
+	tools := map[string]mcp.Tool{}
+	for _, ctrl := range NewGenerator(
+		WithToolAnnotations(Allow([]string{"get", "list"}), mcp.ToolAnnotation{ReadOnlyHint: mcp.ToBoolPtr(true)}),
+		WithToolAnnotations(Allow([]string{"get"}), mcp.ToolAnnotation{Title: "Get resources"}),
+	).FromRootCmd(root) {
+		tools[ctrl.Tool.Name] = ctrl.Tool
+	}
+	require.Len(t, tools, 4)
+
+	get := tools["cli_get"].Annotations
+	assert.Equal(t, "Get resources", get.Title)
+	assert.True(t, *get.ReadOnlyHint)
+	assert.False(t, *get.DestructiveHint, "read-only implies not destructive")
+
+	// An explicit destructive hint is kept
+	list := tools["cli_list"].Annotations
+	assert.True(t, *list.ReadOnlyHint)
+	assert.True(t, *list.DestructiveHint)
+
+	// Invalid values are ignored, leaving the defaults
+	apply := tools["cli_apply"].Annotations
+	assert.Equal(t, "Apply manifests", apply.Title)
+	assert.True(t, *apply.IdempotentHint)
+	assert.True(t, *apply.OpenWorldHint)
+	assert.False(t, *apply.ReadOnlyHint)
+
+	assert.Equal(t, mcp.NewTool("default").Annotations, tools["cli_delete"].Annotations)
+}
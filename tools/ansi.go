@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// ansiPattern matches ANSI/VT100 escape sequences: CSI sequences such as colors and cursor
+// movement, OSC sequences such as hyperlinks and window titles, and two-character escapes.
+var ansiPattern = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// PreserveANSI returns a GeneratorOption that keeps ANSI escape sequences in command output.
+//
+// By default escape sequences are stripped from stdout and stderr, because colors and cursor
+// movement are noise to an LLM, and commands run with the variables of DefaultMachineEnv, such
+// as NO_COLOR=1 and TERM=dumb, so that they avoid writing them at all. PreserveANSI disables
+// both, unless the variables are set with WithMachineEnv.
+func PreserveANSI() GeneratorOption {
+	return func(g *Generator) {
+		g.keepANSI = true
+	}
+}
+
+// stripANSI removes ANSI escape sequences from stdout and stderr.
+// Output that is not valid UTF-8 is binary data and is left untouched.
+func (r *ExecResult) stripANSI() {
+	r.Stdout = stripANSI(r.Stdout)
+	r.Stderr = stripANSI(r.Stderr)
+	if r.combined != nil {
+		r.combined = stripANSI(r.combined)
+	}
+}
+
+func stripANSI(data []byte) []byte {
+	if !utf8.Valid(data) {
+		return data
+	}
+
+	return ansiPattern.ReplaceAll(data, nil)
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStripANSI tests that escape sequences are removed from text output
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain text", "no escapes here", "no escapes here"},
+		{"colors", "\x1b[31mred\x1b[0m and \x1b[1;32mbold green\x1b[m", "red and bold green"},
+		{"256 colors", "\x1b[38;5;208morange\x1b[0m", "orange"},
+		{"cursor movement", "50%\x1b[2K\x1b[1G100%", "50%100%"},
+		{"private mode", "\x1b[?25lhidden cursor\x1b[?25h", "hidden cursor"},
+		{"hyperlink", "\x1b]8;;https://example.com\x07link\x1b]8;;\x07", "link"},
+		{"window title", "\x1b]0;title\x1b\\text", "text"},
+		{"two-character escape", "\x1bMreverse index", "reverse index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, string(stripANSI([]byte(tt.input))))
+		})
+	}
+
+	t.Run("binary data is untouched", func(t *testing.T) {
+		binary := []byte{0xff, 0x1b, '[', '3', '1', 'm'}
+		assert.Equal(t, binary, stripANSI(binary))
+	})
+}
+
+// TestExecuteANSI tests that escape sequences are stripped unless preserved
+func TestExecuteANSI(t *testing.T) {
+	ctrl := helperController(t, "color")
+
+	result, err := ctrl.Execute(context.Background(), helperRequest(""))
+	require.NoError(t, err)
+	assert.Equal(t, "error: failed\n", string(result.Stdout))
+	assert.Equal(t, "warning\n", string(result.Stderr))
+	// The streams are copied concurrently, so their order in the combined output may vary
+	assert.NotContains(t, string(result.Combined()), "\x1b")
+	assert.Len(t, result.Combined(), len("error: failed\nwarning\n"))
+
+	ctrl.keepANSI = true
+	result, err = ctrl.Execute(context.Background(), helperRequest(""))
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[1;31merror\x1b[0m: failed\n", string(result.Stdout))
+}
+
+// TestPreserveANSI tests that the option is applied to every generated tool
+func TestPreserveANSI(t *testing.T) {
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+
+	tools := NewGenerator().FromRootCmd(cmd)
+	require.Len(t, tools, 1)
+	assert.False(t, tools[0].keepANSI)
+	assert.Contains(t, tools[0].environ(), "NO_COLOR=1")
+
+	tools = NewGenerator(PreserveANSI()).FromRootCmd(cmd)
+	require.Len(t, tools, 1)
+	assert.True(t, tools[0].keepANSI)
+	assert.NotContains(t, tools[0].environ(), "NO_COLOR=1")
+}
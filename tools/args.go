@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxProbedArgs is the largest argument count tried when probing a command's Args validator.
+// A validator accepting this many arguments is treated as unbounded.
+const maxProbedArgs = 16
+
+// invalidArgProbe is a placeholder argument that no ValidArgs list is expected to contain.
+const invalidArgProbe = "\x00ophis-invalid-arg"
+
+// argsSpec describes the positional arguments a command accepts.
+type argsSpec struct {
+	min int // minimum number of arguments
+	max int // maximum number of arguments, or -1 if unbounded
+	// valid lists the accepted values when the command only allows ValidArgs
+	valid []string
+}
+
+// argsSpecFromCmd derives the positional argument constraints of a command.
+//
+// Cobra validators such as ExactArgs are opaque functions, so the accepted
+// argument counts are discovered by calling the validator with placeholder
+// arguments. It returns nil if the command accepts arbitrary arguments or the
+// constraints could not be determined.
+func argsSpecFromCmd(logger *slog.Logger, cmd *cobra.Command) *argsSpec {
+	validArgs := validArgsFromCmd(cmd)
+	if cmd.Args == nil {
+		// cobra.ArbitraryArgs is the default
+		return nil
+	}
+
+	placeholder := "arg"
+	if len(validArgs) > 0 {
+		placeholder = validArgs[0]
+	}
+
+	spec := &argsSpec{min: -1, max: -1}
+	for n := 0; n <= maxProbedArgs; n++ {
+		if !acceptsArgs(cmd, slices.Repeat([]string{placeholder}, n)) {
+			continue
+		}
+
+		if spec.min == -1 {
+			spec.min = n
+		}
+		spec.max = n
+	}
+
+	if spec.min == -1 {
+		logger.Debug("could not determine positional argument constraints", "command", cmd.CommandPath())
+		return nil
+	}
+	if spec.max == maxProbedArgs {
+		spec.max = -1
+	}
+
+	// Only enforce ValidArgs if the validator rejects values outside of it (e.g. cobra.OnlyValidArgs)
+	if len(validArgs) > 0 && spec.max != 0 {
+		n := max(spec.min, 1)
+		probe := slices.Repeat([]string{placeholder}, n)
+		probe[0] = invalidArgProbe
+		if !acceptsArgs(cmd, probe) {
+			spec.valid = validArgs
+		}
+	}
+
+	if spec.min == 0 && spec.max == -1 && spec.valid == nil {
+		return nil
+	}
+
+	return spec
+}
+
+// WithoutArgsValidation returns a GeneratorOption that stops the generated tools from calling the
+// Args validator of their command, e.g. cobra.ExactArgs or a custom one, with the positional
+// arguments of a call before running it. By default a call the validator rejects fails with its
+// error, rather than spawning a command that refuses the arguments. Use it for commands whose
+// validator has side effects, or depends on flag values, which are not parsed at that point.
+// The argument counts probed for the input schema are still enforced.
+func WithoutArgsValidation() GeneratorOption {
+	return func(g *Generator) {
+		g.skipArgsValidation = true
+	}
+}
+
+// argsValidator returns a function checking positional arguments with the Args validator of
+// the command, or nil if it has none or validation is disabled.
+func (g *Generator) argsValidator(cmd *cobra.Command) func(args []string) error {
+	if g.skipArgsValidation || cmd.Args == nil {
+		return nil
+	}
+
+	return func(args []string) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("the argument validator of the command panicked: %v", r)
+			}
+		}()
+
+		return cmd.Args(cmd, args)
+	}
+}
+
+// acceptsArgs reports whether the Args validator of the command accepts args.
+// A panicking validator is treated as rejecting the arguments.
+func acceptsArgs(cmd *cobra.Command, args []string) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+
+	return cmd.Args(cmd, args) == nil
+}
+
+// validArgsFromCmd returns the ValidArgs of a command without their completion descriptions.
+func validArgsFromCmd(cmd *cobra.Command) []string {
+	var valid []string
+	for _, arg := range cmd.ValidArgs {
+		// ValidArgs may be in the "value\tdescription" completion format
+		value, _, _ := strings.Cut(arg, "\t")
+		valid = append(valid, value)
+	}
+
+	return valid
+}
+
+// count returns a human readable summary of the accepted argument count.
+func (s *argsSpec) count() string {
+	switch {
+	case s.min == s.max:
+		return "exactly " + pluralArgs(s.min)
+	case s.max == -1:
+		return "at least " + pluralArgs(s.min)
+	case s.min == 0:
+		return "at most " + pluralArgs(s.max)
+	default:
+		return fmt.Sprintf("between %d and %s", s.min, pluralArgs(s.max))
+	}
+}
+
+// describe returns a human readable summary of the constraints.
+func (s *argsSpec) describe() string {
+	desc := "Expects " + s.count()
+	if len(s.valid) > 0 {
+		desc += "\nValid values: " + strings.Join(s.valid, ", ")
+	}
+
+	return desc
+}
+
+// validate checks parsed positional arguments against the constraints.
+func (s *argsSpec) validate(args []string) error {
+	if len(args) < s.min || (s.max != -1 && len(args) > s.max) {
+		return fmt.Errorf("expected %s, got %d", s.count(), len(args))
+	}
+
+	for _, arg := range args {
+		if len(s.valid) > 0 && !slices.Contains(s.valid, arg) {
+			return fmt.Errorf("invalid argument %q: must be one of %s", arg, strings.Join(s.valid, ", "))
+		}
+	}
+
+	return nil
+}
+
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "1 positional argument"
+	}
+
+	return fmt.Sprintf("%d positional arguments", n)
+}
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestArgsSpecFromCmd tests that cobra Args validators are probed into constraints
+func TestArgsSpecFromCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      cobra.PositionalArgs
+		validArgs []string
+		expected  *argsSpec
+		desc      string
+	}{
+		{"no validator", nil, nil, nil, ""},
+		{"arbitrary args", cobra.ArbitraryArgs, nil, nil, ""},
+		{"no args", cobra.NoArgs, nil, &argsSpec{min: 0, max: 0}, "Expects exactly 0 positional arguments"},
+		{"exact args", cobra.ExactArgs(2), nil, &argsSpec{min: 2, max: 2}, "Expects exactly 2 positional arguments"},
+		{"minimum args", cobra.MinimumNArgs(1), nil, &argsSpec{min: 1, max: -1}, "Expects at least 1 positional argument"},
+		{"maximum args", cobra.MaximumNArgs(3), nil, &argsSpec{min: 0, max: 3}, "Expects at most 3 positional arguments"},
+		{"range args", cobra.RangeArgs(1, 3), nil, &argsSpec{min: 1, max: 3}, "Expects between 1 and 3 positional arguments"},
+		{
+			"only valid args",
+			cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+			[]string{"pods\tPods", "services"},
+			&argsSpec{min: 1, max: 1, valid: []string{"pods", "services"}},
+			"Expects exactly 1 positional argument\nValid values: pods, services",
+		},
+		{
+			"valid args used only for completion",
+			cobra.ExactArgs(1),
+			[]string{"pods", "services"},
+			&argsSpec{min: 1, max: 1},
+			"Expects exactly 1 positional argument",
+		},
+		{
+			"panicking validator",
+			func(_ *cobra.Command, _ []string) error { panic("boom") },
+			nil,
+			nil,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test", Args: tt.args, ValidArgs: tt.validArgs}
+			spec := argsSpecFromCmd(discardLogger, cmd)
+			assert.Equal(t, tt.expected, spec)
+			if spec != nil {
+				assert.Equal(t, tt.desc, spec.describe())
+			}
+		})
+	}
+}
+
+// TestArgsSpecValidate tests positional argument validation
+func TestArgsSpecValidate(t *testing.T) {
+	spec := &argsSpec{min: 1, max: 2, valid: []string{"a", "b"}}
+
+	assert.NoError(t, spec.validate([]string{"a"}))
+	assert.NoError(t, spec.validate([]string{"a", "b"}))
+
+	err := spec.validate(nil)
+	require.Error(t, err)
+	assert.Equal(t, "expected between 1 and 2 positional arguments, got 0", err.Error())
+
+	err = spec.validate([]string{"a", "b", "a"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "got 3")
+
+	err = spec.validate([]string{"c"})
+	require.Error(t, err)
+	assert.Equal(t, `invalid argument "c": must be one of a, b`, err.Error())
+}
+
+// TestArgsValidator tests that the Args validator of a command checks the arguments of a call,
+// unless disabled
+func TestArgsValidator(t *testing.T) {
+	calls := 0
+	newRoot := func() *cobra.Command {
+		root := &cobra.Command{Use: "cli"}
+		root.AddCommand(&cobra.Command{
+			Use: "tag",
+			Args: func(_ *cobra.Command, args []string) error {
+				calls++
+				for _, arg := range args {
+					if arg != strings.ToLower(arg) {
+						return fmt.Errorf("tag %q must be lowercase", arg)
+					}
+				}
+				return nil
+			},
+			Run: func(*cobra.Command, []string) {},
+		})
+		return root
+	}
+	request := func(args ...any) mcp.CallToolRequest {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = map[string]any{PositionalArgsParam: args}
+		return request
+	}
+
+	tools := NewGenerator().FromRootCmd(newRoot())
+	require.Len(t, tools, 1)
+
+	args, err := tools[0].BuildArgs(request("app"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tag", "--", "app"}, args)
+
+	_, err = tools[0].BuildArgs(request("app", "App"))
+	assert.ErrorIs(t, err, ErrInvalidArguments)
+	assert.ErrorContains(t, err, `tag "App" must be lowercase`)
+
+	tools = NewGenerator(WithoutArgsValidation()).FromRootCmd(newRoot())
+	calls = 0
+	_, err = tools[0].BuildArgs(request("App"))
+	assert.NoError(t, err)
+	assert.Zero(t, calls)
+}
+
+// TestArgsSchema tests that argument constraints reach the generated tool
+func TestArgsSchema(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{
+		Use:       "get RESOURCE",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"pods", "services"},
+		Run:       func(_ *cobra.Command, _ []string) {},
+	}
+	root.AddCommand(get)
+
+	tools := NewGenerator().FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	prop, ok := tools[0].Tool.InputSchema.Properties[PositionalArgsParam].(map[string]any)
+	require.True(t, ok)
+	assert.Contains(t, prop["description"], "Expects exactly 1 positional argument")
+	modes, ok := prop["anyOf"].([]any)
+	require.True(t, ok)
+	require.Len(t, modes, 2)
+	assert.Equal(t, []string{"pods", "services"}, modes[0].(map[string]any)["items"].(map[string]any)["enum"])
+	assert.Equal(t, []string{"pods", "services"}, modes[1].(map[string]any)["enum"])
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{PositionalArgsParam: "pods services"}
+	_, err := tools[0].buildCommandArgs(request, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected exactly 1 positional argument, got 2")
+
+	request.Params.Arguments = map[string]any{PositionalArgsParam: "pods"}
+	args, err := tools[0].buildCommandArgs(request, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"get", "--", "pods"}, args)
+}
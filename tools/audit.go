@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// AuditRecord describes a single tool execution.
+type AuditRecord struct {
+	Time time.Time `json:"time"` // when the execution started
+	Tool string    `json:"tool"`
+	// Args is the command line below the root command, with the values of sensitive flags
+	// redacted. It is nil if the request arguments were invalid.
+	Args []string `json:"args"`
+	// Session is the ID of the MCP client session, if the call came through an MCP server.
+	Session string `json:"session,omitempty"`
+	// Client is the name and version the MCP client reported, if known.
+	Client        string `json:"client,omitempty"`
+	ClientVersion string `json:"client_version,omitempty"`
+	// ExitCode is the exit code of the process, or -1 if it did not run or exit normally.
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration_ns"`
+	TimedOut bool          `json:"timed_out,omitempty"`
+	Killed   bool          `json:"killed,omitempty"`
+	DryRun   bool          `json:"dry_run,omitempty"`
+	Cached   bool          `json:"cached,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// AuditFunc receives a record of every tool execution once it has finished, including
+// executions that were rejected, failed or timed out. It is called synchronously, so a slow
+// AuditFunc delays the tool result.
+type AuditFunc func(ctx context.Context, record AuditRecord)
+
+// WithAudit returns a GeneratorOption that reports every execution of a generated tool to audit.
+//
+//	Example: NewGenerator(WithAudit(JSONAudit(auditFile)))
+func WithAudit(audit AuditFunc) GeneratorOption {
+	return func(g *Generator) {
+		g.audit = audit
+	}
+}
+
+// JSONAudit returns an AuditFunc that writes each record to w as a line of JSON.
+// Writes are serialized, so it is safe to use with concurrent executions.
+func JSONAudit(w io.Writer) AuditFunc {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+
+	return func(_ context.Context, record AuditRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// There is no one to report a failed write to but the writer itself
+		_ = encoder.Encode(record)
+	}
+}
+
+// auditExecution reports a finished execution to the audit function of the controller.
+func (c *Controller) auditExecution(ctx context.Context, start time.Time, args []string, result *ExecResult, err error) {
+	record := AuditRecord{
+		Time:     start,
+		Tool:     c.Tool.Name,
+		ExitCode: -1,
+		Duration: time.Since(start),
+	}
+	if args != nil {
+		record.Args = c.sensitive.args(args)
+	}
+
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		record.Session = session.SessionID()
+		if info, ok := session.(server.SessionWithClientInfo); ok {
+			client := info.GetClientInfo()
+			record.Client, record.ClientVersion = client.Name, client.Version
+		}
+	}
+
+	if result != nil {
+		record.ExitCode = result.ExitCode
+		record.TimedOut = result.TimedOut
+		record.Killed = result.Killed
+		record.DryRun = result.DryRun
+		record.Cached = result.Cached
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	c.audit(ctx, record)
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAudit tests that every execution is reported, including failures
+func TestAudit(t *testing.T) {
+	var records []AuditRecord
+	audit := func(_ context.Context, record AuditRecord) {
+		records = append(records, record)
+	}
+
+	t.Run("exit code and redacted args", func(t *testing.T) {
+		records = nil
+		ctrl := helperController(t, "exit")
+		ctrl.audit = audit
+
+		request := helperRequest("3")
+		request.Params.Arguments.(map[string]any)[FlagsParam] = map[string]any{"token": "abc"}
+		ctx := server.NewMCPServer("test", "1.0.0").WithContext(context.Background(), newTestSession())
+
+		_, err := ctrl.Execute(ctx, request)
+		require.Error(t, err)
+		require.Len(t, records, 1)
+
+		record := records[0]
+		assert.Equal(t, "helper_exit", record.Tool)
+		assert.Equal(t, []string{"exit", "--token=***", "--", "3"}, record.Args)
+		assert.Equal(t, "test", record.Session)
+		assert.Equal(t, 3, record.ExitCode)
+		assert.NotEmpty(t, record.Error)
+		assert.Positive(t, record.Duration)
+		assert.WithinDuration(t, time.Now(), record.Time, time.Minute)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		records = nil
+		ctrl := &Controller{Tool: mcp.NewTool("test"), audit: audit, executor: &recordingExecutor{}}
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{PositionalArgsParam: []any{1}}
+
+		_, err := ctrl.Execute(context.Background(), request)
+		require.Error(t, err)
+		require.Len(t, records, 1)
+		assert.Nil(t, records[0].Args)
+		assert.Equal(t, -1, records[0].ExitCode)
+		assert.Contains(t, records[0].Error, "invalid tool arguments")
+		assert.Empty(t, records[0].Session)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		records = nil
+		ctrl := helperController(t, "sleep")
+		ctrl.audit = audit
+		ctrl.Timeout = 100 * time.Millisecond
+
+		_, err := ctrl.Execute(context.Background(), helperRequest("10s"))
+		require.ErrorIs(t, err, ErrTimeout)
+		require.Len(t, records, 1)
+		assert.True(t, records[0].TimedOut)
+	})
+}
+
+// TestJSONAudit tests that records are written as JSON lines
+func TestJSONAudit(t *testing.T) {
+	var buf bytes.Buffer
+	audit := JSONAudit(&buf)
+	audit(context.Background(), AuditRecord{Tool: "a", Args: []string{"get"}})
+	audit(context.Background(), AuditRecord{Tool: "b", ExitCode: 1, Error: "exit status 1"})
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var record AuditRecord
+	require.NoError(t, json.Unmarshal(lines[1], &record))
+	assert.Equal(t, "b", record.Tool)
+	assert.Equal(t, 1, record.ExitCode)
+	assert.Equal(t, "exit status 1", record.Error)
+}
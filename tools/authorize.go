@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnauthorized is returned by Execute when the AuthorizeFunc of the generator denied the command.
+var ErrUnauthorized = errors.New("command not authorized")
+
+// AuthorizeFunc approves or denies a command before it runs. It receives the name of the tool
+// and the command line below the root command, e.g. ["delete", "--force", "--", "pod"].
+// Returning an error aborts the execution, and the error is reported to the client.
+//
+// The context is the one of the tool call, so server.ClientSessionFromContext returns the
+// MCP session of the caller for per-client policies.
+type AuthorizeFunc func(ctx context.Context, toolName string, args []string) error
+
+// WithAuthorize returns a GeneratorOption that checks every execution of a generated tool with authorize.
+//
+//	Example: NewGenerator(WithAuthorize(func(ctx context.Context, tool string, args []string) error {
+//		if args[0] == "delete" && !slices.Contains(args, "--dry-run") {
+//			return errors.New("delete requires --dry-run")
+//		}
+//		return nil
+//	}))
+func WithAuthorize(authorize AuthorizeFunc) GeneratorOption {
+	return func(g *Generator) {
+		g.authorize = authorize
+	}
+}
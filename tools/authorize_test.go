@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithAuthorize tests that denied commands never reach the executor
+func TestWithAuthorize(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	del := &cobra.Command{Use: "delete", Run: func(*cobra.Command, []string) {}}
+	del.Flags().Bool("dry-run", false, "only print what would be deleted")
+	root.AddCommand(del)
+
+	var sessions []string
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(
+		WithExecutor(executor),
+		WithAuthorize(func(ctx context.Context, toolName string, args []string) error {
+			if session := server.ClientSessionFromContext(ctx); session != nil {
+				sessions = append(sessions, session.SessionID())
+			}
+			assert.Equal(t, "cli_delete", toolName)
+			if !slices.Contains(args, "--dry-run") {
+				return errors.New("delete requires --dry-run")
+			}
+			return nil
+		}),
+	).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	ctx := server.NewMCPServer("test", "1.0.0").WithContext(context.Background(), newTestSession())
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{PositionalArgsParam: []any{"pod"}}
+	_, err := tools[0].Execute(ctx, request)
+	require.ErrorIs(t, err, ErrUnauthorized)
+	assert.Contains(t, err.Error(), "delete requires --dry-run")
+	assert.Empty(t, executor.invocations)
+
+	toolResult, err := tools[0].Handle(ctx, request, nil, err)
+	require.NoError(t, err)
+	assert.True(t, toolResult.IsError)
+
+	request.Params.Arguments = map[string]any{
+		PositionalArgsParam: []any{"pod"},
+		FlagsParam:          map[string]any{"dry-run": true},
+	}
+	_, err = tools[0].Execute(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, executor.invocations, 1)
+	assert.Equal(t, []string{"delete", "--dry-run", "--", "pod"}, executor.invocations[0].Args)
+	assert.Equal(t, []string{"test", "test"}, sessions)
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultBreakerCooldown is how long an open circuit breaker rejects calls, unless the
+// BreakerPolicy sets a Cooldown.
+const DefaultBreakerCooldown = 30 * time.Second
+
+// BreakerPolicy decides when the circuit breaker of a tool opens. A zero Threshold means the
+// breaker never opens.
+type BreakerPolicy struct {
+	// Threshold is the number of consecutive failed executions that opens the breaker.
+	Threshold int
+	// Window limits the streak to failures within this time of its first failure; an older
+	// streak starts over. If zero, failures count however far apart they are.
+	Window time.Duration
+	// Cooldown is how long the open breaker rejects calls before it lets a trial call through.
+	// If zero, DefaultBreakerCooldown is used.
+	Cooldown time.Duration
+}
+
+// CircuitBreaker returns middleware that stops calling a tool whose command keeps failing, e.g.
+// because of a misconfiguration or a missing dependency, so that agents get a clear signal to stop
+// retrying it. Tools are looked up by name in policies, and use defaultPolicy otherwise; every
+// tool has its own breaker. No tool is limited by default.
+//
+// After Threshold consecutive failures, the breaker opens: calls are not executed, and return an
+// error result stating when to retry, with the number of seconds in the MetaRetryAfter metadata.
+// After the cooldown, a single trial call is let through, which closes the breaker if it succeeds
+// and opens it again otherwise.
+//
+// Only executions of generated tools count. Calls rejected before running, e.g. for invalid
+// arguments, cancelled calls, and hand-written tools neither open nor close the breaker.
+//
+//	Example: ophis.Config{Middleware: []server.ToolHandlerMiddleware{
+//		tools.CircuitBreaker(tools.BreakerPolicy{Threshold: 5, Window: time.Minute}, nil),
+//	}}
+func CircuitBreaker(defaultPolicy BreakerPolicy, policies map[string]BreakerPolicy) server.ToolHandlerMiddleware {
+	return newCircuitBreaker(defaultPolicy, policies, time.Now).middleware
+}
+
+// circuitBreaker holds the circuits of the tools.
+type circuitBreaker struct {
+	defaultPolicy BreakerPolicy
+	policies      map[string]BreakerPolicy
+	now           func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit is the breaker state of a single tool.
+type circuit struct {
+	failures  int       // consecutive failures
+	first     time.Time // time of the first failure of the streak
+	openUntil time.Time // end of the cooldown, zero while closed
+	trial     bool      // whether the trial call after the cooldown is running
+}
+
+func newCircuitBreaker(defaultPolicy BreakerPolicy, policies map[string]BreakerPolicy, now func() time.Time) *circuitBreaker {
+	return &circuitBreaker{
+		defaultPolicy: defaultPolicy,
+		policies:      policies,
+		now:           now,
+		circuits:      map[string]*circuit{},
+	}
+}
+
+func (b *circuitBreaker) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.Params.Name
+		policy := b.policy(name)
+		if policy.Threshold <= 0 {
+			return next(ctx, request)
+		}
+
+		if wait, ok := b.allow(name); !ok {
+			message := fmt.Sprintf("tool %q is temporarily disabled after repeated failures", name)
+			if wait <= 0 {
+				return mcp.NewToolResultError(message + ", a trial call is running"), nil
+			}
+			result := mcp.NewToolResultError(fmt.Sprintf("%s, retry in %s", message, wait.Round(time.Millisecond)))
+			addMeta(result, map[string]any{MetaRetryAfter: wait.Seconds()})
+			return result, nil
+		}
+
+		attempt := &breakerAttempt{}
+		result, err := next(context.WithValue(ctx, breakerAttemptKey{}, attempt), request)
+		b.record(name, policy, attempt)
+		return result, err
+	}
+}
+
+// policy returns the breaker policy of a tool.
+func (b *circuitBreaker) policy(tool string) BreakerPolicy {
+	if policy, ok := b.policies[tool]; ok {
+		return policy
+	}
+
+	return b.defaultPolicy
+}
+
+// allow reports whether a call of a tool may run. While the breaker is open, it returns how
+// long the cooldown lasts, or zero if the trial call is running.
+func (b *circuitBreaker) allow(tool string) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[tool]
+	if !ok || c.openUntil.IsZero() {
+		return 0, true
+	}
+
+	if wait := c.openUntil.Sub(b.now()); wait > 0 {
+		return wait, false
+	}
+	if c.trial {
+		return 0, false
+	}
+
+	c.trial = true
+	return 0, true
+}
+
+// record updates the circuit of a tool with the outcome of a call.
+func (b *circuitBreaker) record(tool string, policy BreakerPolicy, attempt *breakerAttempt) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[tool]
+	if !ok {
+		c = &circuit{}
+		b.circuits[tool] = c
+	}
+	c.trial = false
+
+	switch {
+	case !attempt.counted:
+		// Neither a success nor a failure of the command
+	case !attempt.failed:
+		*c = circuit{}
+	case !c.openUntil.IsZero():
+		// The trial call failed
+		c.openUntil = b.now().Add(policy.cooldown())
+	default:
+		now := b.now()
+		if c.failures == 0 || (policy.Window > 0 && now.Sub(c.first) > policy.Window) {
+			c.failures, c.first = 0, now
+		}
+		c.failures++
+		if c.failures >= policy.Threshold {
+			c.openUntil = now.Add(policy.cooldown())
+		}
+	}
+}
+
+// cooldown returns how long the open breaker rejects calls.
+func (p BreakerPolicy) cooldown() time.Duration {
+	if p.Cooldown <= 0 {
+		return DefaultBreakerCooldown
+	}
+
+	return p.Cooldown
+}
+
+// breakerAttempt receives the outcome of a tool call made by the CircuitBreaker middleware.
+// Controllers record their executions in it, so that hand-written tools are never counted.
+type breakerAttempt struct {
+	counted bool
+	failed  bool
+}
+
+type breakerAttemptKey struct{}
+
+// recordBreakerAttempt records an execution in the breaker attempt of ctx, if there is one.
+// Calls that were rejected before running the command, or cancelled by the client, are not
+// counted.
+func recordBreakerAttempt(ctx context.Context, err error) {
+	attempt, ok := ctx.Value(breakerAttemptKey{}).(*breakerAttempt)
+	if !ok {
+		return
+	}
+
+	switch {
+	case ctx.Err() != nil:
+	case errors.Is(err, ErrInvalidArguments), errors.Is(err, ErrUnauthorized), errors.Is(err, ErrQueueFull), errors.Is(err, ErrOverloaded):
+	default:
+		attempt.counted = true
+		attempt.failed = err != nil
+	}
+}
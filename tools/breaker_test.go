@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCircuitBreaker tests that a tool is disabled after consecutive failures and tried again after the cooldown
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := newCircuitBreaker(BreakerPolicy{Threshold: 2, Window: time.Minute, Cooldown: 10 * time.Second}, nil, func() time.Time { return now })
+
+	executor := &flakyExecutor{failures: 4, exitCode: 1}
+	ctrl := &Controller{Tool: mcp.NewTool("cli_get"), path: []string{"get"}, executor: executor}
+	handler := breaker.middleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := ctrl.Execute(ctx, request)
+		return ctrl.Handle(ctx, request, result, err)
+	})
+
+	var request mcp.CallToolRequest
+	request.Params.Name = "cli_get"
+	call := func() *mcp.CallToolResult {
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		return result
+	}
+
+	// Invalid arguments do not count as failures of the command
+	invalid := request
+	invalid.Params.Arguments = map[string]any{FlagsParam: "not an object"}
+	for range 3 {
+		_, err := handler(context.Background(), invalid)
+		require.NoError(t, err)
+	}
+
+	// Failures too far apart do not open the breaker
+	assert.True(t, call().IsError)
+	now = now.Add(2 * time.Minute)
+	assert.True(t, call().IsError)
+	assert.Equal(t, 2, executor.calls)
+
+	// Consecutive failures open it, and calls are rejected without running
+	assert.True(t, call().IsError)
+	assert.Equal(t, 3, executor.calls)
+	result := call()
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "temporarily disabled after repeated failures, retry in 10s")
+	assert.InDelta(t, 10, result.Meta.AdditionalFields[MetaRetryAfter], 0.1)
+	assert.Equal(t, 3, executor.calls)
+
+	// A failed trial call opens it again
+	now = now.Add(10 * time.Second)
+	assert.True(t, call().IsError)
+	assert.Equal(t, 4, executor.calls)
+	assert.Contains(t, call().Content[0].(mcp.TextContent).Text, "retry in 10s")
+
+	// A successful trial call closes it
+	now = now.Add(10 * time.Second)
+	assert.False(t, call().IsError)
+	assert.False(t, call().IsError)
+	assert.Equal(t, 6, executor.calls)
+}
+
+// TestCircuitBreakerTrial tests that only one trial call runs after the cooldown
+func TestCircuitBreakerTrial(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := newCircuitBreaker(BreakerPolicy{}, map[string]BreakerPolicy{"cli_get": {Threshold: 1}}, func() time.Time { return now })
+
+	breaker.record("cli_get", breaker.policy("cli_get"), &breakerAttempt{counted: true, failed: true})
+	wait, ok := breaker.allow("cli_get")
+	assert.False(t, ok)
+	assert.Equal(t, DefaultBreakerCooldown, wait)
+
+	// Other tools are not affected
+	_, ok = breaker.allow("cli_list")
+	assert.True(t, ok)
+
+	now = now.Add(DefaultBreakerCooldown)
+	_, ok = breaker.allow("cli_get")
+	assert.True(t, ok)
+	wait, ok = breaker.allow("cli_get")
+	assert.False(t, ok)
+	assert.Zero(t, wait)
+
+	// A trial call that does not count lets the next call try again
+	breaker.record("cli_get", breaker.policy("cli_get"), &breakerAttempt{})
+	_, ok = breaker.allow("cli_get")
+	assert.True(t, ok)
+}
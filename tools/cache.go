@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+)
+
+// CacheBackend stores the output of cached tool executions, e.g. in memory or in Redis.
+// Values are opaque and expire after ttl. Implementations must be safe for concurrent use.
+type CacheBackend interface {
+	// Get returns the value stored for key, and whether one was found that has not expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value for key for the duration of ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// cacheRule caches the tools matched by selector for ttl.
+type cacheRule struct {
+	selector Filter
+	ttl      time.Duration
+}
+
+// WithCache returns a GeneratorOption that caches the output of the tools matched by selector, or
+// of every tool if selector is nil, for ttl. A call with the same arguments, standard input,
+// working directory and environment as a cached one returns the cached output without running the
+// command. The environment includes the session ID and client of WithRequestEnv, so sessions
+// never share outputs, but not the progress token or trace context, which differ between calls.
+// Later calls win on conflicts, and a zero ttl disables the cache of the matched tools again.
+//
+// Only successful executions are cached, and only tools annotated as idempotent, with
+// WithToolAnnotations or AnnotationIdempotent, are cached at all, since the output of other
+// commands may change between identical calls. Results are stored in memory unless
+// WithCacheBackend is used.
+//
+//	Example: NewGenerator(
+//		WithToolAnnotations(Allow([]string{"get"}), mcp.ToolAnnotation{IdempotentHint: mcp.ToBoolPtr(true)}),
+//		WithCache(Allow([]string{"get"}), time.Minute),
+//	)
+func WithCache(selector Filter, ttl time.Duration) GeneratorOption {
+	return func(g *Generator) {
+		g.cacheRules = append(g.cacheRules, cacheRule{selector: selector, ttl: ttl})
+	}
+}
+
+// WithCacheBackend returns a GeneratorOption that sets where the output of the tools selected by
+// WithCache is stored. A nil backend restores the in-memory default.
+//
+//	Example: NewGenerator(WithCacheBackend(myRedisCache), WithCache(Allow([]string{"get"}), time.Minute))
+func WithCacheBackend(backend CacheBackend) GeneratorOption {
+	return func(g *Generator) {
+		g.cacheBackend = backend
+	}
+}
+
+// cacheFor returns the cache of the tool generated for a command, nil if it is not cached.
+func (g *Generator) cacheFor(cmd *cobra.Command, tool mcp.Tool) *toolCache {
+	var ttl time.Duration
+	for _, rule := range g.cacheRules {
+		if rule.selector == nil || rule.selector(cmd) {
+			ttl = rule.ttl
+		}
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	if idempotent := tool.Annotations.IdempotentHint; idempotent == nil || !*idempotent {
+		g.logger.Warn("not caching tool that is not annotated as idempotent", "tool", tool.Name)
+		return nil
+	}
+
+	return &toolCache{backend: g.cacheBackend, ttl: ttl}
+}
+
+// toolCache caches the executions of a single tool.
+type toolCache struct {
+	backend CacheBackend
+	ttl     time.Duration
+}
+
+// cachedResult is the stored form of a successful ExecResult.
+type cachedResult struct {
+	Stdout    []byte `json:"stdout"`
+	Stderr    []byte `json:"stderr"`
+	Combined  []byte `json:"combined"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// invocationKey returns the key under which identical invocations of a tool share an output,
+// both in the cache and when coalesced. The environment is part of the key, so that calls with
+// request metadata of different sessions never share an output, and must not hold per-call
+// variables, see keyEnviron.
+func invocationKey(tool string, inv Invocation, stdin string) string {
+	// Marshaling a struct of strings cannot fail
+	data, _ := json.Marshal(struct {
+		Tool  string   `json:"tool"`
+		Args  []string `json:"args"`
+		Dir   string   `json:"dir"`
+		Env   []string `json:"env"`
+		Stdin string   `json:"stdin"`
+	}{tool, inv.Args, inv.Dir, inv.Env, stdin})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached result for key, or nil if there is none.
+func (tc *toolCache) get(ctx context.Context, key string) (*ExecResult, error) {
+	data, ok, err := tc.backend.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var cached cachedResult
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+
+	return &ExecResult{
+		Stdout:    cached.Stdout,
+		Stderr:    cached.Stderr,
+		Truncated: cached.Truncated,
+		Cached:    true,
+		combined:  cached.Combined,
+	}, nil
+}
+
+// set stores a successful result for key.
+func (tc *toolCache) set(ctx context.Context, key string, result *ExecResult) error {
+	data, err := json.Marshal(cachedResult{
+		Stdout:    result.Stdout,
+		Stderr:    result.Stderr,
+		Combined:  result.combined,
+		Truncated: result.Truncated,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tc.backend.Set(ctx, key, data, tc.ttl)
+}
+
+// MemoryCache is a CacheBackend that keeps values in memory. It is the default backend of WithCache.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates an empty in-memory CacheBackend.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]memoryEntry{}, now: time.Now}
+}
+
+// Get returns the value stored for key, unless it has expired.
+func (m *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !m.now().Before(entry.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+
+	return entry.value, true, nil
+}
+
+// Set stores value for key for the duration of ttl. Expired values are removed.
+func (m *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for k, entry := range m.entries {
+		if !now.Before(entry.expires) {
+			delete(m.entries, k)
+		}
+	}
+
+	m.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
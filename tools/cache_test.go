@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithCache tests that identical calls of cached tools return the stored output
+func TestWithCache(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(
+		&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "list", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "watch", Run: func(*cobra.Command, []string) {}},
+	)
+
+	executor := &recordingExecutor{result: &ExecResult{Stdout: []byte("pods"), combined: []byte("pods")}}
+	tools := NewGenerator(
+		WithExecutor(executor),
+		WithToolAnnotations(Allow([]string{"get", "list"}), mcp.ToolAnnotation{IdempotentHint: mcp.ToBoolPtr(true)}),
+		WithCache(Allow([]string{"get", "watch"}), time.Minute),
+	).FromRootCmd(root)
+	require.Len(t, tools, 3)
+
+	byName := map[string]Controller{}
+	for _, tool := range tools {
+		byName[tool.Tool.Name] = tool
+	}
+
+	call := func(name string, args ...any) *ExecResult {
+		t.Helper()
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{PositionalArgsParam: args}
+		ctrl := byName[name]
+		result, err := ctrl.Execute(context.Background(), request)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("identical calls are cached", func(t *testing.T) {
+		executor.invocations = nil
+		assert.False(t, call("cli_get", "pods").Cached)
+		result := call("cli_get", "pods")
+		assert.True(t, result.Cached)
+		assert.Equal(t, "pods", string(result.Stdout))
+		assert.Equal(t, "pods", string(result.Combined()))
+		assert.Equal(t, true, result.meta()[MetaCached])
+		assert.Len(t, executor.invocations, 1)
+
+		assert.False(t, call("cli_get", "nodes").Cached)
+		assert.Len(t, executor.invocations, 2)
+	})
+
+	t.Run("unselected and non-idempotent tools are not cached", func(t *testing.T) {
+		executor.invocations = nil
+		for range 2 {
+			call("cli_list")
+			call("cli_watch")
+		}
+		assert.Len(t, executor.invocations, 4)
+	})
+
+	t.Run("failures are not cached", func(t *testing.T) {
+		executor.invocations = nil
+		executor.result = &ExecResult{ExitCode: 1}
+		executor.err = errors.New("exit status 1")
+		defer func() {
+			executor.result = &ExecResult{Stdout: []byte("pods")}
+			executor.err = nil
+		}()
+
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{PositionalArgsParam: []any{"failing"}}
+		for range 2 {
+			ctrl := byName["cli_get"]
+			_, err := ctrl.Execute(context.Background(), request)
+			assert.Error(t, err)
+		}
+		assert.Len(t, executor.invocations, 2)
+	})
+}
+
+// namedSession is a client session with the given ID.
+type namedSession struct {
+	*testSession
+	id string
+}
+
+func (s *namedSession) SessionID() string { return s.id }
+
+// TestCacheSessions tests that calls of different sessions do not share cached output when the
+// command sees request metadata, while per-call metadata does not prevent hits
+func TestCacheSessions(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+	executor := &recordingExecutor{result: &ExecResult{Stdout: []byte("pods")}}
+	tools := NewGenerator(
+		WithExecutor(executor),
+		WithToolAnnotations(nil, mcp.ToolAnnotation{IdempotentHint: mcp.ToBoolPtr(true)}),
+		WithCache(nil, time.Minute),
+		WithRequestEnv(nil),
+	).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	get := tools[0]
+
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	call := func(session string, progressToken any) *ExecResult {
+		t.Helper()
+		ctx := mcpServer.WithContext(context.Background(), &namedSession{testSession: newTestSession(), id: session})
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{PositionalArgsParam: []any{"pods"}}
+		request.Params.Meta = &mcp.Meta{ProgressToken: progressToken}
+		result, err := get.Execute(ctx, request)
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.False(t, call("a", 1).Cached)
+	assert.True(t, call("a", 2).Cached, "the progress token differs between calls")
+	assert.False(t, call("b", 3).Cached, "another session does not see the output of the first")
+	assert.Len(t, executor.invocations, 2)
+}
+
+// TestMemoryCache tests that values expire after their ttl
+func TestMemoryCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewMemoryCache()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "key", []byte("value"), time.Second))
+	value, ok, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", string(value))
+
+	now = now.Add(time.Second)
+	_, ok, err = cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
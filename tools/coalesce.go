@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+)
+
+// WithCoalescing returns a GeneratorOption that coalesces identical concurrent calls of the tools
+// matched by selector, or of every tool if selector is nil. A call with the same arguments,
+// standard input, working directory and environment as one that is still running does not run
+// the command again, but waits for the running one and returns a copy of its output, with the
+// MetaShared metadata. As with WithCache, the environment includes the session ID and client of
+// WithRequestEnv, but not the progress token or the trace context of WithTracer, which differ
+// between calls. Unlike WithCache, which serves repeated calls over time, only calls that
+// overlap are coalesced, so it pays off for eager or retrying clients.
+//
+// The command keeps running as long as any of the coalesced calls waits for it, and is cancelled
+// when all of them are. Only tools annotated as idempotent, with WithToolAnnotations or
+// AnnotationIdempotent, are coalesced, since running other commands twice may differ from
+// running them once. Output streamed by WithStreaming is only sent to the first caller.
+//
+//	Example: NewGenerator(
+//		WithToolAnnotations(Allow([]string{"get"}), mcp.ToolAnnotation{IdempotentHint: mcp.ToBoolPtr(true)}),
+//		WithCoalescing(Allow([]string{"get"})),
+//	)
+func WithCoalescing(selector Filter) GeneratorOption {
+	return func(g *Generator) {
+		if selector == nil {
+			selector = AllOf()
+		}
+		g.coalescing = selector
+	}
+}
+
+// flightsFor returns the in-flight executions shared by the tool generated for a command, nil if
+// its calls are not coalesced.
+func (g *Generator) flightsFor(cmd *cobra.Command, tool mcp.Tool) *flightGroup {
+	if g.coalescing == nil || !g.coalescing(cmd) {
+		return nil
+	}
+
+	if idempotent := tool.Annotations.IdempotentHint; idempotent == nil || !*idempotent {
+		g.logger.Warn("not coalescing calls of tool that is not annotated as idempotent", "tool", tool.Name)
+		return nil
+	}
+
+	return g.flights
+}
+
+// flightGroup holds the executions in flight of the coalesced tools of a Generator, by the key
+// of their invocation.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is an execution that one or more calls wait for.
+type flight struct {
+	done    chan struct{}
+	result  *ExecResult
+	ran     bool
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: map[string]*flight{}}
+}
+
+// do runs fn for key, unless an execution for key is in flight, in which case it waits for that
+// one instead. Every call gets its own copy of the result, and whether the command ran. fn runs
+// with the values of the context of the first call, and is cancelled once every waiting call is
+// cancelled.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (*ExecResult, bool, error)) (*ExecResult, bool, error) {
+	g.mu.Lock()
+	f, shared := g.flights[key]
+	if shared {
+		f.waiters++
+	} else {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.flights[key] = f
+
+		go func() {
+			f.result, f.ran, f.err = fn(flightCtx)
+			g.forget(key, f)
+			cancel()
+			close(f.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		last := f.waiters == 0
+		g.mu.Unlock()
+		if !last {
+			return nil, false, fmt.Errorf("cancelled while waiting for an identical call: %w", ctx.Err())
+		}
+
+		// Stop the command, and return its partial output like an execution that is not shared
+		g.forget(key, f)
+		f.cancel()
+		<-f.done
+	}
+
+	if f.result == nil {
+		return nil, f.ran, f.err
+	}
+
+	result := *f.result
+	result.Shared = shared
+	return &result, f.ran, f.err
+}
+
+// forget removes f from the flights, so that later calls of key run the command again.
+func (g *flightGroup) forget(key string, f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithCoalescing tests that identical concurrent calls of coalesced tools run the command once
+func TestWithCoalescing(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(
+		&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "list", Run: func(*cobra.Command, []string) {}},
+	)
+
+	executor := &blockingExecutor{started: make(chan struct{}, 4), release: make(chan struct{})}
+	tools := NewGenerator(
+		WithExecutor(executor),
+		WithToolAnnotations(nil, mcp.ToolAnnotation{IdempotentHint: mcp.ToBoolPtr(true)}),
+		WithToolAnnotations(Allow([]string{"list"}), mcp.ToolAnnotation{IdempotentHint: mcp.ToBoolPtr(false)}),
+		WithCoalescing(nil),
+	).FromRootCmd(root)
+	require.Len(t, tools, 2)
+	get, list := tools[0], tools[1]
+	require.NotNil(t, get.flights)
+	assert.Nil(t, list.flights, "non-idempotent tools are not coalesced")
+
+	type outcome struct {
+		result *ExecResult
+		err    error
+	}
+	call := func(ctx context.Context, args ...any) <-chan outcome {
+		outcomes := make(chan outcome, 1)
+		go func() {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]any{PositionalArgsParam: args}
+			result, err := get.Execute(ctx, request)
+			outcomes <- outcome{result, err}
+		}()
+		return outcomes
+	}
+	waitForWaiters := func(n int) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			get.flights.mu.Lock()
+			defer get.flights.mu.Unlock()
+			waiters := 0
+			for _, f := range get.flights.flights {
+				waiters += f.waiters
+			}
+			return waiters == n
+		}, time.Second, time.Millisecond)
+	}
+
+	t.Run("identical calls share one execution", func(t *testing.T) {
+		first := call(context.Background(), "pods")
+		<-executor.started
+		second := call(context.Background(), "pods")
+		waitForWaiters(2)
+		executor.release <- struct{}{}
+
+		a, b := <-first, <-second
+		require.NoError(t, a.err)
+		require.NoError(t, b.err)
+		assert.False(t, a.result.Shared)
+		assert.True(t, b.result.Shared)
+		assert.Equal(t, true, b.result.meta()[MetaShared])
+		assert.NotSame(t, a.result, b.result)
+		assert.EqualValues(t, 1, executor.peak.Load())
+		assert.Empty(t, get.flights.flights)
+	})
+
+	t.Run("different calls run separately", func(t *testing.T) {
+		executor.peak.Store(0)
+		first, second := call(context.Background(), "pods"), call(context.Background(), "nodes")
+		<-executor.started
+		<-executor.started
+		executor.release <- struct{}{}
+		executor.release <- struct{}{}
+
+		assert.False(t, (<-first).result.Shared)
+		assert.False(t, (<-second).result.Shared)
+		assert.EqualValues(t, 2, executor.peak.Load())
+	})
+
+	t.Run("progress tokens do not prevent coalescing", func(t *testing.T) {
+		get.requestEnv = defaultRequestEnv
+		defer func() { get.requestEnv = nil }()
+		callWithToken := func(token any) <-chan outcome {
+			outcomes := make(chan outcome, 1)
+			go func() {
+				request := mcp.CallToolRequest{}
+				request.Params.Arguments = map[string]any{PositionalArgsParam: []any{"pods"}}
+				request.Params.Meta = &mcp.Meta{ProgressToken: token}
+				result, err := get.Execute(context.Background(), request)
+				outcomes <- outcome{result, err}
+			}()
+			return outcomes
+		}
+
+		first := callWithToken(1)
+		<-executor.started
+		second := callWithToken(2)
+		waitForWaiters(2)
+		executor.release <- struct{}{}
+
+		assert.False(t, (<-first).result.Shared)
+		assert.True(t, (<-second).result.Shared)
+	})
+
+	t.Run("the command runs until every call is cancelled", func(t *testing.T) {
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		ctx2, cancel2 := context.WithCancel(context.Background())
+		first := call(ctx1, "pods")
+		<-executor.started
+		second := call(ctx2, "pods")
+		waitForWaiters(2)
+
+		cancel1()
+		assert.ErrorIs(t, (<-first).err, context.Canceled)
+		assert.EqualValues(t, 1, executor.running.Load())
+
+		cancel2()
+		assert.NotNil(t, (<-second).result)
+		assert.EqualValues(t, 0, executor.running.Load())
+		assert.Empty(t, get.flights.flights)
+	})
+}
+
+// TestKeyEnviron tests that per-call variables are left out of the keys of coalesced and cached calls
+func TestKeyEnviron(t *testing.T) {
+	env := []string{"PATH=/bin", "OPHIS_SESSION_ID=a", "OPHIS_PROGRESS_TOKEN=7", "TRACEPARENT=00-1-2-01", "TRACESTATE=x=y"}
+
+	ctrl := &Controller{requestEnv: defaultRequestEnv, tracer: &recordingTracer{}}
+	assert.Equal(t, []string{"PATH=/bin", "OPHIS_SESSION_ID=a"}, ctrl.keyEnviron(env))
+	assert.Len(t, env, 5, "the environment of the call is not changed")
+
+	// Without tracing or request metadata, variables of the same names come from the server
+	assert.Equal(t, env, (&Controller{}).keyEnviron(env))
+}
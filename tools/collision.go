@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrToolNameCollision is returned by Generate when two commands produce the same tool name
+// and the CollisionError policy is in effect.
+var ErrToolNameCollision = errors.New("tool name collision")
+
+// CollisionPolicy decides what happens when two commands produce the same tool name,
+// for example because of a custom NameFunc or command names containing underscores.
+type CollisionPolicy int
+
+const (
+	// CollisionWarn logs a warning and keeps only the first tool with the name. This is the default.
+	CollisionWarn CollisionPolicy = iota
+	// CollisionError makes Generate fail with an error naming the conflicting command paths.
+	CollisionError
+	// CollisionSuffix keeps every tool, renaming later ones with a numeric suffix (e.g. "cli_get_2").
+	CollisionSuffix
+)
+
+// WithCollisionPolicy returns a GeneratorOption that sets how tool name collisions are resolved.
+// Tools are generated depth-first in the order of cobra's Commands, so the tool that is kept or
+// left unsuffixed is deterministic.
+//
+//	Example: NewGenerator(WithCollisionPolicy(CollisionError))
+func WithCollisionPolicy(policy CollisionPolicy) GeneratorOption {
+	return func(g *Generator) {
+		g.collisions = policy
+	}
+}
+
+// resolveCollisions applies the collision policy to generated tools.
+func (g *Generator) resolveCollisions(tools []Controller) ([]Controller, error) {
+	owners := make(map[string]Controller, len(tools))
+	resolved := make([]Controller, 0, len(tools))
+	var conflicts []error
+	for _, ctrl := range tools {
+		name := ctrl.Tool.Name
+		owner, taken := owners[name]
+		if !taken {
+			owners[name] = ctrl
+			resolved = append(resolved, ctrl)
+			continue
+		}
+
+		switch g.collisions {
+		case CollisionError:
+			conflicts = append(conflicts, fmt.Errorf("%w: %q is produced by both %q and %q",
+				ErrToolNameCollision, name, owner.command, ctrl.command))
+		case CollisionSuffix:
+			suffixed := name
+			for n := 2; ; n++ {
+				suffixed = fmt.Sprintf("%s_%d", name, n)
+				if _, taken := owners[suffixed]; !taken {
+					break
+				}
+			}
+
+			g.logger.Warn("renaming tool to avoid a name collision",
+				"tool", name,
+				"renamed", suffixed,
+				"command", ctrl.command,
+				"conflicts_with", owner.command,
+			)
+			ctrl.Tool.Name = suffixed
+			owners[suffixed] = ctrl
+			resolved = append(resolved, ctrl)
+		default:
+			g.logger.Warn("dropping tool with a duplicate name",
+				"tool", name,
+				"command", ctrl.command,
+				"kept", owner.command,
+			)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, errors.Join(conflicts...)
+	}
+
+	return resolved, nil
+}
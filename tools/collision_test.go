@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCollidingTree returns a tree where "cli get_pods" and "cli get pods" both map to "cli_get_pods"
+func newCollidingTree() *cobra.Command {
+	run := func(_ *cobra.Command, _ []string) {}
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get"}
+	get.AddCommand(&cobra.Command{Use: "pods", Short: "nested", Run: run})
+	root.AddCommand(get, &cobra.Command{Use: "get_pods", Short: "flat", Run: run})
+	return root
+}
+
+// TestCollisionPolicy tests the resolution of duplicate tool names
+func TestCollisionPolicy(t *testing.T) {
+	t.Run("warn keeps the first tool", func(t *testing.T) {
+		tools, err := NewGenerator().Generate(newCollidingTree())
+		require.NoError(t, err)
+		require.Len(t, tools, 1)
+		assert.Equal(t, "cli_get_pods", tools[0].Tool.Name)
+		assert.Equal(t, []string{"get", "pods"}, tools[0].path)
+	})
+
+	t.Run("error names both command paths", func(t *testing.T) {
+		gen := NewGenerator(WithCollisionPolicy(CollisionError))
+		tools, err := gen.Generate(newCollidingTree())
+		require.ErrorIs(t, err, ErrToolNameCollision)
+		assert.Nil(t, tools)
+		assert.Contains(t, err.Error(), `"cli_get_pods" is produced by both "cli get pods" and "cli get_pods"`)
+
+		// FromRootCmd logs the error instead
+		assert.Empty(t, gen.FromRootCmd(newCollidingTree()))
+	})
+
+	t.Run("suffix keeps every tool", func(t *testing.T) {
+		tools, err := NewGenerator(WithCollisionPolicy(CollisionSuffix)).Generate(newCollidingTree())
+		require.NoError(t, err)
+		require.Len(t, tools, 2)
+		assert.Equal(t, "cli_get_pods", tools[0].Tool.Name)
+		assert.Equal(t, "nested", tools[0].Tool.Description)
+		assert.Equal(t, "cli_get_pods_2", tools[1].Tool.Name)
+		assert.Equal(t, "flat", tools[1].Tool.Description)
+		assert.Equal(t, []string{"get_pods"}, tools[1].path)
+	})
+
+	t.Run("no collisions", func(t *testing.T) {
+		root := &cobra.Command{Use: "cli", Run: func(_ *cobra.Command, _ []string) {}}
+		tools, err := NewGenerator(WithCollisionPolicy(CollisionError)).Generate(root)
+		require.NoError(t, err)
+		assert.Len(t, tools, 1)
+	})
+}
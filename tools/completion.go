@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// incompleteDirectives mark completions that are not the whole set of valid values, e.g. file
+// extensions to filter by, or prefixes to complete further.
+const incompleteDirectives = cobra.ShellCompDirectiveError | cobra.ShellCompDirectiveNoSpace |
+	cobra.ShellCompDirectiveFilterFileExt | cobra.ShellCompDirectiveFilterDirs
+
+// WithCompletionEnums returns a GeneratorOption that restricts the flags with a completion
+// function registered with cobra's RegisterFlagCompletionFunc to the values it completes, as an
+// enum in the input schema, so that clients pick a valid value, e.g. a region. The function is
+// called once per flag with an empty prefix when the schema is built, and its values are only
+// used if they form a closed set: it must return values along with
+// cobra.ShellCompDirectiveNoFileComp, as cobra.FixedCompletions does, and no directive that
+// filters files or completes partial values.
+//
+// Completion functions are not called by default, since dynamic ones may be slow or reach out
+// to remote services, e.g. to list resources. Combine it with WithLazySchemas to defer the calls
+// until the tools are listed.
+//
+//	Example: NewGenerator(WithCompletionEnums())
+func WithCompletionEnums() GeneratorOption {
+	return func(g *Generator) {
+		g.completionEnums = true
+	}
+}
+
+// completionEnumOption returns a ToolOption that adds the closed sets of values completed for
+// the flags of a command as enums to their schemas.
+func completionEnumOption(logger *slog.Logger, cmd *cobra.Command, flags *pflag.FlagSet) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		flagsSchema, ok := t.InputSchema.Properties[FlagsParam].(map[string]any)
+		if !ok {
+			return
+		}
+		properties, ok := flagsSchema["properties"].(map[string]any)
+		if !ok {
+			return
+		}
+
+		flags.VisitAll(func(flag *pflag.Flag) {
+			schema, ok := properties[flag.Name].(map[string]any)
+			if !ok || hasOptionalValue(flag) {
+				return
+			}
+
+			values := completedValues(logger, cmd, flag.Name)
+			if len(values) == 0 {
+				return
+			}
+
+			// Slice flags take an array of the values
+			target := schema
+			if schema["type"] == "array" {
+				if target, ok = schema["items"].(map[string]any); !ok {
+					return
+				}
+			}
+			enum, ok := enumValues(values, target["type"])
+			if !ok {
+				logger.Debug("completed values do not match the flag type", "flag", flag.Name, "command", cmd.CommandPath())
+				return
+			}
+			target["enum"] = enum
+		})
+	}
+}
+
+// completedValues returns the values completed for a flag of a command, without their
+// descriptions, or nil if the flag has no completion function or its values are not a closed
+// set. A panicking completion function is treated as having none.
+func completedValues(logger *slog.Logger, cmd *cobra.Command, name string) (values []string) {
+	complete, ok := cmd.GetFlagCompletionFunc(name)
+	if !ok || complete == nil {
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Warn("flag completion function panicked", "flag", name, "command", cmd.CommandPath(), "panic", r)
+			values = nil
+		}
+	}()
+
+	completions, directive := complete(cmd, nil, "")
+	if directive&cobra.ShellCompDirectiveNoFileComp == 0 || directive&incompleteDirectives != 0 {
+		return nil
+	}
+
+	for _, completion := range completions {
+		value, _, _ := strings.Cut(completion, "\t")
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// enumValues converts completed values into JSON values of the schema type, reporting false if
+// one of them is not of that type.
+func enumValues(values []string, schemaType any) ([]any, bool) {
+	enum := make([]any, 0, len(values))
+	for _, value := range values {
+		v, ok := parseDefault(value, schemaType)
+		if !ok {
+			return nil, false
+		}
+		enum = append(enum, v)
+	}
+
+	return enum, true
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithCompletionEnums tests that closed sets of completed flag values become enums
+func TestWithCompletionEnums(t *testing.T) {
+	newRoot := func() *cobra.Command {
+		root := &cobra.Command{Use: "cli"}
+		deploy := &cobra.Command{Use: "deploy", Run: func(*cobra.Command, []string) {}}
+		deploy.Flags().String("region", "", "Region")
+		deploy.Flags().StringSlice("zone", nil, "Zones")
+		deploy.Flags().Int("replicas", 1, "Replicas")
+		deploy.Flags().String("config", "", "Config file")
+		deploy.Flags().String("image", "", "Image")
+		deploy.Flags().String("broken", "", "Broken completion")
+		root.AddCommand(deploy)
+
+		require.NoError(t, deploy.RegisterFlagCompletionFunc("region", cobra.FixedCompletions(
+			[]string{"us-east-1\tUS East", "eu-west-1\tEU West"}, cobra.ShellCompDirectiveNoFileComp)))
+		require.NoError(t, deploy.RegisterFlagCompletionFunc("zone", cobra.FixedCompletions(
+			[]string{"a", "b"}, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveKeepOrder)))
+		require.NoError(t, deploy.RegisterFlagCompletionFunc("replicas", cobra.FixedCompletions(
+			[]string{"1", "3", "5"}, cobra.ShellCompDirectiveNoFileComp)))
+		require.NoError(t, deploy.RegisterFlagCompletionFunc("config", cobra.FixedCompletions(
+			[]string{"yaml", "json"}, cobra.ShellCompDirectiveFilterFileExt)))
+		require.NoError(t, deploy.RegisterFlagCompletionFunc("image", cobra.FixedCompletions(
+			[]string{"registry/"}, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace)))
+		require.NoError(t, deploy.RegisterFlagCompletionFunc("broken", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			panic("no cluster")
+		}))
+		return root
+	}
+
+	flagSchemas := func(tools []Controller) map[string]any {
+		require.Len(t, tools, 1)
+		schema := tools[0].Tool.InputSchema.Properties[FlagsParam].(map[string]any)
+		return schema["properties"].(map[string]any)
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		properties := flagSchemas(NewGenerator(WithCompletionEnums()).FromRootCmd(newRoot()))
+
+		assert.Equal(t, []any{"us-east-1", "eu-west-1"}, properties["region"].(map[string]any)["enum"])
+		assert.Equal(t, []any{"a", "b"}, properties["zone"].(map[string]any)["items"].(map[string]any)["enum"])
+		assert.Equal(t, []any{int64(1), int64(3), int64(5)}, properties["replicas"].(map[string]any)["enum"])
+		for _, name := range []string{"config", "image", "broken"} {
+			assert.NotContains(t, properties[name], "enum", "flag %s", name)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		properties := flagSchemas(NewGenerator().FromRootCmd(newRoot()))
+		assert.NotContains(t, properties["region"], "enum")
+	})
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MetaContentEncoding is set to "gzip" if the stdout of the command was compressed by
+// WithCompression. The compressed blob carries it as well, with the MIME type of the
+// uncompressed output in MetaContentType.
+const (
+	MetaContentEncoding = "contentEncoding"
+	MetaContentType     = "contentType"
+)
+
+// WithCompression returns a GeneratorOption that gzips the stdout of successful commands
+// longer than threshold bytes, and returns it as an embedded blob resource of MIME type
+// application/gzip instead of inline text. This saves bandwidth on slow transports, such as
+// SSE over a network, but only clients that decompress the blob can read the output, so it is
+// off by default. Output that does not get smaller is returned unchanged. Outputs stored by
+// WithOutputResources are linked rather than compressed, and the output of tools formatted with
+// a Formatter other than PlainText, ErrorAware or JSONPassthrough is never compressed.
+//
+//	Example: NewGenerator(WithCompression(256 * 1024))
+func WithCompression(threshold int) GeneratorOption {
+	return func(g *Generator) {
+		g.compressThreshold = threshold
+	}
+}
+
+// compressStdout replaces the stdout of a successful tool result with a gzipped blob, if it is
+// longer than threshold and compresses. It reports whether the output was compressed.
+func compressStdout(logger *slog.Logger, toolName string, toolResult *mcp.CallToolResult, stdout []byte, threshold int) bool {
+	if threshold <= 0 || len(stdout) <= threshold || toolResult.IsError || len(toolResult.Content) == 0 {
+		return false
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write(stdout)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		logger.Warn("failed to compress output, returning it uncompressed", "tool", toolName, "error", err)
+		return false
+	}
+	if compressed.Len() >= len(stdout) {
+		return false
+	}
+
+	toolResult.Content[0] = mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+		Meta: &mcp.Meta{AdditionalFields: map[string]any{
+			MetaContentEncoding: "gzip",
+			MetaContentType:     http.DetectContentType(stdout),
+		}},
+		URI:      fmt.Sprintf("ophis://tools/%s/stdout", toolName),
+		MIMEType: "application/gzip",
+		Blob:     base64.StdEncoding.EncodeToString(compressed.Bytes()),
+	})
+	addMeta(toolResult, map[string]any{MetaContentEncoding: "gzip"})
+	return true
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompression tests that large outputs are returned gzipped
+func TestCompression(t *testing.T) {
+	stdout := strings.Repeat("pod-1 Running\n", 100)
+
+	t.Run("large output", func(t *testing.T) {
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get"), compress: 1024, executor: &recordingExecutor{result: NewExecResult([]byte(stdout), []byte("warning"), 0)}}
+
+		result, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		toolResult, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, result, err)
+		require.NoError(t, err)
+		require.Len(t, toolResult.Content, 2, "stderr stays inline")
+		assert.Equal(t, "gzip", toolResult.Meta.AdditionalFields[MetaContentEncoding])
+
+		embedded, ok := toolResult.Content[0].(mcp.EmbeddedResource)
+		require.True(t, ok)
+		blob := embedded.Resource.(mcp.BlobResourceContents)
+		assert.Equal(t, "application/gzip", blob.MIMEType)
+		assert.Equal(t, "gzip", blob.Meta.AdditionalFields[MetaContentEncoding])
+		assert.Equal(t, "text/plain; charset=utf-8", blob.Meta.AdditionalFields[MetaContentType])
+
+		compressed, err := base64.StdEncoding.DecodeString(blob.Blob)
+		require.NoError(t, err)
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, stdout, string(decompressed))
+	})
+
+	tests := map[string]struct {
+		threshold int
+		stdout    string
+		err       error
+		formatter Formatter
+	}{
+		"below the threshold": {threshold: 4096, stdout: stdout},
+		"disabled":            {stdout: stdout},
+		"failed command":      {threshold: 1024, stdout: stdout, err: errors.New("exit status 1")},
+		"incompressible":      {threshold: 8, stdout: "0a8Fz3kQ"},
+		"other formatter":     {threshold: 1024, stdout: stdout, formatter: Markdown{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := &Controller{Tool: mcp.NewTool("cli_get"), compress: tt.threshold, formatter: tt.formatter, executor: &recordingExecutor{result: NewExecResult([]byte(tt.stdout), nil, 0), err: tt.err}}
+
+			result, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+			toolResult, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, result, err)
+			require.NoError(t, err)
+			assert.Equal(t, tt.err != nil, toolResult.IsError)
+			assert.IsType(t, mcp.TextContent{}, toolResult.Content[0])
+			assert.NotContains(t, toolResult.Meta.AdditionalFields, MetaContentEncoding)
+		})
+	}
+
+	t.Run("generator option", func(t *testing.T) {
+		root := &cobra.Command{Use: "cli"}
+		root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+		ctrls, err := NewGenerator(WithCompression(1024)).Generate(root)
+		require.NoError(t, err)
+		assert.Equal(t, 1024, ctrls[0].compress)
+	})
+}
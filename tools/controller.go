@@ -1,133 +1,947 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os"
-	"os/exec"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	sq "github.com/kballard/go-shellquote"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Constants for MCP parameter names and error messages
 const (
 	MCPCommandName   = "mcp"
 	StartCommandName = "start"
-	// PositionalArgsParam is the parameter name for positional arguments
+	// PositionalArgsParam is the parameter name for positional arguments.
+	// It accepts an array of strings passed verbatim, which is the safe choice for
+	// programmatic callers, or a single string split using shell quoting rules.
 	PositionalArgsParam = "args"
 	FlagsParam          = "flags"
+	// StdinParam is the optional parameter name for data written to the command's standard input
+	StdinParam = "stdin"
+	// CwdParam is the optional parameter name for the working directory of the command.
+	// It is only available if the generator was configured with WithWorkingDirRoots.
+	CwdParam = "cwd"
+	// DryRunParam is the optional parameter name for previewing the command instead of running it.
+	// It is only available if the generator was configured with WithDryRun.
+	DryRunParam = "dry_run"
+	// PassthroughArgsParam is the optional parameter name for arguments forwarded verbatim after
+	// a "--" separator. It is only available for tools selected with WithPassthroughArgs.
+	PassthroughArgsParam = "passthrough_args"
 )
 
+// ErrTimeout is returned by Execute when a command exceeds the Controller's Timeout.
+var ErrTimeout = errors.New("command timed out")
+
+// ErrInvalidArguments is returned by Execute when the tool arguments of a request are
+// rejected before running the command.
+var ErrInvalidArguments = errors.New("invalid tool arguments")
+
 // Controller represents an MCP tool with its associated logic for execution and output handling.
 type Controller struct {
-	Tool    mcp.Tool `json:"tool"`
-	handler Handler
+	Tool mcp.Tool `json:"tool"`
+	// Timeout limits how long a single execution may run before the process is killed.
+	// A zero Timeout means no limit beyond the cancellation of the incoming context.
+	// Clients may shorten it for a single call with the MetaRequestTimeout request metadata.
+	Timeout time.Duration `json:"-"`
+	// MaxOutputBytes limits the size of stdout and stderr, each, returned to the client.
+	// Longer output is truncated with a marker, and the result metadata records the truncation.
+	// A zero MaxOutputBytes means no limit.
+	MaxOutputBytes int `json:"-"`
+	// Env sets environment variables of the executed command. It is applied on top of the
+	// server variables passed through by WithEnvPassthrough or WithInheritedEnv.
+	Env map[string]string `json:"-"`
+
+	handler     Handler
+	cmdHandler  ResultHandler           // replaces handler and the default handling for this command, nil for none
+	formatter   Formatter               // builds the content of the default handling, nil for PlainText
+	logger      *slog.Logger            // logs execution, nil to discard
+	sensitive   *sensitiveFlags         // flags whose values are redacted in logs, nil for the defaults
+	audit       AuditFunc               // receives a record of every execution, nil for none
+	metrics     Metrics                 // receives every execution, nil for none
+	tracer      trace.Tracer            // starts a span for every execution, nil for none
+	authorize   AuthorizeFunc           // approves commands before they run, nil to allow all
+	dryRun      bool                    // whether DryRunParam is accepted
+	reportUsage bool                    // whether the resource usage of executions is added to the metadata
+	passthrough bool                    // whether PassthroughArgsParam is accepted
+	cache       *toolCache              // stores successful executions, nil if the tool is not cached
+	flights     *flightGroup            // coalesces identical concurrent calls, nil if they run separately
+	outputs     *OutputResources        // stores large outputs served as resources, nil to inline them
+	compress    int                     // stdout longer than this is returned gzipped, 0 to never compress
+	errStderr   int                     // bytes of stderr included in the errors of failed commands, 0 for none
+	matchFlags  bool                    // whether flag names are matched ignoring case, dashes and underscores
+	strictArgs  bool                    // whether malformed argument strings are rejected instead of split on spaces
+	paths       *pathPolicy             // confines the values of path flags, nil for no confinement
+	path        []string                // command path below the root of the tree, e.g. ["sub", "command"]
+	command     string                  // full command path, e.g. "cli sub command"
+	alias       bool                    // whether the tool was generated for an alias of the command
+	executor    Executor                // runs the command, nil for a DefaultExecutor
+	limiter     *limiter                // bounds concurrent executions, shared by the tools of a Generator
+	pool        *workerPool             // runs the commands, shared by the tools of a Generator, nil to run them directly
+	env         envPolicy               // server environment variables passed to the command
+	requestEnv  map[string]RequestValue // request metadata passed to the command, by variable name
+	roots       []string                // directories the working directory may be chosen from
+	stream      bool                    // whether output is sent to the client while the command runs
+	keepANSI    bool                    // whether ANSI escape sequences are kept in the output
+	structured  bool                    // whether a JSON object on stdout is returned as structured content
+	usage       string                  // usage text of the command, returned with argument errors
+	lazy        *lazySchema             // builds the input schema and usage text, nil if they were built eagerly
+	short       string                  // short description of the command, listed by ListCommandsTool
+	example     string                  // examples of the command, turned into prompts by ExamplePrompts
+	flags       *pflag.FlagSet          // flag definitions of the command
+	injected    map[string]string       // flags set on every call, replacing the values of the client
+	args        *argsSpec               // positional argument constraints, nil if unconstrained
+	checkArgs   func([]string) error    // calls the Args validator of the command, nil to skip it
 }
 
 // Handle processes the result of a tool execution into an MCP response.
-func (c *Controller) Handle(ctx context.Context, request mcp.CallToolRequest, data []byte, err error) (*mcp.CallToolResult, error) {
-	if c.handler != nil {
+// Custom handlers receive the combined stdout and stderr output, unlike the ResultHandler of
+// WithCommandHandler, which takes precedence.
+// The exit code of the process is attached to the result metadata.
+// A failed execution whose handler returned no content, or only blank text, is returned as an
+// error result describing the failure, with the exit code of the process.
+// Errors caused by invalid flags or arguments are followed by the usage text of the command,
+// so that the client can correct the call.
+func (c *Controller) Handle(ctx context.Context, request mcp.CallToolRequest, result *ExecResult, err error) (*mcp.CallToolResult, error) {
+	if err != nil {
+		attrs := []any{"tool", c.Tool.Name, "error", err}
+		if result != nil {
+			attrs = append(attrs, "stdout", string(result.Stdout), "stderr", string(result.Stderr))
+		}
+		c.log().ErrorContext(ctx, "command execution failed", attrs...)
+	}
+
+	execErr := err
+	var toolResult *mcp.CallToolResult
+	if c.cmdHandler != nil {
+		toolResult, err = c.cmdHandler(ctx, request, result, err)
+	} else if c.handler != nil {
 		// Use custom handler if provided
-		return c.handler(ctx, request, data, err)
+		toolResult, err = c.handler(ctx, request, result.Combined(), err)
+	} else {
+		// Default handling: return output as formatted by the formatter of the tool
+		formatter := c.formatter
+		if formatter == nil {
+			formatter = PlainText{}
+		}
+		toolResult, err = formatResult(c.Tool.Name, formatter, result, err)
+		if toolResult != nil && c.structured && result != nil {
+			addStructuredContent(c.log(), toolResult, result.Stdout)
+		}
+		replaceable := toolResult != nil && result != nil && returnsStdout(formatter)
+		stored := replaceable && c.outputs != nil &&
+			c.outputs.storeStdout(ctx, c.log(), c.Tool.Name, toolResult, result.Stdout)
+		if replaceable && !stored {
+			compressStdout(c.log(), c.Tool.Name, toolResult, result.Stdout, c.compress)
+		}
+	}
+
+	if execErr != nil && toolResult != nil && !hasContent(toolResult) {
+		// Never let a command that failed quietly look like one that succeeded without output
+		failed := result
+		if failed == nil {
+			failed = &ExecResult{ExitCode: -1}
+		}
+		toolResult.Content = []mcp.Content{mcp.NewTextContent(failureMessage(failed, execErr))}
+		toolResult.IsError = true
 	}
 
-	// Default handling: return output as plain text
-	return defaultHandler(ctx, request, data, err)
+	if toolResult != nil && c.outputs != nil && result != nil && result.outputFile != "" {
+		c.outputs.addArtifact(ctx, c.log(), c.Tool.Name, toolResult, result.outputFile)
+	}
+
+	if toolResult != nil {
+		if toolResult.IsError && isUsageError(result, execErr) {
+			if usage := c.usageText(); usage != "" {
+				toolResult.Content = append(toolResult.Content, mcp.NewTextContent(usage))
+			}
+		}
+		addMeta(toolResult, result.meta())
+		if c.reportUsage {
+			addMeta(toolResult, result.usageMeta())
+		}
+	}
+
+	return toolResult, err
+}
+
+// log returns the logger of the controller, which discards everything if none was set.
+func (c *Controller) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+
+	return c.logger
+}
+
+// addMeta merges fields into the metadata of a tool result.
+func addMeta(toolResult *mcp.CallToolResult, fields map[string]any) {
+	if len(fields) == 0 {
+		return
+	}
+
+	if toolResult.Meta == nil {
+		toolResult.Meta = &mcp.Meta{}
+	}
+	if toolResult.Meta.AdditionalFields == nil {
+		toolResult.Meta.AdditionalFields = map[string]any{}
+	}
+
+	maps.Copy(toolResult.Meta.AdditionalFields, fields)
 }
 
 // Execute runs the tool command with the provided request.
-func (c *Controller) Execute(ctx context.Context, request mcp.CallToolRequest) ([]byte, error) {
-	// Get the executable path
-	executablePath, err := os.Executable()
+// Stdout and stderr are captured separately; use ExecResult.Combined for the interleaved output.
+func (c *Controller) Execute(ctx context.Context, request mcp.CallToolRequest) (result *ExecResult, err error) {
+	var cmdArgs []string
+	if c.tracer != nil {
+		var endSpan func([]string, *ExecResult, error, any)
+		ctx, endSpan = c.startSpan(ctx)
+		defer func() {
+			// End the span of a panicking execution too, then let the panic continue
+			r := recover()
+			endSpan(cmdArgs, result, err, r)
+			if r != nil {
+				panic(r)
+			}
+		}()
+	}
+	if c.audit != nil {
+		auditCtx, start := ctx, time.Now()
+		defer func() { c.auditExecution(auditCtx, start, cmdArgs, result, err) }()
+	}
+	callCtx := ctx
+	defer func() {
+		c.recordAttempt(callCtx, result, err)
+		recordBreakerAttempt(callCtx, err)
+	}()
+	var ran bool
+	if c.metrics != nil {
+		defer func() {
+			if !ran && err != nil {
+				c.metrics.CallRejected(callCtx, c.Tool.Name, err)
+			}
+		}()
+	}
+
+	var dir string
+	cmdArgs, dir, err = c.resolveArgs(ctx, request)
 	if err != nil {
-		slog.Error("failed to get executable path", "error", err)
-		return nil, fmt.Errorf("failed to get executable path: %w", err)
+		return nil, err
+	}
+	timeout, err := c.callTimeout(request)
+	if err != nil {
+		return nil, err
 	}
 
-	// Build command arguments
-	cmdArgs := c.buildCommandArgs(request)
+	// Request metadata comes last, so that it replaces variables of the same name
+	inv := Invocation{Args: cmdArgs, Env: append(c.environ(), c.requestEnviron(ctx, request)...), Dir: dir}
+	if c.tracer != nil {
+		inv.Env = append(inv.Env, traceEnviron(ctx)...)
+	}
+	var stdin string
+	if stdinValue, ok := request.GetArguments()[StdinParam]; ok && stdinValue != nil {
+		stdin, ok = stdinValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a string, got %T", ErrInvalidArguments, StdinParam, stdinValue)
+		}
+		inv.Stdin = strings.NewReader(stdin)
+	}
+
+	if dryRunValue, ok := request.GetArguments()[DryRunParam]; ok && dryRunValue != nil {
+		dryRun, ok := dryRunValue.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a boolean, got %T", ErrInvalidArguments, DryRunParam, dryRunValue)
+		}
+		if dryRun {
+			if !c.dryRun {
+				return nil, fmt.Errorf("%w: %s is not supported by this tool", ErrInvalidArguments, DryRunParam)
+			}
+			c.log().DebugContext(ctx, "dry run", "tool", c.Tool.Name, "args", c.sensitive.args(cmdArgs))
+			return dryRunResult(inv, c.executor), nil
+		}
+	}
+
+	if c.authorize != nil {
+		if err := c.authorize(ctx, c.Tool.Name, slices.Clone(cmdArgs)); err != nil {
+			c.log().WarnContext(ctx, "command not authorized", "tool", c.Tool.Name, "args", c.sensitive.args(cmdArgs), "error", err)
+			return nil, fmt.Errorf("%w: %w", ErrUnauthorized, err)
+		}
+	}
 
-	slog.Debug("executing command",
+	// Per-call variables would keep identical calls from sharing an output
+	keyInv := inv
+	keyInv.Env = c.keyEnviron(inv.Env)
+	var cacheKey string
+	if c.cache != nil {
+		cacheKey = invocationKey(c.Tool.Name, keyInv, stdin)
+		cached, err := c.cache.get(ctx, cacheKey)
+		if err != nil {
+			c.log().WarnContext(ctx, "failed to read cached output", "tool", c.Tool.Name, "error", err)
+		} else if cached != nil {
+			c.log().DebugContext(ctx, "returning cached output", "tool", c.Tool.Name, "args", c.sensitive.args(cmdArgs))
+			return cached, nil
+		}
+	}
+
+	if c.flights != nil {
+		result, ran, err = c.flights.do(ctx, invocationKey(c.Tool.Name, keyInv, stdin), func(ctx context.Context) (*ExecResult, bool, error) {
+			return c.execute(ctx, request, inv, timeout, cacheKey)
+		})
+	} else {
+		result, ran, err = c.execute(ctx, request, inv, timeout, cacheKey)
+	}
+	return result, err
+}
+
+// execute runs the command of inv, waiting for a free slot first, and post-processes its output.
+// It reports whether the command was run, rather than rejected by the limiter or the pool.
+func (c *Controller) execute(ctx context.Context, request mcp.CallToolRequest, inv Invocation, timeout time.Duration, cacheKey string) (result *ExecResult, ran bool, err error) {
+	if c.stream {
+		if notifier := newOutputNotifier(ctx, c.log(), c.Tool.Name, request); notifier != nil {
+			notifier.strip = !c.keepANSI
+			inv.OnOutput = notifier.send
+		}
+	}
+
+	var outputFile string
+	if c.outputs != nil {
+		if outputFile, err = c.outputs.outputPath(); err != nil {
+			return nil, false, err
+		}
+		inv.Env = append(inv.Env, OutputFileEnv+"="+outputFile)
+	}
+
+	c.log().DebugContext(ctx, "executing command",
 		"tool", c.Tool.Name,
-		"executable", executablePath,
-		"args", cmdArgs,
+		"args", c.sensitive.args(inv.Args),
+		"stdin", inv.Stdin != nil,
+		"dir", inv.Dir,
+		"env", envNames(inv.Env),
 	)
 
-	// Create exec.Cmd and run it
-	cmd := exec.CommandContext(ctx, executablePath, cmdArgs...)
-	return cmd.CombinedOutput()
+	// Wait for a free slot before starting the timeout, which only limits the run itself
+	if c.limiter != nil {
+		release, err := c.limiter.acquire(ctx)
+		if err != nil {
+			c.log().WarnContext(ctx, "command not started", "tool", c.Tool.Name, "error", err)
+			return nil, false, err
+		}
+		defer release()
+	}
+
+	if c.pool != nil {
+		// The timeout starts when a worker picks up the call, like after waiting for a free slot
+		var runErr error
+		if err := c.pool.run(ctx, func() {
+			ran = true
+			result, runErr = c.run(ctx, inv, timeout)
+		}); err != nil {
+			c.log().WarnContext(ctx, "command not started", "tool", c.Tool.Name, "error", err)
+			return nil, false, err
+		}
+		err = runErr
+	} else {
+		ran = true
+		result, err = c.run(ctx, inv, timeout)
+	}
+
+	if result != nil {
+		result.outputFile = outputFile
+	}
+
+	if result != nil && !c.keepANSI {
+		result.stripANSI()
+	}
+
+	if result != nil && c.MaxOutputBytes > 0 {
+		result.truncate(c.MaxOutputBytes)
+	}
+
+	if err != nil && result != nil && c.errStderr > 0 && len(bytes.TrimSpace(result.Stderr)) > 0 {
+		err = &stderrError{err: err, stderr: stderrExcerpt(result.Stderr, c.errStderr)}
+	}
+
+	// Only successful executions are cached, so that a failure is retried on the next call
+	if c.cache != nil && err == nil && result != nil && result.ExitCode == 0 {
+		if err := c.cache.set(ctx, cacheKey, result); err != nil {
+			c.log().WarnContext(ctx, "failed to cache output", "tool", c.Tool.Name, "error", err)
+		}
+	}
+
+	if err != nil && result != nil {
+		c.log().DebugContext(ctx, "command failed",
+			"tool", c.Tool.Name,
+			"exit_code", result.ExitCode,
+			"killed", result.Killed,
+			"timed_out", result.TimedOut,
+			"error", err,
+		)
+	}
+
+	return result, ran, err
+}
+
+// run runs the command of inv with the executor of the tool, killing it after timeout unless it
+// is zero. The error of a command killed because a deadline passed wraps ErrTimeout.
+func (c *Controller) run(callCtx context.Context, inv Invocation, timeout time.Duration) (*ExecResult, error) {
+	ctx := callCtx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	executor := c.executor
+	if executor == nil {
+		executor = &DefaultExecutor{}
+	}
+
+	if c.metrics != nil {
+		c.metrics.ExecStarted(callCtx, c.Tool.Name)
+	}
+	start := time.Now()
+	result, err := executor.Run(ctx, inv)
+	if result.started() && result.Duration == 0 {
+		// Executors that do not measure the run themselves include the time spent by the executor
+		result.Duration = time.Since(start)
+	}
+	if timeoutErr := timeoutError(callCtx, ctx, timeout, err); timeoutErr != nil {
+		// Keep the partial output, but report the timeout rather than a generic failure
+		if result == nil {
+			result = &ExecResult{ExitCode: -1}
+		}
+		result.TimedOut = true
+		err = timeoutErr
+	}
+	if c.metrics != nil {
+		c.metrics.ExecFinished(callCtx, c.Tool.Name, execMetrics(result, err, time.Since(start)))
+	}
+
+	return result, err
+}
+
+// BuildArgs returns the arguments Execute would run the executable with for request, starting
+// with the command path below the root command, e.g. ["get", "--output=json", "--", "pods"].
+// It returns the same error as Execute for invalid tool arguments, without running anything.
+func (c *Controller) BuildArgs(request mcp.CallToolRequest) ([]string, error) {
+	args, _, err := c.resolveArgs(context.Background(), request)
+	return args, err
+}
+
+// resolveArgs builds the command line arguments of request, and resolves its working directory.
+func (c *Controller) resolveArgs(ctx context.Context, request mcp.CallToolRequest) (args []string, dir string, err error) {
+	// Resolve the working directory first, since relative path flags are resolved against it
+	if cwdValue, ok := request.GetArguments()[CwdParam]; ok && cwdValue != nil && cwdValue != "" {
+		cwd, ok := cwdValue.(string)
+		if !ok {
+			return nil, "", fmt.Errorf("%w: %s must be a string, got %T", ErrInvalidArguments, CwdParam, cwdValue)
+		}
+
+		dir, err = resolveDir(cwd, c.roots)
+		if err != nil {
+			c.log().WarnContext(ctx, "rejected working directory", "tool", c.Tool.Name, "error", err)
+			return nil, "", fmt.Errorf("%w: %w", ErrInvalidArguments, err)
+		}
+	}
+
+	args, err = c.buildCommandArgs(request, dir)
+	if err != nil {
+		c.log().WarnContext(ctx, "invalid tool arguments", "tool", c.Tool.Name, "error", err)
+		return nil, "", fmt.Errorf("%w: %w", ErrInvalidArguments, err)
+	}
+
+	return args, dir, nil
 }
 
 // buildCommandArgs builds the command line arguments from the tool and request.
-func (c *Controller) buildCommandArgs(request mcp.CallToolRequest) []string {
+// dir is the working directory of the command, or empty for the working directory of the server.
+func (c *Controller) buildCommandArgs(request mcp.CallToolRequest, dir string) ([]string, error) {
 	message := request.GetArguments()
+	if _, ok := request.Params.Arguments.(map[string]any); !ok && request.Params.Arguments != nil {
+		return nil, fmt.Errorf("the arguments must be an object, got %T", request.Params.Arguments)
+	}
+
+	logger := c.log()
 
-	// Start with the command path (e.g., "root_sub_command" -> ["root", "sub", "command"])
-	// And remove the root command prefix
-	args := strings.Split(c.Tool.Name, "_")[1:]
-	slog.Debug("initial command arguments", "args", args)
+	// Build the flags, rejecting any other shape than an object rather than dropping them
+	var flagMap map[string]any
+	if flagsValue, ok := message[FlagsParam]; ok && flagsValue != nil {
+		if flagMap, ok = flagsValue.(map[string]any); !ok {
+			return nil, fmt.Errorf("%s must be an object of flag names to values, got %T", FlagsParam, flagsValue)
+		}
+	}
+	if flagMap != nil && c.matchFlags && c.flags != nil {
+		var err error
+		if flagMap, err = matchFlagNames(flagMap, c.flags); err != nil {
+			return nil, err
+		}
+	}
+	if flagMap != nil && c.paths != nil && c.flags != nil {
+		var err error
+		if flagMap, err = c.paths.resolveFlags(flagMap, c.flags, dir); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.injected) > 0 {
+		if flagMap != nil {
+			var err error
+			if flagMap, err = normalizeFlagNames(flagMap, c.flags); err != nil {
+				return nil, err
+			}
+		}
+		flagMap = c.injectFlags(flagMap)
+	}
+	var flagArgs []string
+	if flagMap != nil {
+		var err error
+		if flagArgs, err = buildFlagArgs(logger, flagMap, c.flags, c.sensitive); err != nil {
+			return nil, err
+		}
+	}
 
-	// Add flags
-	if flagsValue, ok := message[FlagsParam]; ok {
-		if flagMap, ok := flagsValue.(map[string]any); ok {
-			flagArgs := buildFlagArgs(flagMap)
-			args = append(args, flagArgs...)
+	// Report missing required flags and violated flag groups before spawning a command that is bound to fail
+	if c.flags != nil {
+		if err := checkRequiredFlags(flagMap, c.flags); err != nil {
+			return nil, err
+		}
+		if err := checkFlagGroups(flagMap, c.flags); err != nil {
+			return nil, err
 		}
 	}
 
-	// Add positional arguments
+	// Add positional arguments after a "--" terminator, so that user data
+	// beginning with a dash can never be reinterpreted as a flag. Passthrough
+	// arguments take the place after the terminator if they are given.
+	var parsedArgs []string
 	if argsValue, ok := message[PositionalArgsParam]; ok {
-		if argsStr, ok := argsValue.(string); ok && argsStr != "" {
-			parsedArgs := parseArgumentString(argsStr)
-			args = append(args, parsedArgs...)
+		switch v := argsValue.(type) {
+		case string:
+			if !c.strictArgs {
+				parsedArgs = parseArgumentString(logger, v)
+				break
+			}
+			var err error
+			if parsedArgs, err = splitArgumentString(v); err != nil {
+				return nil, err
+			}
+		case []any:
+			// Array mode: every element is passed through verbatim, without shell parsing
+			for i, item := range v {
+				arg, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("positional argument %d must be a string, got %T", i, item)
+				}
+				parsedArgs = append(parsedArgs, arg)
+			}
+		case nil:
+		default:
+			return nil, fmt.Errorf("%s must be an array of strings or a string, got %T", PositionalArgsParam, v)
 		}
 	}
 
-	return args
+	passthrough, err := c.passthroughArgs(message)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start with the command path below the root command, as recorded when the tool was built,
+	// in a slice sized for the arguments that follow
+	args := make([]string, 0, len(c.path)+len(flagArgs)+len(parsedArgs)+len(passthrough)+2)
+	args = append(args, c.path...)
+	args = append(args, flagArgs...)
+	switch {
+	case len(passthrough) > 0:
+		// Keep the positional arguments before the separator, so the command can tell them apart
+		if err := checkBeforeSeparator(parsedArgs); err != nil {
+			return nil, err
+		}
+		args = append(args, parsedArgs...)
+		args = append(args, "--")
+		args = append(args, passthrough...)
+	case len(parsedArgs) > 0:
+		args = append(args, "--")
+		args = append(args, parsedArgs...)
+	}
+
+	// Reject arguments the command would refuse anyway, before spawning it.
+	// Like cobra, the constraints apply to the arguments on both sides of the separator.
+	if c.args != nil {
+		if err := c.args.validate(append(parsedArgs, passthrough...)); err != nil {
+			return nil, err
+		}
+	}
+	if c.checkArgs != nil {
+		if err := c.checkArgs(append(parsedArgs, passthrough...)); err != nil {
+			return nil, err
+		}
+	}
+
+	return args, nil
 }
 
 // buildFlagArgs converts a flag map to command line flag arguments.
-func buildFlagArgs(flagMap map[string]any) []string {
-	var args []string
+// Flags that are not defined in flags are rejected.
+// Flags are emitted in sorted name order so the generated command line is reproducible.
+// Array values are emitted once per element, as expected by repeated and slice flags.
+// Count flags, e.g. -v for verbosity, are emitted as often as their value says.
+// Flags with an optional value, e.g. --log-level, are emitted without one for true.
+// flags holds the flag definitions of the command, and may be nil if they are unknown.
+// The values of sensitive flags are redacted in log lines.
+func buildFlagArgs(logger *slog.Logger, flagMap map[string]any, flags *pflag.FlagSet, sensitive *sensitiveFlags) ([]string, error) {
+	flagMap, err := normalizeFlagNames(flagMap, flags)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnknownFlags(flagMap, flags); err != nil {
+		return nil, err
+	}
 
-	for name, value := range flagMap {
+	names := make([]string, 0, len(flagMap))
+	for name := range flagMap {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	debug := logger.Enabled(context.Background(), slog.LevelDebug)
+	var args []string
+	for _, name := range names {
+		value := flagMap[name]
 		if name == "" || value == nil {
 			continue
 		}
 
+		var flag *pflag.Flag
+		if flags != nil {
+			flag = flags.Lookup(name)
+		}
+
+		if isCountFlag(flag) {
+			count, err := flagCount(value)
+			if err != nil {
+				return nil, fmt.Errorf("flag %q: %w", name, err)
+			}
+			if debug {
+				logger.Debug("adding count flag argument", "flag_name", name, "count", count)
+			}
+			for range count {
+				args = append(args, "--"+name)
+			}
+			continue
+		}
+
 		if items, ok := value.([]any); ok {
+			if err := checkSliceItems(items); err != nil {
+				return nil, fmt.Errorf("flag %q: %w", name, err)
+			}
+
 			for _, item := range items {
-				slog.Debug("adding flag slice argument", "flag_name", name, "input", value, "value", item)
-				args = append(args, parseFlagArgValue(name, item)...)
+				if debug {
+					logger.Debug("adding flag slice argument",
+						"flag_name", name,
+						"input", sensitive.value(name, value),
+						"value", sensitive.value(name, item),
+					)
+				}
+				args = appendFlagArg(args, logger, debug, sensitive, flag, name, item)
 			}
 
 			continue
 		}
 
-		args = append(args, parseFlagArgValue(name, value)...)
+		args = appendFlagArg(args, logger, debug, sensitive, flag, name, value)
 	}
 
-	return args
+	return args, nil
+}
+
+// normalizeFlagNames maps shorthand flag names (e.g. "v") to their long form (e.g. "verbose"),
+// so that flags are always emitted as --longname. Leading dashes are ignored.
+// It is an error to provide both the shorthand and the long name of a flag.
+func normalizeFlagNames(flagMap map[string]any, flags *pflag.FlagSet) (map[string]any, error) {
+	if usesLongFlagNames(flagMap, flags) {
+		return flagMap, nil
+	}
+
+	normalized := make(map[string]any, len(flagMap))
+	for name, value := range flagMap {
+		longName := longFlagName(name, flags)
+
+		if _, ok := normalized[longName]; ok {
+			return nil, fmt.Errorf("flag %q was provided more than once", longName)
+		}
+		normalized[longName] = value
+	}
+
+	return normalized, nil
+}
+
+// longFlagName returns the long name of a flag name given to normalizeFlagNames.
+func longFlagName(name string, flags *pflag.FlagSet) string {
+	longName := strings.TrimLeft(name, "-")
+	if flags != nil && len(longName) == 1 && flags.Lookup(longName) == nil {
+		if flag := flags.ShorthandLookup(longName); flag != nil {
+			return flag.Name
+		}
+	}
+
+	return longName
+}
+
+// usesLongFlagNames reports whether every name in flagMap is a long flag name, so that
+// normalizeFlagNames can return the map without copying it.
+func usesLongFlagNames(flagMap map[string]any, flags *pflag.FlagSet) bool {
+	for name := range flagMap {
+		if longFlagName(name, flags) != name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkUnknownFlags returns an error listing the names in flagMap that are not defined in flags,
+// so that a made-up flag is not passed on to a command that may ignore or misparse it.
+func checkUnknownFlags(flagMap map[string]any, flags *pflag.FlagSet) error {
+	if flags == nil {
+		return nil
+	}
+
+	var unknown []string
+	for name := range flagMap {
+		if name != "" && flags.Lookup(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return unknownFlagsError(unknown, flags)
+	}
+
+	return nil
+}
+
+// unknownFlagsError returns an error naming unknown flags and the valid flags of a command.
+func unknownFlagsError(unknown []string, flags *pflag.FlagSet) error {
+	noun := "flag"
+	if len(unknown) > 1 {
+		noun = "flags"
+	}
+
+	return fmt.Errorf("unknown %s %s, %s", noun, joinFlags(unknown, "and"), validFlags(flags))
+}
+
+// validFlags describes the flags a command accepts.
+func validFlags(flags *pflag.FlagSet) string {
+	var valid []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		valid = append(valid, flag.Name)
+	})
+
+	if len(valid) == 0 {
+		return "the command has no flags"
+	}
+
+	return "valid flags are: " + strings.Join(valid, ", ")
+}
+
+// checkRequiredFlags returns an error listing the required flags that have no value in flagMap.
+func checkRequiredFlags(flagMap map[string]any, flags *pflag.FlagSet) error {
+	required := requiredFlags(flags)
+	if len(required) == 0 {
+		return nil
+	}
+
+	// Duplicate names were already rejected while building the flag arguments
+	flagMap, _ = normalizeFlagNames(flagMap, flags)
+
+	var missing []string
+	for _, name := range required {
+		if flagMap[name] == nil {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required flags: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
 }
 
-func parseFlagArgValue(name string, value any) (retVal []string) {
-	if value != nil {
-		switch v := value.(type) {
-		case bool:
-			if v {
-				slog.Debug("adding boolean flag argument", "flag_name", name, "value", v)
-				retVal = append(retVal, fmt.Sprintf("--%s", name))
+// checkSliceItems verifies that every element of an array flag value is a scalar of the same JSON type.
+func checkSliceItems(items []any) error {
+	var first string
+	for i, item := range items {
+		kind := jsonKind(item)
+		switch {
+		case kind == "":
+			return fmt.Errorf("array element %d has unsupported type %T", i, item)
+		case i == 0:
+			first = kind
+		case kind != first:
+			return fmt.Errorf("array elements must all be the same type: element 0 is a %s, element %d is a %s", first, i, kind)
+		}
+	}
+
+	return nil
+}
+
+// isCountFlag reports whether flag is a count flag, which is incremented every time it is
+// given, e.g. -vvv for a verbosity of 3.
+func isCountFlag(flag *pflag.Flag) bool {
+	return flag != nil && flag.Value.Type() == "count"
+}
+
+// hasOptionalValue reports whether flag takes a value that may be omitted, in which case its
+// NoOptDefVal is used, e.g. --log-level alone for --log-level=debug. Boolean and count flags
+// never take a value from the tool inputs, and are not included.
+func hasOptionalValue(flag *pflag.Flag) bool {
+	if flag == nil || flag.NoOptDefVal == "" {
+		return false
+	}
+
+	switch flag.Value.Type() {
+	case "bool", "count":
+		return false
+	}
+
+	return true
+}
+
+// flagCount returns the number of times a count flag with value is given, which must be a
+// non-negative integer. Integer strings are accepted as well, as set by WithInjectedFlags.
+func flagCount(value any) (int, error) {
+	switch v := value.(type) {
+	case float64:
+		if v >= 0 && v == math.Trunc(v) && v <= math.MaxInt32 {
+			return int(v), nil
+		}
+	case int:
+		if v >= 0 {
+			return v, nil
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && n >= 0 && n <= math.MaxInt32 {
+			return n, nil
+		}
+	}
+
+	return 0, fmt.Errorf("a count flag must be a non-negative integer, got %v", value)
+}
+
+// jsonKind returns the JSON type name of a scalar value, or "" for anything else.
+func jsonKind(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "number"
+	}
+
+	return ""
+}
+
+// appendFlagArg appends the command line argument of a single flag value to args.
+// Values are emitted in the --name=value form so that a value beginning with
+// a dash is not parsed as the next flag.
+//
+// A true boolean is emitted as the bare --name. A false boolean is dropped, unless
+// the flag defaults to true, in which case --name=false is emitted. The items of slice flags,
+// such as a boolSlice, are always emitted as --name=value, since their flags take a value.
+// Strings such as
+// "true", "false", "1" and "0" given for a boolean flag are treated as booleans, since
+// clients often send them quoted. Log lines are only built if debug is set, to keep calls
+// free of their allocations otherwise.
+func appendFlagArg(args []string, logger *slog.Logger, debug bool, sensitive *sensitiveFlags, flag *pflag.Flag, name string, value any) []string {
+	value = coerceBoolFlagValue(flag, value)
+	switch v := value.(type) {
+	case nil:
+	case bool:
+		if isSliceFlag(flag) {
+			if debug {
+				logger.Debug("adding boolean slice flag argument", "flag_name", name, "value", v)
 			}
-		default:
-			slog.Debug("adding flag argument", "flag_name", name, "value", value)
-			retVal = append(retVal, fmt.Sprintf("--%s", name), fmt.Sprintf("%v", value))
+			args = append(args, "--"+name+"="+strconv.FormatBool(v))
+			break
 		}
+		if v {
+			if debug {
+				logger.Debug("adding boolean flag argument", "flag_name", name, "value", v)
+			}
+			args = append(args, "--"+name)
+		} else if flag != nil && flag.DefValue == "true" && !hasOptionalValue(flag) {
+			if debug {
+				logger.Debug("adding negated boolean flag argument", "flag_name", name, "value", v)
+			}
+			args = append(args, "--"+name+"=false")
+		}
+	default:
+		if debug {
+			logger.Debug("adding flag argument", "flag_name", name, "value", sensitive.value(name, value))
+		}
+		args = append(args, "--"+name+"="+formatFlagValue(value))
+	}
+
+	return args
+}
+
+// isSliceFlag reports whether flag takes a list of values, such as a boolSlice.
+func isSliceFlag(flag *pflag.Flag) bool {
+	if flag == nil {
+		return false
+	}
+
+	_, ok := flag.Value.(pflag.SliceValue)
+	return ok
+}
+
+// coerceBoolFlagValue returns the boolean of a string value of a boolean flag, parsed like
+// pflag does, or value unchanged for other flags and values that are not booleans.
+func coerceBoolFlagValue(flag *pflag.Flag, value any) any {
+	s, ok := value.(string)
+	if !ok || flag == nil || flag.Value.Type() != "bool" {
+		return value
+	}
+
+	if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+		return b
 	}
 
-	return retVal
+	return value
+}
+
+// formatFlagValue formats a flag value like fmt's %v verb, without its allocations for the
+// types JSON decodes to. Numbers are never written with an exponent, e.g. 1000000 rather than
+// 1e+06, since JSON decodes every number to a float64 and the parsers of integer flags reject
+// exponents.
+func formatFlagValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
 }
 
 // parseArgumentString provides shell-like argument parsing with proper quote handling.
@@ -145,21 +959,32 @@ func parseFlagArgValue(name string, value any) (retVal []string) {
 //
 // If parsing fails due to malformed input (e.g., unterminated quotes), the function
 // falls back to simple space-based splitting to ensure robustness.
-func parseArgumentString(argsStr string) []string {
+func parseArgumentString(logger *slog.Logger, argsStr string) []string {
+	args, err := splitArgumentString(argsStr)
+	if err != nil {
+		logger.Warn("failed to parse argument string", "input", argsStr, "error", err)
+		// If parsing fails, fall back to simple splitting
+		// This ensures we don't completely fail on malformed input
+		return strings.Fields(argsStr)
+	}
+
+	return args
+}
+
+// splitArgumentString splits an argument string like parseArgumentString, but returns an
+// error for malformed input instead of falling back to space-based splitting.
+func splitArgumentString(argsStr string) ([]string, error) {
 	// Trim whitespace and handle empty string
 	argsStr = strings.TrimSpace(argsStr)
 	if argsStr == "" {
-		return nil
+		return nil, nil
 	}
 
 	// Use shellquote to properly parse the arguments
 	args, err := sq.Split(argsStr)
 	if err != nil {
-		slog.Error("failed to parse argument string", "input", argsStr, "error", err)
-		// If parsing fails, fall back to simple splitting
-		// This ensures we don't completely fail on malformed input
-		return strings.Fields(argsStr)
+		return nil, fmt.Errorf("malformed %s string: %s; fix the quoting, or pass an array of strings", PositionalArgsParam, strings.ToLower(err.Error()))
 	}
 
-	return args
+	return args, nil
 }
@@ -1,9 +1,17 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestParseArgumentString tests the shell-like argument parsing
@@ -52,19 +60,44 @@ func TestParseArgumentString(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := parseArgumentString(tt.input)
+			result := parseArgumentString(discardLogger, tt.input)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
+// TestStrictArgParsing tests that malformed argument strings are rejected in strict mode, and split on spaces otherwise
+func TestStrictArgParsing(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(&cobra.Command{Use: "grep", Run: func(*cobra.Command, []string) {}})
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{PositionalArgsParam: `it's here`}
+
+	tools := NewGenerator().FromRootCmd(root)
+	require.Len(t, tools, 1)
+	args, err := tools[0].BuildArgs(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"grep", "--", "it's", "here"}, args)
+
+	tools = NewGenerator(WithStrictArgParsing()).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	_, err = tools[0].BuildArgs(request)
+	require.ErrorIs(t, err, ErrInvalidArguments)
+	assert.Contains(t, err.Error(), "malformed args string: unterminated single-quoted string")
+
+	request.Params.Arguments = map[string]any{PositionalArgsParam: `"it's" here`}
+	args, err = tools[0].BuildArgs(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"grep", "--", "it's", "here"}, args)
+}
+
 // TestBuildFlagArgs tests flag argument construction
 func TestBuildFlagArgs(t *testing.T) {
 	tests := []struct {
 		name     string
 		flagMap  map[string]any
 		expected []string
-		multiple bool
 	}{
 		{
 			name: "boolean true flag",
@@ -85,14 +118,14 @@ func TestBuildFlagArgs(t *testing.T) {
 			flagMap: map[string]any{
 				"output": "json",
 			},
-			expected: []string{"--output", "json"},
+			expected: []string{"--output=json"},
 		},
 		{
 			name: "integer flag",
 			flagMap: map[string]any{
 				"count": 42,
 			},
-			expected: []string{"--count", "42"},
+			expected: []string{"--count=42"},
 		},
 		{
 			name: "multiple flags",
@@ -101,9 +134,8 @@ func TestBuildFlagArgs(t *testing.T) {
 				"output":  "json",
 				"quiet":   false,
 			},
-			// Order may vary, so we check length and contents
-			expected: []string{"--verbose", "--output", "json"},
-			multiple: true,
+			// Flags are emitted in sorted order
+			expected: []string{"--output=json", "--verbose"},
 		},
 		{
 			name:     "empty flag map",
@@ -123,8 +155,21 @@ func TestBuildFlagArgs(t *testing.T) {
 				"flag":  []any{"value1", "value2"},
 				"flag2": "json",
 			},
-			expected: []string{"--flag", "value1", "--flag", "value2", "--flag2", "json"},
-			multiple: true,
+			expected: []string{"--flag=value1", "--flag=value2", "--flag2=json"},
+		},
+		{
+			name: "empty slice flag",
+			flagMap: map[string]any{
+				"tag": []any{},
+			},
+			expected: nil,
+		},
+		{
+			name: "number slice flag",
+			flagMap: map[string]any{
+				"port": []any{float64(80), 443},
+			},
+			expected: []string{"--port=80", "--port=443"},
 		},
 		{
 			name: "bool slice flag",
@@ -133,21 +178,518 @@ func TestBuildFlagArgs(t *testing.T) {
 				"flag2": true,
 			},
 			expected: []string{"--flag", "--flag", "--flag2"},
-			multiple: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := buildFlagArgs(tt.flagMap)
-
-			// For tests with multiple flags from a map, check elements match regardless of order
-			// since map iteration order is non-deterministic
-			if tt.multiple {
-				assert.ElementsMatch(t, tt.expected, result)
-			} else {
-				assert.Equal(t, tt.expected, result)
+			result, err := buildFlagArgs(discardLogger, tt.flagMap, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// TestBuildFlagArgsMixedSlice tests that arrays mixing element types are rejected
+func TestBuildFlagArgsMixedSlice(t *testing.T) {
+	_, err := buildFlagArgs(discardLogger, map[string]any{"tag": []any{"a", float64(1)}}, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `flag "tag"`)
+	assert.Contains(t, err.Error(), "element 0 is a string, element 1 is a number")
+
+	_, err = buildFlagArgs(discardLogger, map[string]any{"tag": []any{map[string]any{}}}, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported type")
+}
+
+// TestBuildFlagArgsBoolDefaults tests that false is only emitted for flags defaulting to true
+func TestBuildFlagArgsBoolDefaults(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Bool("color", true, "Colorize output")
+	flags.Bool("verbose", false, "Verbose output")
+
+	tests := []struct {
+		name     string
+		flagMap  map[string]any
+		expected []string
+	}{
+		{"false with true default", map[string]any{"color": false}, []string{"--color=false"}},
+		{"true with true default", map[string]any{"color": true}, []string{"--color"}},
+		{"false with false default", map[string]any{"verbose": false}, nil},
+		{"true with false default", map[string]any{"verbose": true}, []string{"--verbose"}},
+		{"string false with true default", map[string]any{"color": "false"}, []string{"--color=false"}},
+		{"string false with false default", map[string]any{"verbose": "false"}, nil},
+		{"string true", map[string]any{"verbose": "true"}, []string{"--verbose"}},
+		{"string 1", map[string]any{"verbose": "1"}, []string{"--verbose"}},
+		{"string 0", map[string]any{"color": "0"}, []string{"--color=false"}},
+		{"string that is not a boolean", map[string]any{"verbose": "maybe"}, []string{"--verbose=maybe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := buildFlagArgs(discardLogger, tt.flagMap, flags, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// TestBuildFlagArgsBoolSlice tests that the items of boolean slice flags are parsed by pflag
+func TestBuildFlagArgsBoolSlice(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.BoolSlice("bs", nil, "Booleans")
+
+	result, err := buildFlagArgs(discardLogger, map[string]any{"bs": []any{true, false, true}}, flags, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--bs=true", "--bs=false", "--bs=true"}, result)
+
+	require.NoError(t, flags.Parse(result))
+	values, err := flags.GetBoolSlice("bs")
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true}, values)
+}
+
+// TestBuildFlagArgsShorthand tests that shorthand names are normalized to the long form
+func TestBuildFlagArgsShorthand(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.BoolP("verbose", "v", false, "Verbose output")
+	flags.StringP("output", "o", "", "Output format")
+
+	result, err := buildFlagArgs(discardLogger, map[string]any{"v": true, "-o": "json"}, flags, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--output=json", "--verbose"}, result)
+
+	result, err = buildFlagArgs(discardLogger, map[string]any{"--verbose": true}, flags, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--verbose"}, result)
+
+	_, err = buildFlagArgs(discardLogger, map[string]any{"v": true, "verbose": false}, flags, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than once")
+}
+
+// TestBuildFlagArgsCount tests that count flags are repeated as often as their value says
+func TestBuildFlagArgsCount(t *testing.T) {
+	newFlags := func() *pflag.FlagSet {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.CountP("verbose", "v", "Verbosity")
+		return flags
+	}
+
+	result, err := buildFlagArgs(discardLogger, map[string]any{"v": float64(3)}, newFlags(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--verbose", "--verbose", "--verbose"}, result)
+
+	flags := newFlags()
+	require.NoError(t, flags.Parse(result))
+	count, err := flags.GetCount("verbose")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count, "the command counts every occurrence")
+
+	result, err = buildFlagArgs(discardLogger, map[string]any{"verbose": float64(0)}, newFlags(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, result)
+
+	// Integer strings are accepted, as injected flags are strings
+	result, err = buildFlagArgs(discardLogger, map[string]any{"verbose": "2"}, newFlags(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--verbose", "--verbose"}, result)
+
+	for _, value := range []any{float64(-1), 2.5, "three", "-1", true} {
+		_, err := buildFlagArgs(discardLogger, map[string]any{"verbose": value}, newFlags(), nil)
+		assert.ErrorContains(t, err, "a count flag must be a non-negative integer", "value %#v", value)
+	}
+}
+
+// TestBuildFlagArgsOptionalValue tests that true gives a flag with an optional value alone
+func TestBuildFlagArgsOptionalValue(t *testing.T) {
+	newFlags := func() *pflag.FlagSet {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.String("log-level", "info", "Log level")
+		flags.Lookup("log-level").NoOptDefVal = "debug"
+		return flags
+	}
+
+	tests := []struct {
+		value any
+		args  []string
+		level string
+	}{
+		{value: true, args: []string{"--log-level"}, level: "debug"},
+		{value: "warn", args: []string{"--log-level=warn"}, level: "warn"},
+		{value: false, args: nil, level: "info"},
+	}
+
+	for _, tt := range tests {
+		result, err := buildFlagArgs(discardLogger, map[string]any{"log-level": tt.value}, newFlags(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, tt.args, result, "value %#v", tt.value)
+
+		flags := newFlags()
+		require.NoError(t, flags.Parse(append(result, "arg")))
+		level, err := flags.GetString("log-level")
+		require.NoError(t, err)
+		assert.Equal(t, tt.level, level, "value %#v", tt.value)
+		assert.Equal(t, []string{"arg"}, flags.Args(), "the bare flag does not take the next argument")
+	}
+}
+
+// TestBuildFlagArgsUnknown tests that flags the command does not define are rejected
+func TestBuildFlagArgsUnknown(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("output", "", "Output format")
+	flags.Bool("verbose", false, "Verbose output")
+
+	_, err := buildFlagArgs(discardLogger, map[string]any{"output": "json", "format": "json", "force": true}, flags, nil)
+	require.Error(t, err)
+	assert.Equal(t, "unknown flags --force and --format, valid flags are: output, verbose", err.Error())
+
+	_, err = buildFlagArgs(discardLogger, map[string]any{"force": true}, pflag.NewFlagSet("test", pflag.ContinueOnError), nil)
+	require.Error(t, err)
+	assert.Equal(t, "unknown flag --force, the command has no flags", err.Error())
+}
+
+// TestBuildFlagArgsDeterministic tests that flag order does not depend on map iteration
+func TestBuildFlagArgsDeterministic(t *testing.T) {
+	flagMap := map[string]any{}
+	for _, name := range []string{"zeta", "alpha", "mu", "beta", "omega", "gamma"} {
+		flagMap[name] = name
+	}
+
+	first, err := buildFlagArgs(discardLogger, flagMap, nil, nil)
+	require.NoError(t, err)
+	for range 20 {
+		next, _ := buildFlagArgs(discardLogger, flagMap, nil, nil)
+		assert.Equal(t, first, next)
+	}
+	assert.Equal(t, []string{"--alpha=alpha", "--beta=beta", "--gamma=gamma", "--mu=mu", "--omega=omega", "--zeta=zeta"}, first)
+}
+
+// TestBuildCommandArgsInjection tests that dash-prefixed data is never parsed as a flag
+func TestBuildCommandArgsInjection(t *testing.T) {
+	var gotArgs []string
+	var gotForce bool
+	var gotOutput string
+
+	root := &cobra.Command{Use: "cli"}
+	sub := &cobra.Command{
+		Use: "sub",
+		Run: func(_ *cobra.Command, args []string) {
+			gotArgs = args
+		},
+	}
+	sub.Flags().BoolVar(&gotForce, "force", false, "Force")
+	sub.Flags().StringVar(&gotOutput, "output", "", "Output")
+	root.AddCommand(sub)
+
+	ctrl := &Controller{Tool: mcp.NewTool("cli_sub"), path: []string{"sub"}}
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		FlagsParam:          map[string]any{"output": "-x"},
+		PositionalArgsParam: "--force file",
+	}
+
+	args, err := ctrl.buildCommandArgs(request, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub", "--output=-x", "--", "--force", "file"}, args)
+
+	root.SetArgs(args)
+	require.NoError(t, root.Execute())
+	assert.Equal(t, []string{"--force", "file"}, gotArgs)
+	assert.False(t, gotForce)
+	assert.Equal(t, "-x", gotOutput)
+}
+
+// TestBuildCommandArgsArrayMode tests that array arguments are passed through verbatim
+func TestBuildCommandArgsArrayMode(t *testing.T) {
+	ctrl := &Controller{Tool: mcp.NewTool("cli_sub"), path: []string{"sub"}}
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		PositionalArgsParam: []any{"file with spaces.txt", `it's "quoted"`, "--force"},
+	}
+	args, err := ctrl.buildCommandArgs(request, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub", "--", "file with spaces.txt", `it's "quoted"`, "--force"}, args)
+
+	request.Params.Arguments = map[string]any{PositionalArgsParam: []any{}}
+	args, err = ctrl.buildCommandArgs(request, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub"}, args)
+
+	request.Params.Arguments = map[string]any{PositionalArgsParam: []any{"ok", float64(1)}}
+	_, err = ctrl.buildCommandArgs(request, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "positional argument 1 must be a string")
+}
+
+// TestBuildCommandArgsShapes tests that arguments of the wrong shape are rejected instead of dropped
+func TestBuildCommandArgsShapes(t *testing.T) {
+	tests := map[string]struct {
+		arguments any
+		expected  string
+	}{
+		"flags as a string":       {map[string]any{FlagsParam: `{"output":"json"}`}, "flags must be an object of flag names to values, got string"},
+		"flags as an array":       {map[string]any{FlagsParam: []any{"--output=json"}}, "flags must be an object of flag names to values, got []interface {}"},
+		"args as a number":        {map[string]any{PositionalArgsParam: float64(1)}, "args must be an array of strings or a string, got float64"},
+		"args as an object":       {map[string]any{PositionalArgsParam: map[string]any{"name": "pods"}}, "args must be an array of strings or a string, got map[string]interface {}"},
+		"arguments as an array":   {[]any{"pods"}, "the arguments must be an object, got []interface {}"},
+		"null flags and args":     {map[string]any{FlagsParam: nil, PositionalArgsParam: nil}, ""},
+		"missing flags and args":  {map[string]any{}, ""},
+		"missing arguments":       {nil, ""},
+		"missing arguments (map)": {map[string]any(nil), ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			executor := &recordingExecutor{result: &ExecResult{}}
+			ctrl := &Controller{Tool: mcp.NewTool("cli_get"), path: []string{"get"}, executor: executor}
+
+			var request mcp.CallToolRequest
+			request.Params.Arguments = tt.arguments
+			_, err := ctrl.Execute(context.Background(), request)
+			if tt.expected == "" {
+				require.NoError(t, err)
+				assert.Equal(t, []string{"get"}, executor.invocations[0].Args)
+				return
 			}
+
+			require.ErrorIs(t, err, ErrInvalidArguments)
+			assert.Contains(t, err.Error(), tt.expected)
+			assert.Empty(t, executor.invocations, "the command is not run without the flags")
 		})
 	}
 }
+
+// TestBuildArgs tests that BuildArgs returns the arguments Execute runs, without running them
+func TestBuildArgs(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}}
+	get.Flags().StringP("output", "o", "", "Output format")
+	root.AddCommand(get)
+
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(WithExecutor(executor)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		FlagsParam:          map[string]any{"o": "json"},
+		PositionalArgsParam: "pods nginx",
+	}
+	args, err := tools[0].BuildArgs(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"get", "--output=json", "--", "pods", "nginx"}, args)
+	assert.Empty(t, executor.invocations)
+
+	_, err = tools[0].Execute(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, executor.invocations, 1)
+	assert.Equal(t, args, executor.invocations[0].Args)
+
+	request.Params.Arguments = map[string]any{CwdParam: "/tmp"}
+	_, err = tools[0].BuildArgs(request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid tool arguments")
+}
+
+// TestRequiredFlags tests that required flags are in the schema and enforced before execution
+func TestRequiredFlags(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	deploy := &cobra.Command{Use: "deploy", Run: func(*cobra.Command, []string) {}}
+	deploy.Flags().StringP("env", "e", "", "Target environment")
+	deploy.Flags().String("region", "", "Target region")
+	deploy.Flags().Bool("force", false, "Force")
+	require.NoError(t, deploy.MarkFlagRequired("env"))
+	require.NoError(t, deploy.MarkFlagRequired("region"))
+	root.AddCommand(deploy)
+
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(WithExecutor(executor)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	schema := tools[0].Tool.InputSchema.Properties[FlagsParam].(map[string]any)
+	assert.Equal(t, []string{"env", "region"}, schema["required"])
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{FlagsParam: map[string]any{"force": true}}
+	_, err := tools[0].Execute(context.Background(), request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required flags: env, region")
+
+	request.Params.Arguments = map[string]any{FlagsParam: map[string]any{"e": "prod", "region": nil}}
+	_, err = tools[0].Execute(context.Background(), request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required flags: region")
+	assert.Empty(t, executor.invocations)
+
+	request.Params.Arguments = map[string]any{FlagsParam: map[string]any{"e": "prod", "region": "eu"}}
+	_, err = tools[0].Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Len(t, executor.invocations, 1)
+}
+
+// TestExecute tests running commands against the test binary
+func TestExecute(t *testing.T) {
+	t.Run("captures streams and exit code", func(t *testing.T) {
+		ctrl := helperController(t, "echo")
+		result, err := ctrl.Execute(context.Background(), helperRequest("hello"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello\n", string(result.Stdout))
+		assert.Equal(t, "stderr\n", string(result.Stderr))
+		assert.Equal(t, 0, result.ExitCode)
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		ctrl := helperController(t, "cat")
+		request := helperRequest("")
+		request.Params.Arguments.(map[string]any)[StdinParam] = "piped input"
+
+		result, err := ctrl.Execute(context.Background(), request)
+		require.NoError(t, err)
+		assert.Equal(t, "piped input", string(result.Stdout))
+	})
+
+	t.Run("no stdin does not block", func(t *testing.T) {
+		ctrl := helperController(t, "cat")
+		ctrl.Timeout = 5 * time.Second
+
+		result, err := ctrl.Execute(context.Background(), helperRequest(""))
+		require.NoError(t, err)
+		assert.Empty(t, result.Stdout)
+	})
+
+	t.Run("non-string stdin", func(t *testing.T) {
+		ctrl := helperController(t, "cat")
+		request := helperRequest("")
+		request.Params.Arguments.(map[string]any)[StdinParam] = float64(1)
+
+		_, err := ctrl.Execute(context.Background(), request)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "stdin must be a string")
+	})
+
+	t.Run("non-zero exit", func(t *testing.T) {
+		ctrl := helperController(t, "exit")
+		result, err := ctrl.Execute(context.Background(), helperRequest("3"))
+		require.Error(t, err)
+		assert.Equal(t, 3, result.ExitCode)
+		assert.False(t, result.TimedOut)
+	})
+
+	t.Run("timeout kills the command and keeps partial output", func(t *testing.T) {
+		ctrl := helperController(t, "sleep")
+		ctrl.Timeout = 500 * time.Millisecond
+
+		start := time.Now()
+		result, err := ctrl.Execute(context.Background(), helperRequest("10s"))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.True(t, result.TimedOut)
+		assert.Equal(t, "started\n", string(result.Stdout))
+
+		// The client gets the output written before the kill along with the timeout
+		toolResult, handleErr := ctrl.Handle(context.Background(), helperRequest("10s"), result, err)
+		require.NoError(t, handleErr)
+		assert.True(t, toolResult.IsError)
+		text := toolResult.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, ErrTimeout.Error())
+		assert.Contains(t, text, "Output: started")
+		assert.Equal(t, true, toolResult.Meta.AdditionalFields[MetaTimedOut])
+	})
+
+	t.Run("zero timeout means no limit", func(t *testing.T) {
+		ctrl := helperController(t, "sleep")
+		result, err := ctrl.Execute(context.Background(), helperRequest("10ms"))
+		require.NoError(t, err)
+		assert.False(t, result.TimedOut)
+	})
+	t.Run("max output bytes", func(t *testing.T) {
+		ctrl := helperController(t, "echo")
+		ctrl.MaxOutputBytes = 4
+
+		result, err := ctrl.Execute(context.Background(), helperRequest("abcdefgh"))
+		require.NoError(t, err)
+		assert.True(t, result.Truncated)
+		assert.Equal(t, "abcd\n[output truncated, 5 bytes omitted]", string(result.Stdout))
+		assert.Equal(t, "stde\n[output truncated, 3 bytes omitted]", string(result.Stderr))
+		assert.Equal(t, true, result.meta()[MetaTruncated])
+
+		ctrl.MaxOutputBytes = 100
+		result, err = ctrl.Execute(context.Background(), helperRequest("abcdefgh"))
+		require.NoError(t, err)
+		assert.False(t, result.Truncated)
+		assert.NotContains(t, result.meta(), MetaTruncated)
+	})
+}
+
+// TestFormatFlagValue tests that flag values are formatted like fmt's %v verb, except for numbers,
+// which are never formatted with an exponent
+func TestFormatFlagValue(t *testing.T) {
+	for _, value := range []any{"json", "", float64(5), 2.5, -0.001, 42, int64(-7), json.Number("12"), true} {
+		assert.Equal(t, fmt.Sprint(value), formatFlagValue(value), "value %#v", value)
+	}
+
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1e6, "1000000"},
+		{-123456789012, "-123456789012"},
+		{1e21, "1000000000000000000000"},
+		{1.5e-7, "0.00000015"},
+		{9007199254740993, "9007199254740992"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatFlagValue(tt.value), "value %v", tt.value)
+	}
+}
+
+// TestBuildFlagArgsLargeNumbers tests that large numbers are accepted by the parsers of numeric flags
+func TestBuildFlagArgsLargeNumbers(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	limit := flags.Int64("limit", 0, "Maximum number of results")
+	ratio := flags.Float64("ratio", 0, "Sampling ratio")
+	sizes := flags.IntSlice("size", nil, "Sizes")
+
+	args, err := buildFlagArgs(discardLogger, map[string]any{
+		"limit": float64(1e6),
+		"ratio": 2.5e-7,
+		"size":  []any{float64(1e7), float64(3)},
+	}, flags, nil)
+	require.NoError(t, err)
+	require.NoError(t, flags.Parse(args))
+
+	assert.EqualValues(t, 1000000, *limit)
+	assert.InDelta(t, 2.5e-7, *ratio, 1e-20)
+	assert.Equal(t, []int{10000000, 3}, *sizes)
+}
+
+// BenchmarkBuildCommandArgs measures building the command line of a typical tool call
+func BenchmarkBuildCommandArgs(b *testing.B) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}}
+	get.Flags().StringP("output", "o", "", "Output format")
+	get.Flags().Bool("all", false, "Include all resources")
+	get.Flags().StringSlice("label", nil, "Label selectors")
+	get.Flags().Int("limit", 10, "Maximum number of results")
+	root.AddCommand(get)
+
+	tools, err := NewGenerator().Generate(root)
+	require.NoError(b, err)
+	ctrl := tools[0]
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		FlagsParam:          map[string]any{"output": "json", "all": true, "label": []any{"app=web", "tier=db"}, "limit": float64(5)},
+		PositionalArgsParam: []any{"pod-1", "pod-2"},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ctrl.buildCommandArgs(request, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
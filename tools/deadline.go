@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MetaRequestTimeout is the key of the request metadata in which a client may state how many
+// milliseconds it waits for a tool call, e.g. {"_meta": {"timeoutMs": 30000}}. The command is
+// killed when it runs longer, rather than running to completion after the client gave up.
+// It can only shorten the Controller's Timeout, not extend it.
+const MetaRequestTimeout = "timeoutMs"
+
+// requestTimeout returns the timeout a client set in the metadata of request, or zero if none.
+func requestTimeout(request mcp.CallToolRequest) (time.Duration, error) {
+	if request.Params.Meta == nil {
+		return 0, nil
+	}
+
+	value, ok := request.Params.Meta.AdditionalFields[MetaRequestTimeout]
+	if !ok || value == nil {
+		return 0, nil
+	}
+
+	millis, ok := value.(float64)
+	if !ok || millis <= 0 || math.IsInf(millis, 0) || millis > float64(math.MaxInt64/int64(time.Millisecond)) {
+		return 0, fmt.Errorf("%w: _meta.%s must be a positive number of milliseconds, got %v", ErrInvalidArguments, MetaRequestTimeout, value)
+	}
+
+	return time.Duration(millis * float64(time.Millisecond)), nil
+}
+
+// callTimeout returns the timeout of a call of the tool, the shorter of the Controller's Timeout
+// and the timeout of the request, or zero for no limit.
+func (c *Controller) callTimeout(request mcp.CallToolRequest) (time.Duration, error) {
+	timeout, err := requestTimeout(request)
+	if err != nil {
+		return 0, err
+	}
+
+	if timeout == 0 || (c.Timeout > 0 && c.Timeout < timeout) {
+		return c.Timeout, nil
+	}
+
+	return timeout, nil
+}
+
+// timeoutError returns the error of a command that was killed because a deadline passed, or
+// nil if it was not. The deadline is either the timeout of the call, or the deadline of the
+// context it was called with, e.g. one set by a middleware.
+func timeoutError(callCtx, ctx context.Context, timeout time.Duration, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+
+	if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: the deadline of the call passed: %w", ErrTimeout, err)
+	}
+
+	return fmt.Errorf("%w after %s: %w", ErrTimeout, timeout, err)
+}
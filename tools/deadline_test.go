@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCallTimeout tests that clients can shorten, but not extend, the timeout of a call
+func TestCallTimeout(t *testing.T) {
+	withTimeout := func(value any) mcp.CallToolRequest {
+		var request mcp.CallToolRequest
+		request.Params.Meta = &mcp.Meta{AdditionalFields: map[string]any{MetaRequestTimeout: value}}
+		return request
+	}
+
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		request  mcp.CallToolRequest
+		expected time.Duration
+		invalid  bool
+	}{
+		{"no metadata", time.Minute, mcp.CallToolRequest{}, time.Minute, false},
+		{"shorter request timeout", time.Minute, withTimeout(float64(1500)), 1500 * time.Millisecond, false},
+		{"longer request timeout", time.Second, withTimeout(float64(60000)), time.Second, false},
+		{"no controller timeout", 0, withTimeout(float64(250)), 250 * time.Millisecond, false},
+		{"not a number", 0, withTimeout("30s"), 0, true},
+		{"not positive", 0, withTimeout(float64(0)), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &Controller{Timeout: tt.timeout}
+			timeout, err := ctrl.callTimeout(tt.request)
+			if tt.invalid {
+				require.ErrorIs(t, err, ErrInvalidArguments)
+				assert.Contains(t, err.Error(), "_meta.timeoutMs must be a positive number of milliseconds")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, timeout)
+		})
+	}
+}
+
+// TestExecuteDeadline tests that commands are killed when the client gives up on the call
+func TestExecuteDeadline(t *testing.T) {
+	t.Run("request timeout", func(t *testing.T) {
+		ctrl := helperController(t, "sleep")
+		request := helperRequest("10s")
+		request.Params.Meta = &mcp.Meta{AdditionalFields: map[string]any{MetaRequestTimeout: float64(500)}}
+
+		start := time.Now()
+		result, err := ctrl.Execute(context.Background(), request)
+		require.ErrorIs(t, err, ErrTimeout)
+		assert.Contains(t, err.Error(), "after 500ms")
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.True(t, result.TimedOut)
+		assert.Equal(t, "started\n", string(result.Stdout))
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		ctrl := helperController(t, "sleep")
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		result, err := ctrl.Execute(ctx, helperRequest("10s"))
+		require.ErrorIs(t, err, ErrTimeout)
+		assert.Contains(t, err.Error(), "the deadline of the call passed")
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.True(t, result.TimedOut)
+	})
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// DefaultResolver returns the effective default of a flag of a command, when it differs from
+// the DefValue of the flag, e.g. because the command reads the flag through viper, which falls
+// back to a configuration file or environment variables. The default is in the string form of
+// DefValue, e.g. "[a,b]" for slice flags. It reports false to keep DefValue.
+type DefaultResolver func(cmd *cobra.Command, flag *pflag.Flag) (string, bool)
+
+// WithDefaultResolver returns a GeneratorOption that shows the defaults returned by resolver in
+// the input schemas and descriptions of the flags, instead of their DefValue, so that clients
+// know the value a command uses when a flag is not given. The resolver is called when the schema
+// is built, once per flag, and a panicking resolver keeps DefValue. The flags themselves are not
+// changed. By default the schemas show DefValue.
+//
+//	Example: NewGenerator(WithDefaultResolver(func(_ *cobra.Command, flag *pflag.Flag) (string, bool) {
+//		if !viper.IsSet(flag.Name) {
+//			return "", false
+//		}
+//		return viper.GetString(flag.Name), true
+//	}))
+func WithDefaultResolver(resolver DefaultResolver) GeneratorOption {
+	return func(g *Generator) {
+		g.defaultResolver = resolver
+	}
+}
+
+// resolveDefaults returns flags with their DefValue replaced by the defaults of the resolver,
+// or flags itself without a resolver.
+func (g *Generator) resolveDefaults(cmd *cobra.Command, flags *pflag.FlagSet) *pflag.FlagSet {
+	if g.defaultResolver == nil {
+		return flags
+	}
+
+	resolved := pflag.NewFlagSet(flags.Name(), pflag.ContinueOnError)
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if value, ok := g.resolveDefault(cmd, flag); ok && value != flag.DefValue {
+			// A copy, since the flags are shared with the command
+			copied := *flag
+			copied.DefValue = value
+			flag = &copied
+		}
+		resolved.AddFlag(flag)
+	})
+
+	return resolved
+}
+
+// resolveDefault calls the resolver for a flag, treating a panic as no default.
+func (g *Generator) resolveDefault(cmd *cobra.Command, flag *pflag.Flag) (value string, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.logger.Warn("flag default resolver panicked", "flag", flag.Name, "command", cmd.CommandPath(), "panic", r)
+			value, ok = "", false
+		}
+	}()
+
+	return g.defaultResolver(cmd, flag)
+}
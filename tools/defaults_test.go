@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithDefaultResolver tests that resolved defaults replace DefValue in the schemas only
+func TestWithDefaultResolver(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	deploy := &cobra.Command{Use: "deploy", Run: func(*cobra.Command, []string) {}}
+	deploy.Flags().String("region", "us-east-1", "Region")
+	deploy.Flags().Int("replicas", 1, "Replicas")
+	deploy.Flags().StringSlice("zone", nil, "Zones")
+	deploy.Flags().String("broken", "", "Broken")
+	deploy.Flags().Bool("wait", false, "Wait")
+	root.AddCommand(deploy)
+
+	configured := map[string]string{"region": "eu-west-1", "replicas": "3", "zone": "[a,b]"}
+	resolver := func(_ *cobra.Command, flag *pflag.Flag) (string, bool) {
+		if flag.Name == "broken" {
+			panic("no config")
+		}
+		value, ok := configured[flag.Name]
+		return value, ok
+	}
+
+	tools := NewGenerator(WithDefaultResolver(resolver)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	schema := tools[0].Tool.InputSchema.Properties[FlagsParam].(map[string]any)
+	properties := schema["properties"].(map[string]any)
+
+	region := properties["region"].(map[string]any)
+	assert.Equal(t, "eu-west-1", region["default"])
+	assert.Contains(t, region["description"], `(default "eu-west-1")`)
+	assert.Equal(t, int64(3), properties["replicas"].(map[string]any)["default"])
+	assert.Equal(t, []any{"a", "b"}, properties["zone"].(map[string]any)["default"])
+	assert.NotContains(t, properties["broken"], "default")
+	assert.Equal(t, false, properties["wait"].(map[string]any)["default"])
+
+	assert.Equal(t, "us-east-1", deploy.Flags().Lookup("region").DefValue, "the flags are not changed")
+
+	t.Run("defaults to DefValue", func(t *testing.T) {
+		tools := NewGenerator().FromRootCmd(root)
+		schema := tools[0].Tool.InputSchema.Properties[FlagsParam].(map[string]any)
+		assert.Equal(t, "us-east-1", schema["properties"].(map[string]any)["region"].(map[string]any)["default"])
+	})
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// WithWorkingDirRoots returns a GeneratorOption that adds an optional CwdParam parameter to every
+// tool, setting the working directory of the command for that call. The directory must resolve,
+// after cleaning and following symlinks, to one of roots or a directory below them.
+// A relative directory is resolved against the first root.
+//
+// Without roots, tools have no CwdParam parameter and commands run in the working directory
+// of the server.
+//
+//	Example: NewGenerator(WithWorkingDirRoots("/src/monorepo"))
+func WithWorkingDirRoots(roots ...string) GeneratorOption {
+	return func(g *Generator) {
+		g.roots = append(g.roots, roots...)
+	}
+}
+
+// cwdToolOption returns a ToolOption that adds the working directory parameter.
+func cwdToolOption(roots []string) mcp.ToolOption {
+	return mcp.WithString(CwdParam,
+		mcp.Description(fmt.Sprintf(
+			"Optional working directory of the command. Relative paths are resolved against %s. Must be inside one of: %s",
+			roots[0], strings.Join(roots, ", "),
+		)),
+	)
+}
+
+// resolveDir resolves a requested working directory and checks that it is inside one of roots.
+func resolveDir(dir string, roots []string) (string, error) {
+	if len(roots) == 0 {
+		return "", fmt.Errorf("%s is not supported by this tool", CwdParam)
+	}
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(roots[0], dir)
+	}
+
+	// Follow symlinks so that a link inside a root cannot point outside of it
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(dir))
+	if err != nil {
+		return "", fmt.Errorf("%s %q: %w", CwdParam, dir, err)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("%s %q: %w", CwdParam, dir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s %q is not a directory", CwdParam, dir)
+	}
+
+	for _, root := range roots {
+		root, err := filepath.EvalSymlinks(filepath.Clean(root))
+		if err != nil {
+			continue
+		}
+
+		if isWithin(resolved, root) {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s %q is outside of the allowed directories: %s", CwdParam, dir, strings.Join(roots, ", "))
+}
+
+// isWithin reports whether path is root or a descendant of it. Both must be clean.
+func isWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel))
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResolveDir tests that working directories are confined to the allowed roots
+func TestResolveDir(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0o755))
+	require.NoError(t, os.MkdirAll(outside, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file"), nil, 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "rootsibling"), 0o755))
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	require.NoError(t, os.Symlink(filepath.Join(root, "sub"), filepath.Join(base, "link")))
+
+	roots := []string{root}
+	tests := []struct {
+		name    string
+		dir     string
+		want    string
+		wantErr string
+	}{
+		{name: "root", dir: root, want: root},
+		{name: "absolute subdirectory", dir: filepath.Join(root, "sub"), want: filepath.Join(root, "sub")},
+		{name: "relative subdirectory", dir: "sub", want: filepath.Join(root, "sub")},
+		{name: "unclean path", dir: filepath.Join(root, "sub", "..", "sub", "."), want: filepath.Join(root, "sub")},
+		{name: "symlink into root", dir: filepath.Join(base, "link"), want: filepath.Join(root, "sub")},
+		{name: "parent traversal", dir: "../outside", wantErr: "outside of the allowed directories"},
+		{name: "sibling with common prefix", dir: filepath.Join(base, "rootsibling"), wantErr: "outside of the allowed directories"},
+		{name: "symlink out of root", dir: "escape", wantErr: "outside of the allowed directories"},
+		{name: "missing", dir: "missing", wantErr: "no such file or directory"},
+		{name: "file", dir: "file", wantErr: "is not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDir(tt.dir, roots)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("no roots", func(t *testing.T) {
+		_, err := resolveDir(root, nil)
+		assert.ErrorContains(t, err, "not supported")
+	})
+}
+
+// TestExecuteCwd tests that the command runs in the requested directory
+func TestExecuteCwd(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(root, "project"), 0o755))
+
+	ctrl := helperController(t, "pwd")
+	ctrl.roots = []string{root}
+
+	request := helperRequest("")
+	request.Params.Arguments.(map[string]any)[CwdParam] = "project"
+	result, err := ctrl.Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "project"), strings.TrimSpace(string(result.Stdout)))
+
+	request.Params.Arguments.(map[string]any)[CwdParam] = ".."
+	result, err = ctrl.Execute(context.Background(), request)
+	assert.Nil(t, result)
+	assert.ErrorContains(t, err, "invalid tool arguments")
+}
+
+// TestWithWorkingDirRoots tests that the cwd parameter is only exposed when roots are configured
+func TestWithWorkingDirRoots(t *testing.T) {
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+
+	tools := NewGenerator().FromRootCmd(cmd)
+	require.Len(t, tools, 1)
+	assert.NotContains(t, tools[0].Tool.InputSchema.Properties, CwdParam)
+
+	tools = NewGenerator(WithWorkingDirRoots("/src")).FromRootCmd(cmd)
+	require.Len(t, tools, 1)
+	assert.Contains(t, tools[0].Tool.InputSchema.Properties, CwdParam)
+	assert.NotContains(t, tools[0].Tool.InputSchema.Required, CwdParam)
+	assert.Equal(t, []string{"/src"}, tools[0].roots)
+}
@@ -20,6 +20,11 @@
 //   - Allow([]string): Only expose commands with specific names
 //   - Exclude([]string): Hide specific commands from MCP
 //   - Hidden(): Exclude hidden Cobra commands (applied by default)
+//   - ExcludePaths(...string): Hide commands by full command path
+//   - Not, AllOf, AnyOf: Combine filters
+//
+// WithAllowedPaths restricts the exposed commands to full command paths, such as
+// "kubectl get", and applies in addition to the filters.
 //
 // Handlers: Functions that process command output before returning it to MCP clients.
 // The default handler returns output as plain text, but you can provide custom
@@ -46,7 +51,7 @@
 // # Default Behavior
 //
 // When no custom Generator is provided, the default behavior:
-//   - Excludes hidden commands
+//   - Excludes hidden commands and hidden flags (see IncludeHidden)
 //   - Excludes "mcp", "help", and "completion" commands
 //   - Returns command output as plain text
 //
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	sq "github.com/kballard/go-shellquote"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// WithDryRun returns a GeneratorOption that adds an optional DryRunParam parameter to every tool.
+// A call with dry_run set to true returns the shell command that would be executed instead of
+// running it, which lets clients preview destructive commands and helps debug the mapping of
+// flags and positional arguments.
+//
+// A dry run is not checked by the AuthorizeFunc, since nothing is executed.
+func WithDryRun() GeneratorOption {
+	return func(g *Generator) {
+		g.dryRun = true
+	}
+}
+
+// dryRunToolOption returns a ToolOption that adds the dry run parameter.
+func dryRunToolOption() mcp.ToolOption {
+	return mcp.WithBoolean(DryRunParam,
+		mcp.Description("If true, return the command that would be executed without running it"),
+	)
+}
+
+// dryRunResult returns the result of a dry run of the invocation by executor. Stdout holds a
+// shell-quoted command line that can be pasted into a terminal, and Args the arguments of the
+// executable. The command line starts with the Command of a DefaultExecutor, and is wrapped by
+// its Wrapper. It starts with the current binary for other executors.
+func dryRunResult(inv Invocation, executor Executor) *ExecResult {
+	defaultExecutor, ok := executor.(*DefaultExecutor)
+	if !ok {
+		defaultExecutor = &DefaultExecutor{}
+	}
+	executable, args, err := defaultExecutor.launch(inv.Args)
+	if err != nil {
+		executable, args = os.Args[0], inv.Args
+	}
+
+	command := sq.Join(append([]string{executable}, args...)...)
+	if inv.Dir != "" {
+		command = fmt.Sprintf("cd %s && %s", sq.Join(inv.Dir), command)
+	}
+
+	stdout := []byte(command + "\n")
+	return &ExecResult{
+		Stdout:   stdout,
+		ExitCode: -1,
+		DryRun:   true,
+		Args:     slices.Clone(inv.Args),
+		combined: stdout,
+	}
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	sq "github.com/kballard/go-shellquote"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithDryRun tests that a dry run returns the quoted command line without executing it
+func TestWithDryRun(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	del := &cobra.Command{Use: "delete", Run: func(*cobra.Command, []string) {}}
+	del.Flags().String("selector", "", "label selector")
+	root.AddCommand(del)
+
+	authorized := false
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(
+		WithDryRun(),
+		WithExecutor(executor),
+		WithAuthorize(func(context.Context, string, []string) error {
+			authorized = true
+			return nil
+		}),
+	).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	assert.Contains(t, tools[0].Tool.InputSchema.Properties, DryRunParam)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		DryRunParam:         true,
+		FlagsParam:          map[string]any{"selector": "app=web"},
+		PositionalArgsParam: []any{"my pod", "it's"},
+	}
+
+	result, err := tools[0].Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Empty(t, executor.invocations)
+	assert.False(t, authorized)
+
+	args := []string{"delete", "--selector=app=web", "--", "my pod", "it's"}
+	assert.True(t, result.DryRun)
+	assert.Equal(t, args, result.Args)
+
+	// The command line splits back into the same arguments
+	split, err := sq.Split(string(result.Stdout))
+	require.NoError(t, err)
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	assert.Equal(t, append([]string{executable}, args...), split)
+
+	toolResult, err := tools[0].Handle(context.Background(), request, result, nil)
+	require.NoError(t, err)
+	assert.False(t, toolResult.IsError)
+	assert.Equal(t, map[string]any{MetaDryRun: true, MetaArgs: args}, toolResult.Meta.AdditionalFields)
+
+	request.Params.Arguments = map[string]any{DryRunParam: false}
+	_, err = tools[0].Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Len(t, executor.invocations, 1)
+	assert.True(t, authorized)
+}
+
+// TestDryRunUnsupported tests that dry runs are rejected unless enabled
+func TestDryRunUnsupported(t *testing.T) {
+	executor := &recordingExecutor{result: &ExecResult{}}
+	ctrl := &Controller{Tool: mcp.NewTool("test"), executor: executor}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{DryRunParam: true}
+	_, err := ctrl.Execute(context.Background(), request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported")
+
+	request.Params.Arguments = map[string]any{DryRunParam: "yes"}
+	_, err = ctrl.Execute(context.Background(), request)
+	require.Error(t, err)
+	assert.Empty(t, executor.invocations)
+}
+
+// TestDryRunResultDir tests that the working directory is part of the command line
+func TestDryRunResultDir(t *testing.T) {
+	result := dryRunResult(Invocation{Args: []string{"ls"}, Dir: "/src/my repo"}, nil)
+	assert.Regexp(t, `^cd '/src/my repo' && \S+ ls\n$`, string(result.Stdout))
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultMachineEnv discourages commands from writing escape sequences, prompts and progress
+// animations in the first place, in key=value form. See https://no-color.org.
+var defaultMachineEnv = []string{"CI=1", "NO_COLOR=1", "TERM=dumb"}
+
+// envPolicy selects the server environment variables passed to executed commands.
+type envPolicy struct {
+	inheritEnv bool     // pass the whole environment
+	passEnv    []string // names of the variables to pass, if not inheriting
+	// machineEnv asks for plain output in key=value form, if set by WithMachineEnv. Otherwise
+	// defaultMachineEnv is used, unless ANSI escape sequences are preserved.
+	machineEnv    []string
+	setMachineEnv bool
+}
+
+// envOverride sets environment variables for the tools matched by selector.
+type envOverride struct {
+	selector Filter
+	env      map[string]string
+}
+
+// WithInheritedEnv returns a GeneratorOption that passes the whole environment of the server
+// to every executed command, which was the behavior of earlier versions.
+//
+// By default commands start with an empty environment, because an MCP client can trigger any
+// exposed command with arguments of its choosing. Inheriting the environment hands every secret
+// the server holds, such as AWS_SECRET_ACCESS_KEY or GITHUB_TOKEN, to those commands. Prefer
+// WithEnvPassthrough unless the server only runs for a single trusted user.
+func WithInheritedEnv() GeneratorOption {
+	return func(g *Generator) {
+		g.env.inheritEnv = true
+	}
+}
+
+// WithEnvPassthrough returns a GeneratorOption that passes the named environment variables of
+// the server to every executed command. Variables that are not set are skipped.
+// It can be used multiple times, and the names accumulate.
+//
+//	Example: NewGenerator(WithEnvPassthrough("PATH", "HOME", "KUBECONFIG"))
+func WithEnvPassthrough(names ...string) GeneratorOption {
+	return func(g *Generator) {
+		g.env.passEnv = append(g.env.passEnv, names...)
+	}
+}
+
+// WithEnv returns a GeneratorOption that sets environment variables for the tools matched by
+// selector, or for every tool if selector is nil. These values are applied after passthrough and
+// inheritance, so they take precedence over the server environment. Later calls win on conflicts.
+//
+//	Example: NewGenerator(WithEnv(Allow([]string{"deploy"}), map[string]string{"DRY_RUN": "1"}))
+func WithEnv(selector Filter, env map[string]string) GeneratorOption {
+	return func(g *Generator) {
+		g.envOverrides = append(g.envOverrides, envOverride{selector: selector, env: env})
+	}
+}
+
+// DefaultMachineEnv returns the environment variables every command runs with by default, which
+// ask it for plain, machine-readable output: NO_COLOR=1 and TERM=dumb disable colors, and CI=1
+// disables prompts and progress animations in most tools.
+func DefaultMachineEnv() map[string]string {
+	env := make(map[string]string, len(defaultMachineEnv))
+	for _, kv := range defaultMachineEnv {
+		name, value, _ := strings.Cut(kv, "=")
+		env[name] = value
+	}
+
+	return env
+}
+
+// WithMachineEnv returns a GeneratorOption that replaces the environment variables every command
+// runs with to get plain output, DefaultMachineEnv by default, e.g. to add variables of the
+// wrapped tools such as GIT_PAGER=cat. An empty env sets none. Unlike the defaults, they are
+// also set with PreserveANSI. They are applied after passthrough and inheritance, and before
+// WithEnv, which takes precedence.
+//
+//	Example: env := DefaultMachineEnv(); env["GIT_PAGER"] = "cat"; NewGenerator(WithMachineEnv(env))
+func WithMachineEnv(env map[string]string) GeneratorOption {
+	return func(g *Generator) {
+		g.env.machineEnv = nil
+		for _, name := range slices.Sorted(maps.Keys(env)) {
+			g.env.machineEnv = append(g.env.machineEnv, name+"="+env[name])
+		}
+		g.env.setMachineEnv = true
+	}
+}
+
+// envFor returns the environment overrides of a generated tool.
+func (g *Generator) envFor(cmd *cobra.Command) map[string]string {
+	var env map[string]string
+	for _, override := range g.envOverrides {
+		if override.selector != nil && !override.selector(cmd) {
+			continue
+		}
+
+		if env == nil {
+			env = map[string]string{}
+		}
+		maps.Copy(env, override.env)
+	}
+
+	return env
+}
+
+// environ returns the environment of an executed command in key=value form.
+// It is never nil, so an empty result means an empty environment rather than an inherited one.
+func (c *Controller) environ() []string {
+	env := []string{}
+	if c.env.inheritEnv {
+		env = append(env, os.Environ()...)
+	} else {
+		for _, name := range c.env.passEnv {
+			if value, ok := os.LookupEnv(name); ok {
+				env = append(env, name+"="+value)
+			}
+		}
+	}
+
+	switch {
+	case c.env.setMachineEnv:
+		env = append(env, c.env.machineEnv...)
+	case !c.keepANSI:
+		env = append(env, defaultMachineEnv...)
+	}
+
+	// Overrides come last, since the last value of a duplicate key wins
+	for _, name := range slices.Sorted(maps.Keys(c.Env)) {
+		env = append(env, name+"="+c.Env[name])
+	}
+
+	return env
+}
+
+// envNames returns the variable names of an environment, for logging without leaking values.
+func envNames(env []string) []string {
+	names := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+
+	return names
+}
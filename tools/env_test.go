@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestControllerEnviron tests which environment variables reach executed commands
+func TestControllerEnviron(t *testing.T) {
+	t.Setenv("OPHIS_TEST_PUBLIC", "visible")
+	t.Setenv("OPHIS_TEST_SECRET", "hunter2")
+
+	t.Run("empty by default", func(t *testing.T) {
+		ctrl := &Controller{keepANSI: true}
+		env := ctrl.environ()
+		assert.NotNil(t, env)
+		assert.Empty(t, env)
+	})
+
+	t.Run("asks for plain output", func(t *testing.T) {
+		ctrl := &Controller{}
+		assert.Equal(t, []string{"CI=1", "NO_COLOR=1", "TERM=dumb"}, ctrl.environ())
+
+		ctrl.Env = map[string]string{"TERM": "xterm"}
+		assert.Equal(t, []string{"CI=1", "NO_COLOR=1", "TERM=dumb", "TERM=xterm"}, ctrl.environ())
+	})
+
+	t.Run("passthrough", func(t *testing.T) {
+		ctrl := &Controller{keepANSI: true, env: envPolicy{passEnv: []string{"OPHIS_TEST_PUBLIC", "OPHIS_TEST_UNSET"}}}
+		assert.Equal(t, []string{"OPHIS_TEST_PUBLIC=visible"}, ctrl.environ())
+	})
+
+	t.Run("inherited", func(t *testing.T) {
+		ctrl := &Controller{env: envPolicy{inheritEnv: true}}
+		env := ctrl.environ()
+		assert.Contains(t, env, "OPHIS_TEST_PUBLIC=visible")
+		assert.Contains(t, env, "OPHIS_TEST_SECRET=hunter2")
+	})
+
+	t.Run("overrides last", func(t *testing.T) {
+		ctrl := &Controller{
+			keepANSI: true,
+			Env:      map[string]string{"OPHIS_TEST_PUBLIC": "overridden", "B": "2", "A": "1"},
+			env:      envPolicy{passEnv: []string{"OPHIS_TEST_PUBLIC"}},
+		}
+		assert.Equal(t, []string{"OPHIS_TEST_PUBLIC=visible", "A=1", "B=2", "OPHIS_TEST_PUBLIC=overridden"}, ctrl.environ())
+	})
+}
+
+// TestExecuteEnv tests that only permitted variables are visible to the executed command
+func TestExecuteEnv(t *testing.T) {
+	t.Setenv("OPHIS_TEST_PUBLIC", "visible")
+	t.Setenv("OPHIS_TEST_SECRET", "hunter2")
+
+	ctrl := helperController(t, "env")
+	ctrl.env = envPolicy{passEnv: []string{"OPHIS_TEST_PUBLIC"}}
+	ctrl.Env["OPHIS_TEST_EXTRA"] = "set"
+
+	result, err := ctrl.Execute(context.Background(), helperRequest(""))
+	require.NoError(t, err)
+
+	env := strings.Split(strings.TrimSpace(string(result.Stdout)), "\n")
+	assert.Contains(t, env, "OPHIS_TEST_PUBLIC=visible")
+	assert.Contains(t, env, "OPHIS_TEST_EXTRA=set")
+	assert.NotContains(t, string(result.Stdout), "OPHIS_TEST_SECRET")
+}
+
+// TestEnvOptions tests that the generator options configure every tool
+func TestEnvOptions(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	root.AddCommand(
+		&cobra.Command{Use: "deploy", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "status", Run: func(*cobra.Command, []string) {}},
+	)
+
+	gen := NewGenerator(
+		WithEnvPassthrough("PATH"),
+		WithEnvPassthrough("HOME"),
+		WithEnv(nil, map[string]string{"NO_COLOR": "1", "DRY_RUN": "0"}),
+		WithEnv(Allow([]string{"deploy"}), map[string]string{"DRY_RUN": "1"}),
+	)
+
+	controllers := map[string]Controller{}
+	for _, ctrl := range gen.FromRootCmd(root) {
+		controllers[ctrl.Tool.Name] = ctrl
+	}
+	require.Len(t, controllers, 2)
+
+	for _, ctrl := range controllers {
+		assert.Equal(t, envPolicy{passEnv: []string{"PATH", "HOME"}}, ctrl.env)
+	}
+	assert.Equal(t, map[string]string{"NO_COLOR": "1", "DRY_RUN": "1"}, controllers["root_deploy"].Env)
+	assert.Equal(t, map[string]string{"NO_COLOR": "1", "DRY_RUN": "0"}, controllers["root_status"].Env)
+
+	for _, ctrl := range NewGenerator(WithInheritedEnv()).FromRootCmd(root) {
+		assert.True(t, ctrl.env.inheritEnv)
+		assert.Nil(t, ctrl.Env)
+	}
+}
+
+// TestWithMachineEnv tests that the variables asking for plain output can be replaced
+func TestWithMachineEnv(t *testing.T) {
+	root := &cobra.Command{Use: "root", Run: func(*cobra.Command, []string) {}}
+
+	env := DefaultMachineEnv()
+	assert.Equal(t, map[string]string{"CI": "1", "NO_COLOR": "1", "TERM": "dumb"}, env)
+	env["GIT_PAGER"] = "cat"
+	delete(env, "CI")
+
+	tools := NewGenerator(WithMachineEnv(env), PreserveANSI()).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	assert.Equal(t, []string{"GIT_PAGER=cat", "NO_COLOR=1", "TERM=dumb"}, tools[0].environ())
+	assert.NotContains(t, DefaultMachineEnv(), "GIT_PAGER", "the defaults are not changed")
+
+	tools = NewGenerator(WithMachineEnv(nil)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	assert.Empty(t, tools[0].environ())
+}
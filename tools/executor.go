@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"time"
+)
+
+const (
+	// DefaultGracePeriod is how long a cancelled command is given to exit before it is killed.
+	DefaultGracePeriod = 5 * time.Second
+
+	// killWaitDelay is how long Wait blocks on output pipes after the command is killed.
+	killWaitDelay = time.Second
+)
+
+// Invocation describes a single command execution requested by a tool call.
+type Invocation struct {
+	// Args are the command line arguments built from the tool call, excluding the executable.
+	// They start with the command path below the root command, e.g. ["get", "pods", "--", "nginx"].
+	Args []string
+	// Stdin is written to the standard input of the command, which then sees EOF.
+	// It is nil if the tool call did not provide any input.
+	Stdin io.Reader
+	// Env is the complete environment of the command in key=value form.
+	// Unlike exec.Cmd, a nil or empty Env means an empty environment, not the inherited one.
+	Env []string
+	// Dir is the working directory of the command, or empty for the working directory of the server.
+	Dir string
+	// OnOutput, if set, is called with each line the command writes while it is running,
+	// together with the stream (StreamStdout or StreamStderr) it was written to.
+	// The full output must still be returned in the ExecResult. Executors that cannot
+	// observe output as it is written may ignore it.
+	OnOutput func(stream, line string)
+}
+
+// Executor runs the command for a tool call.
+//
+// Implementations must respect cancellation of ctx. The returned ExecResult should be
+// non-nil whenever the command was started, even if an error is also returned, so that
+// partial output and the exit code reach the client.
+type Executor interface {
+	Run(ctx context.Context, inv Invocation) (*ExecResult, error)
+}
+
+// WithExecutor returns a GeneratorOption that sets the Executor used to run every generated tool.
+// By default, tools are run by a DefaultExecutor, which re-executes the current binary.
+func WithExecutor(executor Executor) GeneratorOption {
+	return func(g *Generator) {
+		g.executor = executor
+	}
+}
+
+// DefaultExecutor runs commands by re-executing the current binary (os.Executable)
+// as a subprocess with the invocation arguments, or by running its Command.
+type DefaultExecutor struct {
+	// GracePeriod is how long a cancelled or timed-out command is given to exit after being
+	// asked to terminate, before its whole process group is killed.
+	// A zero GracePeriod kills the process group immediately.
+	GracePeriod time.Duration
+	// TerminationSignal asks the process group of a cancelled or timed-out command to
+	// terminate, e.g. os.Interrupt for commands that clean up on SIGINT. If nil, SIGTERM is
+	// sent. It is ignored on Windows.
+	TerminationSignal os.Signal
+	// Command is the executable and leading arguments that the invocation arguments are appended
+	// to, e.g. []string{"go", "run", "./cmd/mytool"}. An executable without a path separator is
+	// looked up in the PATH of the server. Relative paths, including those in the arguments of
+	// "go run", are resolved against the working directory of the command.
+	// If empty, the current binary is re-executed.
+	Command []string
+	// Wrapper transforms the launch of every command, e.g. to run it in a sandbox.
+	// If nil, commands are launched directly.
+	Wrapper CommandWrapper
+}
+
+// CommandWrapper returns the executable and arguments that run executable with args inside a
+// sandbox, such as bubblewrap, firejail or a container, e.g. "bwrap --ro-bind / / -- executable
+// args...". The wrapper process is the one started by the DefaultExecutor, so it gets the
+// environment and working directory of the invocation, and its process group is terminated on
+// cancellation. The sandbox must pass the environment on to the command, and exit with it,
+// e.g. with "docker run --rm --init".
+type CommandWrapper func(executable string, args []string) (string, []string)
+
+// WithCommandWrapper returns a GeneratorOption that launches every command through wrapper,
+// e.g. to isolate commands run for untrusted clients. The wrapper is called for each call with
+// the executable and arguments that would run otherwise, and is also applied to the command
+// lines of dry runs. It configures the DefaultExecutor, and has no effect if WithExecutor or
+// WithInProcessExecution is used.
+//
+//	Example: WithCommandWrapper(func(executable string, args []string) (string, []string) {
+//		return "bwrap", append([]string{"--ro-bind", "/", "/", "--unshare-net", "--", executable}, args...)
+//	})
+func WithCommandWrapper(wrapper CommandWrapper) GeneratorOption {
+	return func(g *Generator) {
+		g.wrapper = wrapper
+	}
+}
+
+// Run executes the current binary, or the Command, with the invocation arguments.
+func (e *DefaultExecutor) Run(ctx context.Context, inv Invocation) (*ExecResult, error) {
+	executable, args, err := e.launch(inv.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create exec.Cmd and run it
+	capture := &outputCapture{}
+	cmd := exec.CommandContext(ctx, executable, args...)
+	cmd.Stdout = capture.stdoutWriter()
+	cmd.Stderr = capture.stderrWriter()
+	if inv.OnOutput != nil {
+		stdoutLines := &lineWriter{stream: StreamStdout, emit: inv.OnOutput}
+		stderrLines := &lineWriter{stream: StreamStderr, emit: inv.OnOutput}
+		defer stdoutLines.flush()
+		defer stderrLines.flush()
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, stdoutLines)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, stderrLines)
+	}
+	// A nil Stdin reads from the null device, so commands waiting on input see EOF instead of blocking
+	cmd.Stdin = inv.Stdin
+	cmd.Dir = inv.Dir
+	cmd.Env = inv.Env
+	if cmd.Env == nil {
+		cmd.Env = []string{}
+	}
+	configureProcessGroup(cmd, e.GracePeriod, e.TerminationSignal)
+	start := time.Now()
+	err = cmd.Run()
+	duration := time.Since(start)
+
+	result := capture.result(cmd.ProcessState)
+	if cmd.ProcessState != nil {
+		result.Duration = duration
+		result.Usage = processUsage(cmd.ProcessState)
+	}
+	return result, err
+}
+
+// launch returns the executable and arguments started to run the invocation arguments, as
+// transformed by the Wrapper.
+func (e *DefaultExecutor) launch(invArgs []string) (executable string, args []string, err error) {
+	command, err := e.command()
+	if err != nil {
+		return "", nil, err
+	}
+
+	executable, args = command[0], slices.Concat(command[1:], invArgs)
+	if e.Wrapper != nil {
+		if executable, args = e.Wrapper(executable, args); executable == "" {
+			return "", nil, errors.New("command wrapper returned no executable")
+		}
+	}
+
+	return executable, args, nil
+}
+
+// command returns the executable and leading arguments of the commands run by e.
+func (e *DefaultExecutor) command() ([]string, error) {
+	if len(e.Command) > 0 {
+		return e.Command, nil
+	}
+
+	executablePath, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	return []string{executablePath}, nil
+}
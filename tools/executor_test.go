@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	sq "github.com/kballard/go-shellquote"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingExecutor records invocations and returns a canned result
+type recordingExecutor struct {
+	invocations []Invocation
+	result      *ExecResult
+	err         error
+}
+
+func (e *recordingExecutor) Run(_ context.Context, inv Invocation) (*ExecResult, error) {
+	e.invocations = append(e.invocations, inv)
+	return e.result, e.err
+}
+
+// TestWithExecutor tests that a custom executor receives the built arguments
+func TestWithExecutor(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get", Run: func(_ *cobra.Command, _ []string) {}}
+	get.Flags().String("output", "", "Output format")
+	root.AddCommand(get)
+
+	executor := &recordingExecutor{result: &ExecResult{Stdout: []byte("fake")}}
+	tools := NewGenerator(WithExecutor(executor)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		FlagsParam:          map[string]any{"output": "json"},
+		PositionalArgsParam: []any{"pods"},
+	}
+
+	result, err := tools[0].Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "fake", string(result.Stdout))
+	require.Len(t, executor.invocations, 1)
+	assert.Equal(t, []string{"get", "--output=json", "--", "pods"}, executor.invocations[0].Args)
+}
+
+// TestDefaultExecutorConfig tests that the generator configures the default executor
+func TestDefaultExecutorConfig(t *testing.T) {
+	cmd := &cobra.Command{Use: "test", Run: func(_ *cobra.Command, _ []string) {}}
+
+	tools := NewGenerator().FromRootCmd(cmd)
+	require.Len(t, tools, 1)
+	assert.Equal(t, &DefaultExecutor{GracePeriod: DefaultGracePeriod}, tools[0].executor)
+
+	tools = NewGenerator(WithGracePeriod(0)).FromRootCmd(cmd)
+	require.Len(t, tools, 1)
+	assert.Equal(t, &DefaultExecutor{}, tools[0].executor)
+
+	tools = NewGenerator(WithCommand("go", "run", "./cmd/test")).FromRootCmd(cmd)
+	require.Len(t, tools, 1)
+	assert.Equal(t, &DefaultExecutor{GracePeriod: DefaultGracePeriod, Command: []string{"go", "run", "./cmd/test"}}, tools[0].executor)
+
+	tools = NewGenerator(WithTerminationSignal(os.Interrupt)).FromRootCmd(cmd)
+	require.Len(t, tools, 1)
+	assert.Equal(t, &DefaultExecutor{GracePeriod: DefaultGracePeriod, TerminationSignal: os.Interrupt}, tools[0].executor)
+}
+
+// TestDefaultExecutorCommand tests that the invocation arguments are appended to the Command
+func TestDefaultExecutorCommand(t *testing.T) {
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	// The helper runs "echo one", followed by the command path of the tool
+	ctrl := helperController(t, "two")
+	ctrl.executor = &DefaultExecutor{Command: []string{executable, "echo", "one"}}
+
+	request := helperRequest("three")
+	result, err := ctrl.Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\nthree\n", string(result.Stdout))
+
+	request.Params.Arguments = map[string]any{PositionalArgsParam: "three", DryRunParam: true}
+	ctrl.dryRun = true
+	result, err = ctrl.Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, sq.Join(executable, "echo", "one", "two", "--", "three")+"\n", string(result.Stdout))
+}
+
+// TestDefaultExecutorWrapper tests that commands are launched through the Wrapper, with the
+// environment of the invocation
+func TestDefaultExecutorWrapper(t *testing.T) {
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	var wrapped []string
+	ctrl := helperController(t, "echo")
+	ctrl.executor = &DefaultExecutor{Wrapper: func(executable string, args []string) (string, []string) {
+		wrapped = append([]string{executable}, args...)
+		return executable, append([]string{"echo", "sandboxed"}, args...)
+	}}
+
+	result, err := ctrl.Execute(context.Background(), helperRequest("one"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{executable, "echo", "--", "one"}, wrapped)
+	assert.Equal(t, "sandboxed\necho\none\n", string(result.Stdout))
+
+	request := helperRequest("one")
+	request.Params.Arguments = map[string]any{PositionalArgsParam: "one", DryRunParam: true}
+	ctrl.dryRun = true
+	result, err = ctrl.Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, sq.Join(executable, "echo", "sandboxed", "echo", "--", "one")+"\n", string(result.Stdout))
+
+	t.Run("environment", func(t *testing.T) {
+		ctrl := helperController(t, "echo")
+		ctrl.executor = &DefaultExecutor{Wrapper: func(executable string, _ []string) (string, []string) {
+			return executable, []string{"env"}
+		}}
+
+		result, err := ctrl.Execute(context.Background(), helperRequest(""))
+		require.NoError(t, err)
+		assert.Contains(t, string(result.Stdout), helperEnv+"=1")
+	})
+
+	t.Run("no executable", func(t *testing.T) {
+		ctrl := helperController(t, "echo")
+		ctrl.executor = &DefaultExecutor{Wrapper: func(string, []string) (string, []string) { return "", nil }}
+
+		_, err := ctrl.Execute(context.Background(), helperRequest(""))
+		assert.ErrorContains(t, err, "command wrapper returned no executable")
+	})
+
+	t.Run("generator option", func(t *testing.T) {
+		cmd := &cobra.Command{Use: "test", Run: func(_ *cobra.Command, _ []string) {}}
+		tools := NewGenerator(WithCommandWrapper(func(e string, a []string) (string, []string) { return e, a })).FromRootCmd(cmd)
+		require.Len(t, tools, 1)
+		require.IsType(t, &DefaultExecutor{}, tools[0].executor)
+		assert.NotNil(t, tools[0].executor.(*DefaultExecutor).Wrapper)
+	})
+}
+
+// TestCommandPathWithUnderscores tests that the command path is not derived from the tool name
+func TestCommandPathWithUnderscores(t *testing.T) {
+	root := &cobra.Command{Use: "my_cli", Run: func(_ *cobra.Command, _ []string) {}}
+	sub := &cobra.Command{Use: "get_all"}
+	leaf := &cobra.Command{Use: "pods", Run: func(_ *cobra.Command, _ []string) {}}
+	sub.AddCommand(leaf)
+	root.AddCommand(sub)
+
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(WithExecutor(executor)).FromRootCmd(root)
+	require.Len(t, tools, 2)
+
+	for _, tool := range tools {
+		_, err := tool.Execute(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+	}
+
+	require.Len(t, executor.invocations, 2)
+	assert.Equal(t, "my_cli_get_all_pods", tools[0].Tool.Name)
+	assert.Equal(t, []string{"get_all", "pods"}, executor.invocations[0].Args)
+	assert.Equal(t, "my_cli", tools[1].Tool.Name)
+	assert.Empty(t, executor.invocations[1].Args)
+}
@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"log/slog"
 	"slices"
 	"strings"
 
@@ -26,14 +25,33 @@ func AddFilter(filter Filter) GeneratorOption {
 	}
 }
 
+// IncludeHidden returns a GeneratorOption that exposes hidden commands and hidden flags,
+// which are excluded by default because they are usually not meant for end users.
+// It only lifts the default exclusion of hidden commands, so other filters are kept whatever
+// the order of the options, while a Hidden() filter set with WithFilters still applies.
+func IncludeHidden() GeneratorOption {
+	return func(g *Generator) {
+		g.includeHidden = true
+	}
+}
+
+// AnnotationMCPCommand marks the command added by ophis.Command, whatever it is named, so that
+// it is never exposed as a tool. Its value is the name of the subcommand starting the server.
+const AnnotationMCPCommand = "ophis.mcpCommand"
+
+// defaultExclude returns the filter excluding the commands that are never useful as tools.
+func defaultExclude() Filter {
+	byName := Exclude([]string{MCPCommandName, "help", "completion"})
+	return func(cmd *cobra.Command) bool {
+		_, mcp := cmd.Annotations[AnnotationMCPCommand]
+		return !mcp && byName(cmd)
+	}
+}
+
 // Exclude adds a filter to exclude listed command names from the generated tools.
 func Exclude(list []string) Filter {
 	return func(cmd *cobra.Command) bool {
-		excluded := slices.Contains(list, cmd.Name())
-		if excluded {
-			slog.Debug("excluding command by name", "command", cmd.Name(), "exclude_list", list)
-		}
-		return !excluded
+		return !slices.Contains(list, cmd.Name())
 	}
 }
 
@@ -44,11 +62,9 @@ func Allow(list []string) Filter {
 	return func(cmd *cobra.Command) bool {
 		for _, name := range list {
 			if strings.Contains(cmd.CommandPath(), name) {
-				slog.Debug("allowing command by path", "command", cmd.CommandPath(), "matched", name)
 				return true
 			}
 		}
-		slog.Debug("filtering out command not in allow list", "command", cmd.CommandPath(), "allow_list", list)
 		return false
 	}
 }
@@ -56,9 +72,111 @@ func Allow(list []string) Filter {
 // Hidden returns a filter that excludes hidden commands from the generated tools.
 func Hidden() Filter {
 	return func(cmd *cobra.Command) bool {
-		if cmd.Hidden {
-			slog.Debug("excluding hidden command", "command", cmd.Name())
-		}
 		return !cmd.Hidden
 	}
 }
+
+// defaultHidden returns the default filter excluding hidden commands, unless IncludeHidden is used.
+func (g *Generator) defaultHidden() Filter {
+	return func(cmd *cobra.Command) bool {
+		return g.includeHidden || !cmd.Hidden
+	}
+}
+
+// ExcludePaths returns a filter that excludes commands by their full command path
+// (e.g. "kubectl delete"), together with all of their subcommands.
+// Unlike Exclude, it only matches the command at that exact position in the tree.
+func ExcludePaths(paths ...string) Filter {
+	paths = normalizePaths(paths)
+	return func(cmd *cobra.Command) bool {
+		path := cmd.CommandPath()
+		for _, excluded := range paths {
+			if path == excluded {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// WithAllowedPaths returns a GeneratorOption that only exposes the commands at the given full
+// command paths (e.g. "kubectl get pods") and their subcommands. The parents of an allowed path
+// are still searched for it, but are not exposed themselves.
+// It applies in addition to the filters, so a command must also pass every filter.
+// It can be used multiple times, and the paths accumulate.
+//
+//	Example: NewGenerator(WithAllowedPaths("kubectl get", "kubectl describe"))
+func WithAllowedPaths(paths ...string) GeneratorOption {
+	return func(g *Generator) {
+		g.allowedPaths = append(g.allowedPaths, normalizePaths(paths)...)
+	}
+}
+
+// Not returns a filter that includes exactly the commands excluded by filter.
+func Not(filter Filter) Filter {
+	return func(cmd *cobra.Command) bool {
+		return !filter(cmd)
+	}
+}
+
+// AllOf returns a filter that includes a command only if every filter includes it.
+func AllOf(filters ...Filter) Filter {
+	return func(cmd *cobra.Command) bool {
+		for _, filter := range filters {
+			if !filter(cmd) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyOf returns a filter that includes a command if at least one filter includes it.
+func AnyOf(filters ...Filter) Filter {
+	return func(cmd *cobra.Command) bool {
+		for _, filter := range filters {
+			if filter(cmd) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// normalizePaths collapses repeated whitespace in command paths.
+func normalizePaths(paths []string) []string {
+	normalized := make([]string, 0, len(paths))
+	for _, path := range paths {
+		normalized = append(normalized, strings.Join(strings.Fields(path), " "))
+	}
+	return normalized
+}
+
+// isPathPrefix reports whether path is prefix or a command below it.
+func isPathPrefix(prefix, path string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+" ")
+}
+
+// allowsPath reports whether a command is at or below one of the allowed paths.
+func (g *Generator) allowsPath(cmd *cobra.Command) bool {
+	if len(g.allowedPaths) == 0 {
+		return true
+	}
+
+	path := cmd.CommandPath()
+	return slices.ContainsFunc(g.allowedPaths, func(allowed string) bool {
+		return isPathPrefix(allowed, path)
+	})
+}
+
+// searchesPath reports whether a command is allowed or may contain an allowed command.
+func (g *Generator) searchesPath(cmd *cobra.Command) bool {
+	if g.allowsPath(cmd) {
+		return true
+	}
+
+	path := cmd.CommandPath()
+	return slices.ContainsFunc(g.allowedPaths, func(allowed string) bool {
+		return isPathPrefix(path, allowed)
+	})
+}
@@ -186,3 +186,104 @@ func TestAddFilter(t *testing.T) {
 	assert.Len(t, tools, 1)
 	assert.Equal(t, "root_normal", tools[0].Tool.Name)
 }
+
+// newPathTree returns a command tree for the path based filters:
+// kubectl (runnable) > get (runnable) > pods, nodes; kubectl > delete > pods
+func newPathTree() *cobra.Command {
+	run := func(_ *cobra.Command, _ []string) {}
+	root := &cobra.Command{Use: "kubectl", Run: run}
+	get := &cobra.Command{Use: "get", Run: run}
+	get.AddCommand(&cobra.Command{Use: "pods", Run: run}, &cobra.Command{Use: "nodes", Run: run})
+	del := &cobra.Command{Use: "delete"}
+	del.AddCommand(&cobra.Command{Use: "pods", Run: run})
+	internal := &cobra.Command{Use: "internal", Hidden: true}
+	internal.AddCommand(&cobra.Command{Use: "pods", Run: run})
+	root.AddCommand(get, del, internal)
+	return root
+}
+
+func toolNames(tools []Controller) []string {
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Tool.Name)
+	}
+	return names
+}
+
+// TestExcludePaths tests that commands are excluded by full path together with their subcommands
+func TestExcludePaths(t *testing.T) {
+	tools := NewGenerator(AddFilter(ExcludePaths("kubectl  delete", "kubectl get nodes"))).FromRootCmd(newPathTree())
+	assert.ElementsMatch(t, []string{"kubectl", "kubectl_get", "kubectl_get_pods"}, toolNames(tools))
+}
+
+// TestWithAllowedPaths tests that only allowed paths are exposed while their parents are searched
+func TestWithAllowedPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []GeneratorOption
+		expected []string
+	}{
+		{
+			name:     "single leaf",
+			opts:     []GeneratorOption{WithAllowedPaths("kubectl get pods")},
+			expected: []string{"kubectl_get_pods"},
+		},
+		{
+			name:     "subtree",
+			opts:     []GeneratorOption{WithAllowedPaths("kubectl get")},
+			expected: []string{"kubectl_get", "kubectl_get_pods", "kubectl_get_nodes"},
+		},
+		{
+			name:     "accumulates",
+			opts:     []GeneratorOption{WithAllowedPaths("kubectl get nodes"), WithAllowedPaths("kubectl delete pods")},
+			expected: []string{"kubectl_get_nodes", "kubectl_delete_pods"},
+		},
+		{
+			name:     "combined with default hidden filter",
+			opts:     []GeneratorOption{WithAllowedPaths("kubectl internal pods", "kubectl get pods")},
+			expected: []string{"kubectl_get_pods"},
+		},
+		{
+			name:     "combined with filters",
+			opts:     []GeneratorOption{WithAllowedPaths("kubectl get"), AddFilter(Exclude([]string{"nodes"}))},
+			expected: []string{"kubectl_get", "kubectl_get_pods"},
+		},
+		{
+			name:     "prefix of a name is not a parent",
+			opts:     []GeneratorOption{WithAllowedPaths("kubectl ge")},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools := NewGenerator(tt.opts...).FromRootCmd(newPathTree())
+			assert.ElementsMatch(t, tt.expected, toolNames(tools))
+		})
+	}
+}
+
+// TestFilterCombinators tests Not, AllOf and AnyOf
+func TestFilterCombinators(t *testing.T) {
+	get := &cobra.Command{Use: "get"}
+	list := &cobra.Command{Use: "list", Hidden: true}
+	del := &cobra.Command{Use: "delete"}
+
+	readOnly := Exclude([]string{"delete"})
+	visible := Hidden()
+
+	assert.False(t, Not(readOnly)(get))
+	assert.True(t, Not(readOnly)(del))
+
+	both := AllOf(readOnly, visible)
+	assert.True(t, both(get))
+	assert.False(t, both(list))
+	assert.False(t, both(del))
+	assert.True(t, AllOf()(del))
+
+	either := AnyOf(Not(readOnly), visible)
+	assert.True(t, either(get))
+	assert.False(t, either(list))
+	assert.True(t, either(del))
+	assert.False(t, AnyOf()(get))
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Annotations cobra stores on the flags of a flag group, each holding the space-separated
+// names of the flags in the groups the flag belongs to.
+const (
+	requiredTogetherAnnotation  = "cobra_annotation_required_if_others_set" // MarkFlagsRequiredTogether
+	oneRequiredAnnotation       = "cobra_annotation_one_required"           // MarkFlagsOneRequired
+	mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"     // MarkFlagsMutuallyExclusive
+)
+
+// flagGroups holds the flag groups of a command.
+type flagGroups struct {
+	requiredTogether  [][]string
+	oneRequired       [][]string
+	mutuallyExclusive [][]string
+}
+
+// flagGroupsFromFlags reads the flag groups from the annotations of flags. Groups with a flag
+// that is not in flags, such as a hidden flag, are left out, since clients cannot set it.
+func flagGroupsFromFlags(flags *pflag.FlagSet) flagGroups {
+	return flagGroups{
+		requiredTogether:  groupsOf(flags, requiredTogetherAnnotation),
+		oneRequired:       groupsOf(flags, oneRequiredAnnotation),
+		mutuallyExclusive: groupsOf(flags, mutuallyExclusiveAnnotation),
+	}
+}
+
+// groupsOf returns the distinct groups stored in an annotation of flags. The flags of a group
+// keep the order they were marked in, and the groups are sorted.
+func groupsOf(flags *pflag.FlagSet, annotation string) [][]string {
+	seen := map[string]bool{}
+	var groups [][]string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		for _, group := range flag.Annotations[annotation] {
+			if seen[group] {
+				continue
+			}
+			seen[group] = true
+
+			names := strings.Fields(group)
+			if slices.ContainsFunc(names, func(name string) bool { return flags.Lookup(name) == nil }) {
+				continue
+			}
+			groups = append(groups, names)
+		}
+	})
+
+	slices.SortFunc(groups, func(a, b []string) int { return slices.Compare(a, b) })
+	return groups
+}
+
+// empty reports whether the command has no flag groups.
+func (fg flagGroups) empty() bool {
+	return len(fg.requiredTogether) == 0 && len(fg.oneRequired) == 0 && len(fg.mutuallyExclusive) == 0
+}
+
+// schema returns JSON schema constraints of the flags object that express the flag groups.
+func (fg flagGroups) schema() []any {
+	var constraints []any
+	for _, group := range fg.requiredTogether {
+		// Either all flags of the group are set, or none of them
+		constraints = append(constraints, map[string]any{
+			"anyOf": []any{
+				map[string]any{"required": group},
+				map[string]any{"not": map[string]any{"anyOf": requiredEach(group)}},
+			},
+		})
+	}
+	for _, group := range fg.oneRequired {
+		constraints = append(constraints, map[string]any{"anyOf": requiredEach(group)})
+	}
+	for _, group := range fg.mutuallyExclusive {
+		// No two flags of the group are set
+		var pairs []any
+		for i, a := range group {
+			for _, b := range group[i+1:] {
+				pairs = append(pairs, map[string]any{"required": []string{a, b}})
+			}
+		}
+		constraints = append(constraints, map[string]any{"not": map[string]any{"anyOf": pairs}})
+	}
+
+	return constraints
+}
+
+// requiredEach returns a schema requiring each of names on its own.
+func requiredEach(names []string) []any {
+	schemas := make([]any, 0, len(names))
+	for _, name := range names {
+		schemas = append(schemas, map[string]any{"required": []string{name}})
+	}
+
+	return schemas
+}
+
+// checkFlagGroups returns an error if the flags in flagMap violate a flag group of flags.
+func checkFlagGroups(flagMap map[string]any, flags *pflag.FlagSet) error {
+	groups := flagGroupsFromFlags(flags)
+	if groups.empty() {
+		return nil
+	}
+
+	// Duplicate names were already rejected while building the flag arguments
+	flagMap, _ = normalizeFlagNames(flagMap, flags)
+	isSet := func(name string) bool { return flagIsSet(flags.Lookup(name), flagMap[name]) }
+
+	for _, group := range groups.requiredTogether {
+		set, missing := partition(group, isSet)
+		if len(set) > 0 && len(missing) > 0 {
+			return fmt.Errorf("flags %s must be set together, missing %s", joinFlags(group, "and"), joinFlags(missing, "and"))
+		}
+	}
+	for _, group := range groups.oneRequired {
+		if set, _ := partition(group, isSet); len(set) == 0 {
+			return fmt.Errorf("one of the flags %s is required", joinFlags(group, "or"))
+		}
+	}
+	for _, group := range groups.mutuallyExclusive {
+		if set, _ := partition(group, isSet); len(set) > 1 {
+			return fmt.Errorf("flags %s are mutually exclusive", joinFlags(set, "and"))
+		}
+	}
+
+	return nil
+}
+
+// flagIsSet reports whether a flag value produces a command line argument, see appendFlagArg.
+func flagIsSet(flag *pflag.Flag, value any) bool {
+	if isCountFlag(flag) {
+		count, err := flagCount(value)
+		return err == nil && count > 0
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v || (flag.DefValue == "true" && !hasOptionalValue(flag))
+	case []any:
+		return len(v) > 0
+	}
+
+	return true
+}
+
+// partition splits names into those for which isSet reports true, and the others.
+func partition(names []string, isSet func(string) bool) (set, unset []string) {
+	for _, name := range names {
+		if isSet(name) {
+			set = append(set, name)
+		} else {
+			unset = append(unset, name)
+		}
+	}
+
+	return set, unset
+}
+
+// joinFlags formats flag names as a list, e.g. "--json, --yaml or --table".
+func joinFlags(names []string, conjunction string) string {
+	flags := make([]string, len(names))
+	for i, name := range names {
+		flags[i] = "--" + name
+	}
+
+	if len(flags) == 1 {
+		return flags[0]
+	}
+
+	return strings.Join(flags[:len(flags)-1], ", ") + " " + conjunction + " " + flags[len(flags)-1]
+}
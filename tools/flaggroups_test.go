@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFlagGroups tests that cobra flag groups are expressed in the schema and enforced before execution
+func TestFlagGroups(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}}
+	get.Flags().Bool("json", false, "JSON output")
+	get.Flags().Bool("yaml", false, "YAML output")
+	get.Flags().String("user", "", "User")
+	get.Flags().String("password", "", "Password")
+	get.Flags().String("name", "", "Name")
+	get.Flags().String("id", "", "ID")
+	get.MarkFlagsMutuallyExclusive("json", "yaml")
+	get.MarkFlagsRequiredTogether("user", "password")
+	get.MarkFlagsOneRequired("name", "id")
+	root.AddCommand(get)
+
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(WithExecutor(executor)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	schema := tools[0].Tool.InputSchema.Properties[FlagsParam].(map[string]any)
+	assert.Len(t, schema["allOf"], 3)
+
+	tests := []struct {
+		name  string
+		flags map[string]any
+		err   string
+	}{
+		{"valid", map[string]any{"name": "web", "json": true, "yaml": false}, ""},
+		{"mutually exclusive", map[string]any{"name": "web", "json": true, "yaml": true}, "flags --json and --yaml are mutually exclusive"},
+		{"required together", map[string]any{"id": "1", "user": "admin"}, "flags --user and --password must be set together, missing --password"},
+		{"all required together", map[string]any{"id": "1", "user": "admin", "password": "secret"}, ""},
+		{"one required", map[string]any{"json": true}, "one of the flags --name or --id is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor.invocations = nil
+			var request mcp.CallToolRequest
+			request.Params.Arguments = map[string]any{FlagsParam: tt.flags}
+
+			_, err := tools[0].Execute(context.Background(), request)
+			if tt.err == "" {
+				require.NoError(t, err)
+				assert.Len(t, executor.invocations, 1)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+			assert.Empty(t, executor.invocations)
+		})
+	}
+}
+
+// TestFlagGroupsSchema tests the JSON schema constraints of flag groups
+func TestFlagGroupsSchema(t *testing.T) {
+	groups := flagGroups{mutuallyExclusive: [][]string{{"json", "table", "yaml"}}}
+	assert.Equal(t, []any{
+		map[string]any{"not": map[string]any{"anyOf": []any{
+			map[string]any{"required": []string{"json", "table"}},
+			map[string]any{"required": []string{"json", "yaml"}},
+			map[string]any{"required": []string{"table", "yaml"}},
+		}}},
+	}, groups.schema())
+}
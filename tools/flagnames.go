@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// WithFlagNameMatching returns a GeneratorOption that maps the flag names sent by clients to the
+// flags of the command, ignoring case, dashes and underscores. Models often name JSON keys in
+// camelCase or snake_case, so that "dryRun" and "dry_run" both select the --dry-run flag.
+//
+//	Example: NewGenerator(WithFlagNameMatching())
+func WithFlagNameMatching() GeneratorOption {
+	return func(g *Generator) {
+		g.matchFlagNames = true
+	}
+}
+
+// matchFlagNames replaces the keys of flagMap with the names of the flags they match.
+// Shorthands and exact names are kept, and are normalized later by normalizeFlagNames.
+func matchFlagNames(flagMap map[string]any, flags *pflag.FlagSet) (map[string]any, error) {
+	// Index the flags by their folded name, recording ambiguous names with an empty entry
+	index := map[string]string{}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		folded := foldFlagName(flag.Name)
+		if _, ok := index[folded]; ok {
+			index[folded] = ""
+		} else {
+			index[folded] = flag.Name
+		}
+	})
+
+	matched := make(map[string]any, len(flagMap))
+	for key, value := range flagMap {
+		name := strings.TrimLeft(key, "-")
+		if flags.Lookup(name) == nil && (len(name) != 1 || flags.ShorthandLookup(name) == nil) {
+			match, ok := index[foldFlagName(name)]
+			switch {
+			case !ok:
+				return nil, unknownFlagsError([]string{name}, flags)
+			case match == "":
+				return nil, fmt.Errorf("flag %q matches more than one flag, %s", key, validFlags(flags))
+			}
+			name = match
+		}
+
+		if _, ok := matched[name]; ok {
+			return nil, fmt.Errorf("flag %q was provided more than once", name)
+		}
+		matched[name] = value
+	}
+
+	return matched, nil
+}
+
+// foldFlagName returns the lower case form of a flag name without dashes and underscores,
+// so that "dry-run", "dry_run" and "dryRun" are all folded to "dryrun".
+func foldFlagName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithFlagNameMatching tests that flag names in other casings select the flags of the command
+func TestWithFlagNameMatching(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	deploy := &cobra.Command{Use: "deploy", Run: func(*cobra.Command, []string) {}}
+	deploy.Flags().Bool("dry-run", false, "Dry run")
+	deploy.Flags().StringP("output-format", "o", "", "Output format")
+	deploy.Flags().Int("max_retries", 0, "Retries")
+	root.AddCommand(deploy)
+
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(WithExecutor(executor), WithFlagNameMatching()).FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	execute := func(flags map[string]any) ([]string, error) {
+		executor.invocations = nil
+		var request mcp.CallToolRequest
+		request.Params.Arguments = map[string]any{FlagsParam: flags}
+		if _, err := tools[0].Execute(context.Background(), request); err != nil {
+			return nil, err
+		}
+		require.Len(t, executor.invocations, 1)
+		return executor.invocations[0].Args, nil
+	}
+
+	args, err := execute(map[string]any{"dryRun": true, "OUTPUT_FORMAT": "json", "maxRetries": 3})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deploy", "--dry-run", "--max_retries=3", "--output-format=json"}, args)
+
+	args, err = execute(map[string]any{"o": "yaml", "--dry-run": true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deploy", "--dry-run", "--output-format=yaml"}, args)
+
+	_, err = execute(map[string]any{"force": true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown flag --force, valid flags are: dry-run, max_retries, output-format")
+
+	_, err = execute(map[string]any{"dryRun": true, "dry_run": false})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than once")
+
+	// Without the option, only exact names are accepted
+	tools = NewGenerator(WithExecutor(executor)).FromRootCmd(root)
+	_, err = execute(map[string]any{"dryRun": true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown flag --dryRun")
+}
+
+// TestMatchFlagNamesAmbiguous tests that names matching several flags are rejected
+func TestMatchFlagNamesAmbiguous(t *testing.T) {
+	cmd := &cobra.Command{Use: "cli"}
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.Flags().Bool("dryrun", false, "")
+
+	matched, err := matchFlagNames(map[string]any{"dryrun": true}, cmd.Flags())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"dryrun": true}, matched)
+
+	_, err = matchFlagNames(map[string]any{"dryRun": true}, cmd.Flags())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matches more than one flag")
+}
@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+)
+
+// Formatter turns the output of a command into the content of its tool result.
+//
+// result is nil if the command was not run, and err is the error of a failed command, in which
+// case the content is returned as an error result. Format returns an error only if the output
+// cannot be formatted at all, not if the command failed. Blob resources without a URI are given
+// the stdout URI of the tool.
+type Formatter interface {
+	Format(result *ExecResult, err error) ([]mcp.Content, error)
+}
+
+// formatterOverride sets the Formatter of the tools matched by selector.
+type formatterOverride struct {
+	selector  Filter
+	formatter Formatter
+}
+
+// WithFormatter returns a GeneratorOption that sets the Formatter turning the output of the tools
+// matched by selector into MCP content, or of every tool if selector is nil. Later calls win on
+// conflicts, so a default for every tool is set first and overridden for single tools. Without
+// it, output is formatted by PlainText. Handlers set by WithHandler or WithCommandHandler take
+// precedence.
+//
+//	Example: NewGenerator(WithFormatter(nil, ErrorAware{}), WithFormatter(Allow([]string{"logs"}), Markdown{}))
+func WithFormatter(selector Filter, formatter Formatter) GeneratorOption {
+	return func(g *Generator) {
+		g.formatters = append(g.formatters, formatterOverride{selector: selector, formatter: formatter})
+	}
+}
+
+// WithCodeFence returns a GeneratorOption that wraps the text output of the tools matched by
+// selector, or of every tool if selector is nil, in a fenced code block with language as its info
+// string, e.g. "yaml" or "" for none, so that clients rendering tool results as markdown keep the
+// alignment of tables and logs. Binary and JSON output is returned unwrapped. It is a shorthand
+// for WithFormatter with a Markdown formatter, and later calls win on conflicts.
+//
+//	Example: NewGenerator(WithCodeFence(nil, ""), WithCodeFence(Allow([]string{"config"}), "yaml"))
+func WithCodeFence(selector Filter, language string) GeneratorOption {
+	return WithFormatter(selector, Markdown{Language: language})
+}
+
+// formatterFor returns the Formatter of a generated tool, nil for PlainText.
+func (g *Generator) formatterFor(cmd *cobra.Command) Formatter {
+	var formatter Formatter
+	for _, override := range g.formatters {
+		if override.selector == nil || override.selector(cmd) {
+			formatter = override.formatter
+		}
+	}
+
+	return formatter
+}
+
+// returnsStdout reports whether formatter returns the stdout of a successful command as its first
+// content block, which WithCompression and WithOutputResources then replace. The content of
+// other formatters is left as is.
+func returnsStdout(formatter Formatter) bool {
+	switch formatter.(type) {
+	case PlainText, *PlainText, ErrorAware, *ErrorAware, JSONPassthrough, *JSONPassthrough:
+		return true
+	default:
+		return false
+	}
+}
+
+// formatResult builds the tool result of a command from the content returned by formatter.
+func formatResult(toolName string, formatter Formatter, result *ExecResult, err error) (*mcp.CallToolResult, error) {
+	content, formatErr := formatter.Format(result, err)
+	if formatErr != nil {
+		return nil, fmt.Errorf("failed to format the output of %s: %w", toolName, formatErr)
+	}
+
+	for i, item := range content {
+		resource, ok := item.(mcp.EmbeddedResource)
+		if !ok {
+			continue
+		}
+		if blob, ok := resource.Resource.(mcp.BlobResourceContents); ok && blob.URI == "" {
+			blob.URI = fmt.Sprintf("ophis://tools/%s/stdout", toolName)
+			resource.Resource = blob
+			content[i] = resource
+		}
+	}
+
+	return &mcp.CallToolResult{Content: content, IsError: err != nil}, nil
+}
+
+// PlainText is the default Formatter. Stdout is returned as the primary text content, or as
+// base64 encoded image, audio or blob content if it is not valid UTF-8. On success, stderr is
+// attached as a secondary text content block; on failure, the error, stdout and stderr are
+// returned as a single text.
+type PlainText struct{}
+
+// Format implements Formatter.
+func (PlainText) Format(result *ExecResult, err error) ([]mcp.Content, error) {
+	if result == nil {
+		result = &ExecResult{ExitCode: -1}
+	}
+
+	if err != nil {
+		errMsg := failureMessage(result, err)
+		if len(result.Stdout) > 0 {
+			errMsg += fmt.Sprintf("\nOutput: %s", result.Stdout)
+		}
+		// The error may hold the stderr already, see WithErrorStderr
+		var withStderr *stderrError
+		if len(result.Stderr) > 0 && !errors.As(err, &withStderr) {
+			errMsg += fmt.Sprintf("\nStderr: %s", result.Stderr)
+		}
+		return []mcp.Content{mcp.NewTextContent(errMsg)}, nil
+	}
+
+	return appendStderr([]mcp.Content{stdoutContent(result.Stdout)}, result), nil
+}
+
+// ErrorAware formats successful output like PlainText, but returns the output of a failed command
+// in separate content blocks: the error first, then stdout and stderr, each labelled, so that
+// clients can tell the cause of the failure apart from the partial output.
+type ErrorAware struct{}
+
+// Format implements Formatter.
+func (ErrorAware) Format(result *ExecResult, err error) ([]mcp.Content, error) {
+	if err == nil {
+		return PlainText{}.Format(result, nil)
+	}
+	if result == nil {
+		result = &ExecResult{ExitCode: -1}
+	}
+
+	// Stderr gets a block of its own, rather than being repeated in the error
+	var withStderr *stderrError
+	if errors.As(err, &withStderr) {
+		err = withStderr.err
+	}
+
+	content := []mcp.Content{mcp.NewTextContent(failureMessage(result, err))}
+	if len(result.Stdout) > 0 {
+		content = append(content, mcp.NewTextContent(fmt.Sprintf("Output: %s", result.Stdout)))
+	}
+	return appendStderr(content, result), nil
+}
+
+// JSONPassthrough returns the stdout of a successful command unchanged as the only text content
+// if it is valid JSON, so that clients parsing it are not confused by stderr. Other output, and
+// the output of failed commands, is formatted like PlainText.
+type JSONPassthrough struct{}
+
+// Format implements Formatter.
+func (JSONPassthrough) Format(result *ExecResult, err error) ([]mcp.Content, error) {
+	if err != nil || result == nil || !json.Valid(result.Stdout) {
+		return PlainText{}.Format(result, err)
+	}
+
+	return []mcp.Content{mcp.NewTextContent(string(result.Stdout))}, nil
+}
+
+// Markdown wraps the text stdout of a successful command in a fenced code block, so that clients
+// rendering tool results as markdown keep the alignment of tables and logs. Language is the info
+// string of the fence, e.g. "yaml", and may be empty. Empty, binary and JSON output, which is
+// read by machines rather than rendered, and the output of failed commands, is formatted like
+// PlainText.
+type Markdown struct {
+	Language string
+}
+
+// Format implements Formatter.
+func (m Markdown) Format(result *ExecResult, err error) ([]mcp.Content, error) {
+	if err != nil || result == nil || len(result.Stdout) == 0 || !utf8.Valid(result.Stdout) || json.Valid(result.Stdout) {
+		return PlainText{}.Format(result, err)
+	}
+
+	return appendStderr([]mcp.Content{mcp.NewTextContent(codeFence(string(result.Stdout), m.Language))}, result), nil
+}
+
+// codeFence wraps text in a fenced code block whose fence is longer than any run of backticks
+// in text, so that the text cannot close it.
+func codeFence(text, language string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	return fence + language + "\n" + text + fence
+}
+
+// failureMessage describes the failure of a command.
+func failureMessage(result *ExecResult, err error) string {
+	switch {
+	case result.TimedOut:
+		// The error already describes the timeout
+	case result.Killed:
+		return fmt.Sprintf("command was terminated by a signal: %s", err.Error())
+	case result.ExitCode > 0:
+		return fmt.Sprintf("command exited with code %d: %s", result.ExitCode, err.Error())
+	}
+
+	return fmt.Sprintf("command execution failed: %s", err.Error())
+}
+
+// hasContent reports whether a tool result holds any content other than blank text.
+func hasContent(toolResult *mcp.CallToolResult) bool {
+	for _, item := range toolResult.Content {
+		if text, ok := item.(mcp.TextContent); !ok || strings.TrimSpace(text.Text) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendStderr appends the stderr of result as a labelled text block, if there is any.
+func appendStderr(content []mcp.Content, result *ExecResult) []mcp.Content {
+	if len(result.Stderr) == 0 {
+		return content
+	}
+
+	return append(content, mcp.NewTextContent(fmt.Sprintf("Stderr: %s", result.Stderr)))
+}
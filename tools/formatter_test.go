@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contentTexts returns the text of every text content block.
+func contentTexts(t *testing.T, content []mcp.Content) []string {
+	t.Helper()
+
+	texts := make([]string, 0, len(content))
+	for _, item := range content {
+		text, ok := item.(mcp.TextContent)
+		require.True(t, ok, "content %T is not text", item)
+		texts = append(texts, text.Text)
+	}
+
+	return texts
+}
+
+// TestFormatters tests the output of the built-in formatters
+func TestFormatters(t *testing.T) {
+	success := &ExecResult{Stdout: []byte(`{"ok":true}`), Stderr: []byte("warning")}
+	failure := &ExecResult{Stdout: []byte("partial"), Stderr: []byte("boom"), ExitCode: 2}
+	exitErr := errors.New("exit status 2")
+
+	t.Run("plain text", func(t *testing.T) {
+		content, err := PlainText{}.Format(success, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`{"ok":true}`, "Stderr: warning"}, contentTexts(t, content))
+
+		content, err = PlainText{}.Format(failure, exitErr)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"command exited with code 2: exit status 2\nOutput: partial\nStderr: boom"}, contentTexts(t, content))
+	})
+
+	t.Run("error aware", func(t *testing.T) {
+		content, err := ErrorAware{}.Format(success, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`{"ok":true}`, "Stderr: warning"}, contentTexts(t, content))
+
+		content, err = ErrorAware{}.Format(failure, exitErr)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"command exited with code 2: exit status 2", "Output: partial", "Stderr: boom"}, contentTexts(t, content))
+
+		// The stderr excerpt of the error is not repeated
+		content, err = ErrorAware{}.Format(failure, &stderrError{err: exitErr, stderr: "boom"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"command exited with code 2: exit status 2", "Output: partial", "Stderr: boom"}, contentTexts(t, content))
+	})
+
+	t.Run("json passthrough", func(t *testing.T) {
+		content, err := JSONPassthrough{}.Format(success, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`{"ok":true}`}, contentTexts(t, content))
+
+		content, err = JSONPassthrough{}.Format(&ExecResult{Stdout: []byte("not json"), Stderr: []byte("warning")}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"not json", "Stderr: warning"}, contentTexts(t, content))
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		content, err := Markdown{Language: "yaml"}.Format(&ExecResult{Stdout: []byte("ok: true"), Stderr: []byte("warning")}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"```yaml\nok: true\n```", "Stderr: warning"}, contentTexts(t, content))
+
+		// JSON and empty output are not wrapped
+		content, err = Markdown{}.Format(success, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`{"ok":true}`, "Stderr: warning"}, contentTexts(t, content))
+		content, err = Markdown{}.Format(&ExecResult{}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{""}, contentTexts(t, content))
+
+		// A longer fence keeps backticks in the output from closing the block
+		content, err = Markdown{}.Format(&ExecResult{Stdout: []byte("a ``` b\n")}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"````\na ``` b\n````"}, contentTexts(t, content))
+
+		content, err = Markdown{}.Format(failure, exitErr)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"command exited with code 2: exit status 2\nOutput: partial\nStderr: boom"}, contentTexts(t, content))
+	})
+}
+
+// upperFormatter fails for empty output, to test formatter errors.
+type upperFormatter struct{}
+
+func (upperFormatter) Format(result *ExecResult, _ error) ([]mcp.Content, error) {
+	if result == nil || len(result.Stdout) == 0 {
+		return nil, errors.New("no output")
+	}
+
+	return []mcp.Content{mcp.NewTextContent("OUT: " + string(result.Stdout))}, nil
+}
+
+// TestWithFormatter tests that formatters are chosen per tool, with later options winning
+func TestWithFormatter(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	for _, name := range []string{"get", "logs"} {
+		root.AddCommand(&cobra.Command{Use: name, Run: func(*cobra.Command, []string) {}})
+	}
+
+	generator := NewGenerator(
+		WithFormatter(nil, upperFormatter{}),
+		WithFormatter(Allow([]string{"logs"}), Markdown{}),
+	)
+	controllers := generator.FromRootCmd(root)
+	require.Len(t, controllers, 2)
+
+	byName := map[string]*Controller{}
+	for i := range controllers {
+		byName[controllers[i].Tool.Name] = &controllers[i]
+	}
+
+	result, err := byName["cli_get"].Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{Stdout: []byte("pods")}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"OUT: pods"}, contentTexts(t, result.Content))
+
+	result, err = byName["cli_logs"].Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{Stdout: []byte("line")}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"```\nline\n```"}, contentTexts(t, result.Content))
+
+	result, err = byName["cli_logs"].Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{ExitCode: 1}, errors.New("exit status 1"))
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	_, err = byName["cli_get"].Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{}, nil)
+	assert.ErrorContains(t, err, "failed to format the output of cli_get: no output")
+}
+
+// TestWithCodeFence tests that text output is fenced with the language of the tool
+func TestWithCodeFence(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	for _, name := range []string{"status", "config"} {
+		root.AddCommand(&cobra.Command{Use: name, Run: func(*cobra.Command, []string) {}})
+	}
+
+	controllers := NewGenerator(
+		WithCodeFence(nil, ""),
+		WithCodeFence(Allow([]string{"config"}), "yaml"),
+	).FromRootCmd(root)
+	require.Len(t, controllers, 2)
+
+	fenced := map[string]string{}
+	for _, controller := range controllers {
+		result, err := controller.Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{Stdout: []byte("a: 1\n")}, nil)
+		require.NoError(t, err)
+		fenced[controller.Tool.Name] = contentTexts(t, result.Content)[0]
+	}
+	assert.Equal(t, map[string]string{
+		"cli_status": "```\na: 1\n```",
+		"cli_config": "```yaml\na: 1\n```",
+	}, fenced)
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	result, err := controllers[0].Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{Stdout: png}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, mcp.ImageContent{}, result.Content[0])
+}
@@ -3,37 +3,88 @@ package tools
 import (
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
-func toolOptsFromCmd(cmd *cobra.Command) []mcp.ToolOption {
+func toolOptsFromCmd(logger *slog.Logger, cmd *cobra.Command, flags *pflag.FlagSet, spec *argsSpec) []mcp.ToolOption {
 	toolOptions := []mcp.ToolOption{
 		mcp.WithDescription(descFromCmd(cmd)),
 	}
 
 	// add flags to tool
-	flagMap := flagMapFromCmd(cmd)
-	toolOptions = append(toolOptions, mcp.WithObject(FlagsParam,
+	flagMap := flagMapFromCmd(logger, cmd, flags)
+	flagOptions := []mcp.PropertyOption{
 		mcp.Description("Flag options"),
 		mcp.Properties(flagMap),
 		mcp.Required(),
-	))
+	}
+	if required := requiredFlags(flags); len(required) > 0 {
+		flagOptions = append(flagOptions, func(schema map[string]any) {
+			schema["required"] = required
+		})
+	}
+	if groups := flagGroupsFromFlags(flags); !groups.empty() {
+		flagOptions = append(flagOptions, func(schema map[string]any) {
+			schema["allOf"] = groups.schema()
+		})
+	}
+	toolOptions = append(toolOptions, mcp.WithObject(FlagsParam, flagOptions...))
 
 	// Add an "args" parameter for positional arguments
-	argsDescription := argsDescFromCmd(cmd)
-	toolOptions = append(toolOptions, mcp.WithString(PositionalArgsParam,
-		mcp.Description(argsDescription),
-		mcp.Required(),
+	toolOptions = append(toolOptions, withProperty(PositionalArgsParam, argsSchema(argsDescFromCmd(cmd, spec), spec)))
+
+	// Add an optional "stdin" parameter for commands that read piped input
+	toolOptions = append(toolOptions, mcp.WithString(StdinParam,
+		mcp.Description("Optional data written to the standard input of the command"),
 	))
 
 	return toolOptions
 }
 
-func argsDescFromCmd(cmd *cobra.Command) string {
+// withProperty returns a ToolOption that adds a required property with a raw JSON schema.
+func withProperty(name string, schema map[string]any) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		t.InputSchema.Properties[name] = schema
+		t.InputSchema.Required = append(t.InputSchema.Required, name)
+	}
+}
+
+// argsSchema builds the schema of the positional arguments parameter.
+// Arguments may be given as an array of strings, which are passed verbatim,
+// or as a single string, which is split using shell quoting rules.
+func argsSchema(description string, spec *argsSpec) map[string]any {
+	arrayItems := map[string]any{"type": "string"}
+	arraySchema := map[string]any{
+		"type":        "array",
+		"description": "Arguments passed verbatim, one per element (recommended)",
+		"items":       arrayItems,
+	}
+	stringSchema := map[string]any{
+		"type":        "string",
+		"description": "Arguments split using shell quoting rules",
+	}
+
+	if spec != nil && len(spec.valid) > 0 {
+		arrayItems["enum"] = spec.valid
+		if spec.min == 1 && spec.max == 1 {
+			// A single argument restricted to ValidArgs can be expressed as an enum
+			stringSchema["enum"] = spec.valid
+		}
+	}
+
+	return map[string]any{
+		"description": description,
+		"anyOf":       []any{arraySchema, stringSchema},
+	}
+}
+
+func argsDescFromCmd(cmd *cobra.Command, spec *argsSpec) string {
 	argsDescription := "Positional arguments"
 	if cmd.Use != "" {
 		// Strip the command name from the Use field to avoid redundancy
@@ -52,36 +103,64 @@ func argsDescFromCmd(cmd *cobra.Command) string {
 		// so we don't add anything to the description
 	}
 
+	if spec != nil {
+		argsDescription += "\n" + spec.describe()
+	}
+
 	return argsDescription
 }
 
-func flagMapFromCmd(cmd *cobra.Command) map[string]any {
-	// map for tool object
-	flagMap := map[string]any{}
+// flagsFromCmd collects the visible local and inherited flags of a command into a single flag set.
+// Hidden flags are only collected if includeHidden is set.
+// Local flags take precedence over inherited flags with the same name.
+func flagsFromCmd(logger *slog.Logger, cmd *cobra.Command, includeHidden bool) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
 
-	// add local flags to flag map
+	// add local flags to flag set
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
-		if flag.Hidden {
-			slog.Debug("skipping hidden flag", "flag", flag.Name, "command", cmd.Name())
+		if flag.Hidden && !includeHidden {
+			logger.Debug("skipping hidden flag", "flag", flag.Name, "command", cmd.Name())
 			return
 		}
 
-		flagMap[flag.Name] = flagToolOption(flag)
+		flags.AddFlag(flag)
 	})
 
-	// add inherited flags to flag map
+	// add inherited flags to flag set
 	cmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
-		if flag.Hidden {
+		if flag.Hidden && !includeHidden {
 			return
 		}
 
 		// Check if this flag was already added from local flags to avoid duplicates
-		if _, ok := flagMap[flag.Name]; !ok {
-			flagMap[flag.Name] = flagToolOption(flag)
+		if flags.Lookup(flag.Name) == nil {
+			flags.AddFlag(flag)
 		}
 	})
 
-	slog.Debug("collected flags for command",
+	return flags
+}
+
+// requiredFlags returns the sorted names of the flags marked with cobra's MarkFlagRequired.
+func requiredFlags(flags *pflag.FlagSet) []string {
+	var required []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if values := flag.Annotations[cobra.BashCompOneRequiredFlag]; len(values) > 0 && values[0] == "true" {
+			required = append(required, flag.Name)
+		}
+	})
+
+	return required
+}
+
+func flagMapFromCmd(logger *slog.Logger, cmd *cobra.Command, flags *pflag.FlagSet) map[string]any {
+	// map for tool object
+	flagMap := map[string]any{}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		flagMap[flag.Name] = flagToolOption(logger, flag)
+	})
+
+	logger.Debug("collected flags for command",
 		"command", cmd.Name(),
 		"total_flags", len(flagMap),
 	)
@@ -89,44 +168,99 @@ func flagMapFromCmd(cmd *cobra.Command) map[string]any {
 	return flagMap
 }
 
-// descFromCmd creates a description for the MCP tool from the Cobra command
+// maxDescriptionLength is the number of characters of Long help kept in a tool description.
+// Examples are appended in full after it.
+const maxDescriptionLength = 2048
+
+// descFromCmd creates a description for the MCP tool from the Cobra command.
+// It uses the Long help, falling back to Short, followed by the fenced Example.
 func descFromCmd(cmd *cobra.Command) string {
-	desc := cmd.Long
+	desc := strings.TrimSpace(cmd.Long)
 	if desc == "" {
-		desc = cmd.Short
+		desc = strings.TrimSpace(cmd.Short)
 	}
+	desc = truncateDescription(desc, maxDescriptionLength)
 
-	if cmd.Example != "" {
-		desc += "\nExamples:\n" + cmd.Example
+	if example := strings.Trim(cmd.Example, "\n"); strings.TrimSpace(example) != "" {
+		desc += "\n\nExamples:\n```\n" + strings.TrimRight(example, " \t\n") + "\n```"
 	}
 
 	return desc
 }
 
-func flagToolOption(flag *pflag.Flag) map[string]any {
+// truncateDescription shortens desc to at most limit characters, cutting at the last
+// whitespace so that words are kept whole, and marks the text as truncated.
+func truncateDescription(desc string, limit int) string {
+	runes := []rune(desc)
+	if len(runes) <= limit {
+		return desc
+	}
+
+	cut := string(runes[:limit])
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > limit/2 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRightFunc(cut, unicode.IsSpace) + "... (truncated)"
+}
+
+func flagToolOption(logger *slog.Logger, flag *pflag.Flag) map[string]any {
 	description := flag.Usage
 	if description == "" {
 		description = fmt.Sprintf("Flag: %s", flag.Name)
 	}
+	if !defaultIsZero(flag) {
+		// Quote string defaults like the cobra help output, so that spaces stay visible
+		if flag.Value.Type() == "string" {
+			description += fmt.Sprintf(" (default %q)", flag.DefValue)
+		} else {
+			description += fmt.Sprintf(" (default %s)", flag.DefValue)
+		}
+	}
+	if flag.Shorthand != "" {
+		description += fmt.Sprintf(" (shorthand: -%s)", flag.Shorthand)
+	}
 
 	// Improve type detection for better MCP tool parameter definitions
 	flagType := flag.Value.Type()
 	var schema map[string]any
 	switch flagType {
-	case "stringSlice", "stringArray":
+	case "stringSlice", "stringArray", "ipSlice":
 		schema = map[string]any{
 			"type": "array",
 			"items": map[string]any{
 				"type": "string",
 			},
 		}
-	case "intSlice":
+	case "durationSlice":
+		schema = map[string]any{
+			"type":  "array",
+			"items": durationSchema(),
+		}
+	case "duration":
+		schema = durationSchema()
+		description += " (duration, e.g. 30s or 1m30s)"
+	case "intSlice", "int32Slice", "int64Slice", "uintSlice":
 		schema = map[string]any{
 			"type": "array",
 			"items": map[string]any{
 				"type": "integer",
 			},
 		}
+	case "float32Slice", "float64Slice":
+		schema = map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "number",
+			},
+		}
+	case "boolSlice":
+		schema = map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "boolean",
+			},
+		}
 	case "bool":
 		schema = map[string]any{
 			"type": "boolean",
@@ -135,23 +269,115 @@ func flagToolOption(flag *pflag.Flag) map[string]any {
 		schema = map[string]any{
 			"type": "integer",
 		}
+	case "count":
+		// Count flags are given once per increment, e.g. -vvv for 3
+		schema = map[string]any{
+			"type":    "integer",
+			"minimum": 0,
+		}
+		description += " (number of times the flag is given)"
 	case "float32", "float64":
 		schema = map[string]any{
 			"type": "number",
 		}
+	case "string":
+		schema = map[string]any{
+			"type": "string",
+		}
 	default:
+		// Custom pflag.Value implementations are passed as their string form
 		schema = map[string]any{
 			"type": "string",
 		}
+		description += fmt.Sprintf(" (type: %s)", flagType)
 	}
 
-	slog.Debug("mapped flag type",
+	logger.Debug("mapped flag type",
 		"flag", flag.Name,
 		"original_type", flagType,
 		"schema", schema,
 	)
 
-	// Add description to the schema
+	// Add description and default value to the schema
+	if defValue, ok := flagDefault(flag, schema); ok {
+		schema["default"] = defValue
+	}
+	if hasOptionalValue(flag) {
+		// The flag may also be given alone, which true selects
+		if schemaType, ok := schema["type"].(string); ok {
+			schema["type"] = []any{schemaType, "boolean"}
+		}
+		description += fmt.Sprintf(" (true to give the flag without a value, which means %q)", flag.NoOptDefVal)
+	}
 	schema["description"] = description
+
 	return schema
 }
+
+// defaultIsZero reports whether the default of a flag is its zero value, which cobra omits
+// from the help output as well.
+func defaultIsZero(flag *pflag.Flag) bool {
+	switch flag.DefValue {
+	case "", "false", "0", "0s", "[]", "<nil>":
+		return true
+	}
+
+	return false
+}
+
+// durationPattern matches the durations accepted by time.ParseDuration, e.g. "1h30m" or "0.5s".
+const durationPattern = `^[-+]?(0|((\d+(\.\d*)?|\.\d+)(ns|us|µs|μs|ms|s|m|h))+)$`
+
+// durationSchema returns the schema of a time.Duration flag value.
+func durationSchema() map[string]any {
+	return map[string]any{
+		"type":     "string",
+		"pattern":  durationPattern,
+		"examples": []any{"30s", "1m30s"},
+	}
+}
+
+// flagDefault converts the DefValue of a flag into a JSON value of the schema's type.
+// It reports false for empty defaults and defaults that cannot be represented.
+func flagDefault(flag *pflag.Flag, schema map[string]any) (any, bool) {
+	if flag.DefValue == "" {
+		return nil, false
+	}
+
+	if schema["type"] == "array" {
+		list := strings.Trim(flag.DefValue, "[]")
+		if list == "" {
+			return nil, false
+		}
+
+		items, _ := schema["items"].(map[string]any)
+		var values []any
+		for _, item := range strings.Split(list, ",") {
+			value, ok := parseDefault(item, items["type"])
+			if !ok {
+				return nil, false
+			}
+			values = append(values, value)
+		}
+		return values, true
+	}
+
+	return parseDefault(flag.DefValue, schema["type"])
+}
+
+// parseDefault parses a string default value as the given JSON schema type.
+func parseDefault(value string, schemaType any) (any, bool) {
+	switch schemaType {
+	case "boolean":
+		v, err := strconv.ParseBool(value)
+		return v, err == nil
+	case "integer":
+		v, err := strconv.ParseInt(value, 10, 64)
+		return v, err == nil
+	case "number":
+		v, err := strconv.ParseFloat(value, 64)
+		return v, err == nil
+	}
+
+	return value, true
+}
@@ -1,8 +1,12 @@
 package tools
 
 import (
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
@@ -52,6 +56,8 @@ func TestGenerator_CommandConversion(t *testing.T) {
 				for _, tool := range tools {
 					// Verify tool has proper structure
 					assert.NotNil(t, tool.Tool.InputSchema)
+					assert.Contains(t, tool.Tool.InputSchema.Properties, StdinParam)
+					assert.NotContains(t, tool.Tool.InputSchema.Required, StdinParam)
 					// Just verify the tool was created properly
 					// The schema structure is handled by mcp-go library
 				}
@@ -139,6 +145,12 @@ func TestGenerator_CommandConversion(t *testing.T) {
 
 				// Verify the tool was created
 				assert.Equal(t, "cli_run", tools[0].Tool.Name)
+
+				// The controller keeps the flag definitions for execution
+				require.NotNil(t, tools[0].flags)
+				assert.NotNil(t, tools[0].flags.Lookup("input"))
+				assert.NotNil(t, tools[0].flags.Lookup("config"))
+				assert.NotNil(t, tools[0].flags.Lookup("verbose"))
 				// The actual flag verification would require inspecting the schema
 				// which is internal to the mcp.Tool structure
 			},
@@ -171,6 +183,69 @@ func TestFlagTypeMapping(t *testing.T) {
 				assert.Equal(t, "desc", result["description"])
 			},
 		},
+		{
+			flagType: "shorthand",
+			setup:    func(cmd *cobra.Command) { cmd.Flags().BoolP("test", "t", false, "desc") },
+			validateSchema: func(t *testing.T, result map[string]any) {
+				assert.Equal(t, "boolean", result["type"])
+				assert.Equal(t, "desc (shorthand: -t)", result["description"])
+			},
+		},
+		{
+			flagType: "default",
+			setup:    func(cmd *cobra.Command) { cmd.Flags().DurationP("test", "t", 30*time.Second, "desc") },
+			validateSchema: func(t *testing.T, result map[string]any) {
+				assert.Equal(t, "desc (default 30s) (shorthand: -t) (duration, e.g. 30s or 1m30s)", result["description"])
+				assert.Equal(t, "string", result["type"])
+				assert.Equal(t, "30s", result["default"])
+
+				pattern := regexp.MustCompile(result["pattern"].(string))
+				for _, valid := range []string{"30s", "1m30s", "-1.5h", "0", "250ms", ".5s"} {
+					_, err := time.ParseDuration(valid)
+					require.NoError(t, err)
+					assert.True(t, pattern.MatchString(valid), valid)
+				}
+				for _, invalid := range []string{"30", "1d", "s", ""} {
+					assert.False(t, pattern.MatchString(invalid), invalid)
+				}
+			},
+		},
+		{
+			flagType: "custom",
+			setup:    func(cmd *cobra.Command) { cmd.Flags().Var(&customValue{}, "test", "desc") },
+			validateSchema: func(t *testing.T, result map[string]any) {
+				assert.Equal(t, "string", result["type"])
+				assert.Equal(t, "desc (type: level)", result["description"])
+			},
+		},
+		{
+			flagType: "count",
+			setup:    func(cmd *cobra.Command) { cmd.Flags().Count("test", "desc") },
+			validateSchema: func(t *testing.T, result map[string]any) {
+				assert.Equal(t, "integer", result["type"])
+				assert.Equal(t, 0, result["minimum"])
+				assert.Equal(t, "desc (number of times the flag is given)", result["description"])
+			},
+		},
+		{
+			flagType: "optional value",
+			setup: func(cmd *cobra.Command) {
+				cmd.Flags().String("test", "info", "desc")
+				cmd.Flags().Lookup("test").NoOptDefVal = "debug"
+			},
+			validateSchema: func(t *testing.T, result map[string]any) {
+				assert.Equal(t, []any{"string", "boolean"}, result["type"])
+				assert.Equal(t, "info", result["default"])
+				assert.Equal(t, `desc (default "info") (true to give the flag without a value, which means "debug")`, result["description"])
+			},
+		},
+		{
+			flagType: "string default",
+			setup:    func(cmd *cobra.Command) { cmd.Flags().String("test", "json", "desc") },
+			validateSchema: func(t *testing.T, result map[string]any) {
+				assert.Equal(t, `desc (default "json")`, result["description"])
+			},
+		},
 		{
 			flagType: "bool",
 			setup:    func(cmd *cobra.Command) { cmd.Flags().Bool("test", false, "desc") },
@@ -198,6 +273,26 @@ func TestFlagTypeMapping(t *testing.T) {
 				assert.Equal(t, "string", items["type"])
 			},
 		},
+		{
+			flagType: "stringArray",
+			setup:    func(cmd *cobra.Command) { cmd.Flags().StringArray("test", nil, "desc") },
+			validateSchema: func(t *testing.T, result map[string]any) {
+				assert.Equal(t, "array", result["type"])
+				items, ok := result["items"].(map[string]any)
+				require.True(t, ok, "items should be a map")
+				assert.Equal(t, "string", items["type"])
+			},
+		},
+		{
+			flagType: "float64Slice",
+			setup:    func(cmd *cobra.Command) { cmd.Flags().Float64Slice("test", nil, "desc") },
+			validateSchema: func(t *testing.T, result map[string]any) {
+				assert.Equal(t, "array", result["type"])
+				items, ok := result["items"].(map[string]any)
+				require.True(t, ok, "items should be a map")
+				assert.Equal(t, "number", items["type"])
+			},
+		},
 		{
 			flagType: "intSlice",
 			setup:    func(cmd *cobra.Command) { cmd.Flags().IntSlice("test", nil, "desc") },
@@ -222,12 +317,51 @@ func TestFlagTypeMapping(t *testing.T) {
 			})
 			require.NotNil(t, flag)
 
-			result := flagToolOption(flag)
+			result := flagToolOption(discardLogger, flag)
 			tt.validateSchema(t, result)
 		})
 	}
 }
 
+// customValue is a pflag.Value that is not known to the schema generator
+type customValue struct{ value string }
+
+func (v *customValue) String() string     { return v.value }
+func (v *customValue) Set(s string) error { v.value = s; return nil }
+func (v *customValue) Type() string       { return "level" }
+
+// TestFlagDefaults tests that flag default values are typed according to the schema
+func TestFlagDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(flags *pflag.FlagSet)
+		expected any
+	}{
+		{"string", func(f *pflag.FlagSet) { f.String("test", "json", "desc") }, "json"},
+		{"empty string", func(f *pflag.FlagSet) { f.String("test", "", "desc") }, nil},
+		{"bool", func(f *pflag.FlagSet) { f.Bool("test", true, "desc") }, true},
+		{"int", func(f *pflag.FlagSet) { f.Int("test", 30, "desc") }, int64(30)},
+		{"float", func(f *pflag.FlagSet) { f.Float64("test", 0.5, "desc") }, 0.5},
+		{"string slice", func(f *pflag.FlagSet) { f.StringSlice("test", []string{"a", "b"}, "desc") }, []any{"a", "b"}},
+		{"int slice", func(f *pflag.FlagSet) { f.IntSlice("test", []int{1, 2}, "desc") }, []any{int64(1), int64(2)}},
+		{"empty slice", func(f *pflag.FlagSet) { f.StringSlice("test", nil, "desc") }, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			tt.setup(flags)
+
+			result := flagToolOption(discardLogger, flags.Lookup("test"))
+			if tt.expected == nil {
+				assert.NotContains(t, result, "default")
+			} else {
+				assert.Equal(t, tt.expected, result["default"])
+			}
+		})
+	}
+}
+
 // TestDefaultFilters tests that default filters work as expected
 func TestDefaultFilters(t *testing.T) {
 	root := &cobra.Command{Use: "cli", Short: "CLI"}
@@ -250,6 +384,91 @@ func TestDefaultFilters(t *testing.T) {
 	assert.Equal(t, "cli_normal", tools[0].Tool.Name)
 }
 
+// TestIncludeHidden tests that hidden commands and flags are excluded unless requested
+func TestIncludeHidden(t *testing.T) {
+	root := &cobra.Command{Use: "cli", Short: "CLI"}
+	root.PersistentFlags().String("trace", "", "Internal tracing")
+	require.NoError(t, root.PersistentFlags().MarkHidden("trace"))
+
+	debug := &cobra.Command{Use: "debug", Short: "Debug internals", Hidden: true, Run: func(_ *cobra.Command, _ []string) {}}
+	get := &cobra.Command{Use: "get", Short: "Get", Run: func(_ *cobra.Command, _ []string) {}}
+	get.Flags().String("output", "", "Output format")
+	get.Flags().Bool("internal", false, "Internal flag")
+	require.NoError(t, get.Flags().MarkHidden("internal"))
+	completion := &cobra.Command{Use: "completion", Short: "Completion", Run: func(_ *cobra.Command, _ []string) {}}
+	root.AddCommand(debug, get, completion)
+
+	flagNames := func(ctrl Controller) []string {
+		var names []string
+		ctrl.flags.VisitAll(func(flag *pflag.Flag) { names = append(names, flag.Name) })
+		return names
+	}
+
+	t.Run("excluded by default", func(t *testing.T) {
+		tools := NewGenerator().FromRootCmd(root)
+		require.Len(t, tools, 1)
+		assert.Equal(t, "cli_get", tools[0].Tool.Name)
+		assert.Equal(t, []string{"output"}, flagNames(tools[0]))
+
+		flagsProp := tools[0].Tool.InputSchema.Properties[FlagsParam].(map[string]any)
+		assert.NotContains(t, flagsProp["properties"], "internal")
+	})
+
+	t.Run("included on request", func(t *testing.T) {
+		tools := NewGenerator(IncludeHidden()).FromRootCmd(root)
+		names := map[string]Controller{}
+		for _, tool := range tools {
+			names[tool.Tool.Name] = tool
+		}
+		require.Len(t, names, 2, "completion is still excluded")
+		require.Contains(t, names, "cli_debug")
+		require.Contains(t, names, "cli_get")
+		assert.ElementsMatch(t, []string{"internal", "output", "trace"}, flagNames(names["cli_get"]))
+	})
+
+	t.Run("keeps filters added before", func(t *testing.T) {
+		for name, opts := range map[string][]GeneratorOption{
+			"filter first": {AddFilter(Exclude([]string{"get"})), IncludeHidden()},
+			"filter last":  {IncludeHidden(), AddFilter(Exclude([]string{"get"}))},
+		} {
+			tools := NewGenerator(opts...).FromRootCmd(root)
+			require.Len(t, tools, 1, name)
+			assert.Equal(t, "cli_debug", tools[0].Tool.Name, name)
+		}
+	})
+}
+
+// TestInheritedFlags tests that persistent flags of every ancestor are tool inputs of their
+// subcommands, and are passed to them
+func TestInheritedFlags(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.PersistentFlags().Bool("verbose", false, "Verbose output")
+	root.PersistentFlags().String("output", "text", "Output format")
+	cluster := &cobra.Command{Use: "cluster"}
+	cluster.PersistentFlags().String("namespace", "default", "Namespace")
+	list := &cobra.Command{Use: "list", Run: func(_ *cobra.Command, _ []string) {}}
+	list.Flags().Int("output", 10, "Rows to output") // shadows the persistent flag of root
+	cluster.AddCommand(list)
+	root.AddCommand(cluster)
+
+	tools := NewGenerator().FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	properties := tools[0].Tool.InputSchema.Properties[FlagsParam].(map[string]any)["properties"].(map[string]any)
+	assert.Contains(t, properties, "verbose")
+	assert.Contains(t, properties, "namespace")
+	assert.Equal(t, "integer", properties["output"].(map[string]any)["type"], "local flags take precedence")
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		FlagsParam: map[string]any{"verbose": true, "namespace": "prod", "output": 5},
+	}
+	args, err := tools[0].BuildArgs(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cluster", "list"}, args[:2])
+	assert.ElementsMatch(t, []string{"--namespace=prod", "--output=5", "--verbose"}, args[2:])
+}
+
 // TestCommandDescriptions tests that command descriptions are properly extracted
 func TestCommandDescriptions(t *testing.T) {
 	tests := []struct {
@@ -276,7 +495,19 @@ func TestCommandDescriptions(t *testing.T) {
 			short:    "Only short description",
 			long:     "",
 			example:  "and example",
-			expected: "Only short description\nExamples:\nand example",
+			expected: "Only short description\n\nExamples:\n```\nand example\n```",
+		},
+		{
+			name:     "trims surrounding whitespace",
+			long:     "\n  Long description\n\n",
+			example:  "\n  cli test --all\n  cli test one\n\n",
+			expected: "Long description\n\nExamples:\n```\n  cli test --all\n  cli test one\n```",
+		},
+		{
+			name:     "ignores blank example",
+			short:    "Short",
+			example:  "\n   \n",
+			expected: "Short",
 		},
 	}
 
@@ -299,6 +530,21 @@ func TestCommandDescriptions(t *testing.T) {
 	}
 }
 
+// TestTruncateDescription tests that enormous descriptions are cut at a word boundary
+func TestTruncateDescription(t *testing.T) {
+	assert.Equal(t, "short", truncateDescription("short", 10))
+	assert.Equal(t, "exactly10!", truncateDescription("exactly10!", 10))
+	assert.Equal(t, "hello... (truncated)", truncateDescription("hello world again", 9))
+	assert.Equal(t, "abcdefghij... (truncated)", truncateDescription("abcdefghijklmnop", 10))
+	assert.Equal(t, "héllo... (truncated)", truncateDescription("héllo wörld", 8))
+
+	long := strings.Repeat("word ", maxDescriptionLength)
+	cmd := &cobra.Command{Use: "test", Long: long, Example: "test --flag"}
+	desc := descFromCmd(cmd)
+	assert.Less(t, len(desc), maxDescriptionLength+100)
+	assert.Contains(t, desc, "... (truncated)\n\nExamples:\n```\ntest --flag\n```")
+}
+
 // TestArgsDescFromCmd tests that the argsDescFromCmd function strips
 // the first word from the Use field to avoid redundancy with the command name
 func TestArgsDescFromCmd(t *testing.T) {
@@ -333,7 +579,7 @@ func TestArgsDescFromCmd(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Note: Cobra's Name() method returns the first word of Use
 			cmd := &cobra.Command{Use: tt.use}
-			result := argsDescFromCmd(cmd)
+			result := argsDescFromCmd(cmd, nil)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -1,10 +1,17 @@
 package tools
 
 import (
+	"fmt"
 	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // FromRootCmd creates a default generator and converts a Cobra command tree into MCP tools.
@@ -14,19 +21,142 @@ func FromRootCmd(cmd *cobra.Command) []Controller {
 
 // Generator converts Cobra commands into MCP tools with configurable exclusions.
 type Generator struct {
-	filters []Filter
-	handler Handler
+	filters  []Filter
+	handler  Handler
+	logger   *slog.Logger
+	nameFunc NameFunc
+	timeout  time.Duration
+	grace    time.Duration
+	signal   os.Signal
+	executor Executor
+	// command runs the tools instead of the current binary, nil for the current binary
+	command []string
+	// wrapper transforms the launch of the commands, nil to launch them directly
+	wrapper CommandWrapper
+	// maxOutput limits the bytes of each output stream, 0 for no limit
+	maxOutput int
+	// streaming selects the tools whose output is streamed, nil for none
+	streaming Filter
+	// environment of executed commands, empty unless configured
+	env          envPolicy
+	envOverrides []envOverride
+	// requestEnv passes request metadata to the commands, keyed by variable name
+	requestEnv map[string]RequestValue
+	// allowedPaths limits the exposed commands to these command paths, nil for all
+	allowedPaths []string
+	// includeHidden exposes hidden flags in the input schema
+	includeHidden bool
+	// roots of the working directories clients may choose, nil to disable CwdParam
+	roots []string
+	// collisions decides how duplicate tool names are resolved
+	collisions CollisionPolicy
+	// aliases exposes command aliases as additional tools
+	aliases bool
+	// keepANSI disables stripping escape sequences from the output
+	keepANSI bool
+	// structured selects the tools whose JSON output is returned as structured content, nil for none
+	structured Filter
+	// concurrency limit shared by every generated tool
+	maxConcurrent int
+	maxQueue      int
+	limiter       *limiter
+	// worker pool shared by every generated tool, replacing the limiter
+	workers     int
+	workerQueue int
+	pool        *workerPool
+	// sensitive selects the flags whose values are redacted in logs
+	sensitive sensitiveFlags
+	// audit receives a record of every execution, nil for none
+	audit AuditFunc
+	// metrics receives every execution, nil for none
+	metrics Metrics
+	// tracer starts a span for every execution, nil for none
+	tracer trace.Tracer
+	// authorize approves commands before they run, nil to allow all
+	authorize AuthorizeFunc
+	// dryRun adds DryRunParam to every tool
+	dryRun bool
+	// annotations set MCP tool annotations of the selected tools
+	annotations []annotationOverride
+	// cacheRules select the tools whose output is cached, stored in cacheBackend
+	cacheRules   []cacheRule
+	cacheBackend CacheBackend
+	// coalescing selects the tools whose identical concurrent calls share flights, nil for none
+	coalescing Filter
+	flights    *flightGroup
+	// outputs stores large outputs of every tool as resources, nil to inline them
+	outputs *OutputResources
+	// compressThreshold is the stdout length above which output is gzipped, 0 to never compress
+	compressThreshold int
+	// errorStderr is how many bytes of stderr the errors of failed commands include
+	errorStderr int
+	// matchFlagNames maps flag names in camelCase or snake_case to the flags of the command
+	matchFlagNames bool
+	// lazySchemas defers building the input schemas of the tools until they are needed,
+	// under schemaMu if it is set
+	lazySchemas bool
+	schemaMu    *sync.Mutex
+	// strictArgs rejects argument strings that are not valid shell quoting
+	strictArgs bool
+	// completionEnums turns the values completed for flags into enums of their schemas
+	completionEnums bool
+	// defaultResolver resolves the defaults of flags shown in the schemas, nil for their DefValue
+	defaultResolver DefaultResolver
+	// skipArgsValidation stops calling the Args validators of the commands before running them
+	skipArgsValidation bool
+	// paths confines the values of path flags, without roots it defaults to the working directory roots
+	paths pathPolicy
+	// inProcess runs the tools with inProcessExecutor, created for the tree of each root by GenerateFrom
+	inProcess         bool
+	inProcessExecutor *InProcessExecutor
+	// flagInjections set flags on every call of the selected tools
+	flagInjections []flagInjection
+	// passthrough selects the tools that accept PassthroughArgsParam, nil for none
+	passthrough Filter
+	// ttyCommands select commands that require a terminal, which are not generated
+	ttyCommands []Filter
+	// commandHandlers replace the handler of single commands, by full command path
+	commandHandlers map[string]ResultHandler
+	// formatters turn the output of the selected tools into content, nil for PlainText
+	formatters []formatterOverride
+	// resourceUsage reports the resource usage of every execution in the result metadata
+	resourceUsage bool
 }
 
 // GeneratorOption is a function type for configuring Generator instances.
 type GeneratorOption func(*Generator)
 
+// NameFunc builds the name of a tool from the path of its command, starting with the
+// name of the root command (e.g. ["kubectl", "get", "pods"]).
+type NameFunc func(path []string) string
+
+// DefaultNameFunc joins the command path with underscores, e.g. "kubectl_get_pods".
+func DefaultNameFunc(path []string) string {
+	return strings.Join(path, "_")
+}
+
+// WithNameFunc returns a GeneratorOption that sets how tool names are built from command paths.
+// Tools are always executed through the stored command path, so any naming scheme is safe
+// to use, as long as it produces unique names. A nil nameFunc restores DefaultNameFunc.
+//
+//	Example: NewGenerator(WithNameFunc(func(path []string) string { return strings.Join(path[1:], ".") }))
+func WithNameFunc(nameFunc NameFunc) GeneratorOption {
+	return func(g *Generator) {
+		if nameFunc == nil {
+			nameFunc = DefaultNameFunc
+		}
+		g.nameFunc = nameFunc
+	}
+}
+
 // NewGenerator creates a new Generator with the specified options.
 //
 // By default, the Generator:
 //   - Excludes hidden commands
 //   - Excludes "mcp", "help", and "completion" commands
-//   - Uses DefaultHandler() which returns command output as plain text
+//   - Formats command output as plain text with PlainText
+//   - Runs commands with an empty environment
+//   - Strips ANSI escape sequences from command output
 //
 // Available options:
 //
@@ -39,73 +169,454 @@ type GeneratorOption func(*Generator)
 //	WithHandler(handler Handler) - Set a custom handler for processing command output
 //	  Example: NewGenerator(WithHandler(myCustomHandler))
 //
+//	WithCommandHandler(path string, handler ResultHandler) - Process the output of one command differently
+//	  Example: NewGenerator(WithCommandHandler("mytool metrics", metricsTable))
+//
+//	WithFormatter(selector Filter, formatter Formatter) - Format the output of the selected tools, e.g. as Markdown
+//	  Example: NewGenerator(WithFormatter(nil, ErrorAware{}))
+//
+//	WithCodeFence(selector Filter, language string) - Wrap the text output of the selected tools in a code block
+//	  Example: NewGenerator(WithCodeFence(nil, ""))
+//
+//	WithNameFunc(nameFunc NameFunc) - Set how tool names are built from command paths
+//	  Example: NewGenerator(WithNameFunc(myNameFunc))
+//
+//	WithCollisionPolicy(policy CollisionPolicy) - Set how duplicate tool names are resolved
+//	  Example: NewGenerator(WithCollisionPolicy(CollisionSuffix))
+//
+//	WithLogger(logger *slog.Logger) - Log generation and execution, which is silent by default
+//	  Example: NewGenerator(WithLogger(slog.Default()))
+//
+//	WithSensitiveFlags(names ...string) - Redact the values of these flags in logs
+//	  Example: NewGenerator(WithSensitiveFlags("dsn"))
+//
+//	WithSensitiveFlagPattern(pattern *regexp.Regexp) - Replace the pattern of flag names redacted in logs
+//	  Example: NewGenerator(WithSensitiveFlagPattern(regexp.MustCompile(`(?i)credential`)))
+//
+//	WithAudit(audit AuditFunc) - Report every execution, e.g. as JSON lines with JSONAudit
+//	  Example: NewGenerator(WithAudit(JSONAudit(auditFile)))
+//
+//	WithMetrics(metrics Metrics) - Count executions and record their duration, e.g. with Prometheus
+//	  Example: NewGenerator(WithMetrics(myPrometheusMetrics))
+//
+//	WithTracer(tracer trace.Tracer) - Start an OpenTelemetry span for every execution
+//	  Example: NewGenerator(WithTracer(otel.Tracer("mycli")))
+//
+//	WithAuthorize(authorize AuthorizeFunc) - Approve or deny each command before it runs
+//	  Example: NewGenerator(WithAuthorize(myPolicy))
+//
+//	WithDryRun() - Let clients preview the command line instead of running it
+//	  Example: NewGenerator(WithDryRun())
+//
+//	WithToolAnnotations(selector Filter, annotation mcp.ToolAnnotation) - Set read-only and destructive hints
+//	  Example: NewGenerator(WithToolAnnotations(Allow([]string{"get"}), mcp.ToolAnnotation{ReadOnlyHint: mcp.ToBoolPtr(true)}))
+//
+//	WithCache(selector Filter, ttl time.Duration) - Cache the output of the selected idempotent tools
+//	  Example: NewGenerator(WithCache(Allow([]string{"get"}), time.Minute))
+//
+//	WithCacheBackend(backend CacheBackend) - Store cached output elsewhere than in memory
+//	  Example: NewGenerator(WithCacheBackend(myRedisCache))
+//
+//	WithCoalescing(selector Filter) - Run identical concurrent calls of the selected idempotent tools once
+//	  Example: NewGenerator(WithCoalescing(Allow([]string{"get"})))
+//
+//	WithFlagNameMatching() - Accept flag names in camelCase or snake_case, and reject unknown flags
+//	  Example: NewGenerator(WithFlagNameMatching())
+//
+//	WithTimeout(timeout time.Duration) - Limit how long each command may run
+//	  Example: NewGenerator(WithTimeout(30 * time.Second))
+//
+//	WithMaxOutputBytes(limit int) - Truncate stdout and stderr of each command to limit bytes
+//	  Example: NewGenerator(WithMaxOutputBytes(1 << 20))
+//
+//	WithGracePeriod(grace time.Duration) - Set how long cancelled commands have to exit
+//	  Example: NewGenerator(WithGracePeriod(2 * time.Second))
+//
+//	WithTerminationSignal(sig os.Signal) - Set the signal asking cancelled commands to exit
+//	  Example: NewGenerator(WithTerminationSignal(os.Interrupt))
+//
+//	WithMaxConcurrent(limit int) - Limit how many commands run at the same time
+//	  Example: NewGenerator(WithMaxConcurrent(4), WithMaxQueue(16))
+//
+//	WithWorkerPool(workers, queueDepth int) - Run commands on a fixed number of workers, rejecting calls when the queue is full
+//	  Example: NewGenerator(WithWorkerPool(4, 16))
+//
+//	WithExecutor(executor Executor) - Replace how commands are run
+//	  Example: NewGenerator(WithExecutor(myExecutor))
+//
+//	WithInProcessExecution() - Run commands in the server process instead of a subprocess
+//	  Example: NewGenerator(WithInProcessExecution())
+//
+//	WithEnvPassthrough(names ...string) - Pass server environment variables to commands
+//	  Example: NewGenerator(WithEnvPassthrough("PATH", "HOME"))
+//
+//	WithEnv(selector Filter, env map[string]string) - Set environment variables of commands
+//	  Example: NewGenerator(WithEnv(nil, map[string]string{"NO_COLOR": "1"}))
+//
+//	WithInheritedEnv() - Pass the whole server environment to commands (not recommended)
+//	  Example: NewGenerator(WithInheritedEnv())
+//
+//	WithWorkingDirRoots(roots ...string) - Let clients choose a working directory below roots
+//	  Example: NewGenerator(WithWorkingDirRoots("/src/monorepo"))
+//
+//	WithPathRoots(roots ...string) - Confine the values of path flags to roots
+//	  Example: NewGenerator(WithPathRoots("/src/monorepo"), WithPathFlags("kubeconfig"), RequireExistingPaths())
+//
+//	WithAllowedPaths(paths ...string) - Only expose commands at or below these command paths
+//	  Example: NewGenerator(WithAllowedPaths("kubectl get", "kubectl describe"))
+//
+//	WithAliases() - Also expose command aliases as tools
+//	  Example: NewGenerator(WithAliases())
+//
+//	IncludeHidden() - Expose hidden commands and flags
+//	  Example: NewGenerator(IncludeHidden())
+//
+//	WithStructuredOutput(selector Filter) - Return JSON object output of the selected tools as structured content
+//	  Example: NewGenerator(WithStructuredOutput(Allow([]string{"get"})))
+//
+//	PreserveANSI() - Keep ANSI escape sequences (colors) in command output
+//	  Example: NewGenerator(PreserveANSI())
+//
+//	WithStreaming(selector Filter) - Stream the output of the selected tools while they run
+//	  Example: NewGenerator(WithStreaming(Allow([]string{"build"})))
+//
 // Common filter functions:
 //
 //	Hidden() - Excludes hidden commands (applied by default)
 //	Exclude([]string) - Excludes commands by name
 //	Allow([]string) - Only includes commands whose path contains these names
+//	ExcludePaths(paths ...string) - Excludes commands by full command path
+//	Not, AllOf, AnyOf - Combine filters
 func NewGenerator(opts ...GeneratorOption) *Generator {
 	g := &Generator{
-		// default filters
-		filters: []Filter{
-			Hidden(),
-			Exclude([]string{MCPCommandName, "help", "completion"}),
-		},
+		logger:      discardLogger,
+		sensitive:   sensitiveFlags{pattern: DefaultSensitiveFlagPattern},
+		grace:       DefaultGracePeriod,
+		errorStderr: DefaultErrorStderrBytes,
+		nameFunc:    DefaultNameFunc,
+	}
+	// default filters
+	g.filters = []Filter{
+		g.defaultHidden(),
+		defaultExclude(),
 	}
 
 	for _, opt := range opts {
 		opt(g)
 	}
 
+	if g.pool = newWorkerPool(g.workers, g.workerQueue); g.pool == nil {
+		g.limiter = newLimiter(g.maxConcurrent, g.maxQueue)
+	}
+	if g.cacheBackend == nil && len(g.cacheRules) > 0 {
+		g.cacheBackend = NewMemoryCache()
+	}
+	if g.coalescing != nil {
+		g.flights = newFlightGroup()
+	}
 	return g
 }
 
+// discardLogger is used when no logger was configured.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// WithLogger returns a GeneratorOption that sets the logger used while generating tools and by
+// the generated tools when they are executed. By default nothing is logged, and a nil logger
+// restores that default.
+//
+//	Example: NewGenerator(WithLogger(slog.Default()))
+func WithLogger(logger *slog.Logger) GeneratorOption {
+	return func(g *Generator) {
+		if logger == nil {
+			logger = discardLogger
+		}
+		g.logger = logger
+	}
+}
+
+// WithTimeout returns a GeneratorOption that sets the execution timeout of every generated tool.
+// A command that runs longer than the timeout is killed and its partial output is returned.
+// A zero timeout means no limit.
+func WithTimeout(timeout time.Duration) GeneratorOption {
+	return func(g *Generator) {
+		g.timeout = timeout
+	}
+}
+
+// WithMaxOutputBytes returns a GeneratorOption that limits the size of stdout and stderr,
+// each, returned by every generated tool. Longer output is truncated with a marker.
+// A zero limit means no limit.
+func WithMaxOutputBytes(limit int) GeneratorOption {
+	return func(g *Generator) {
+		g.maxOutput = limit
+	}
+}
+
+// WithGracePeriod returns a GeneratorOption that sets how long a cancelled or timed-out command
+// is given to exit before its whole process group is killed. Defaults to DefaultGracePeriod.
+// A zero grace period kills the process group immediately.
+// It configures the DefaultExecutor, and has no effect if WithExecutor is used.
+func WithGracePeriod(grace time.Duration) GeneratorOption {
+	return func(g *Generator) {
+		g.grace = grace
+	}
+}
+
+// WithTerminationSignal returns a GeneratorOption that sets the signal sent to the process group
+// of a cancelled or timed-out command, e.g. os.Interrupt for commands that only clean up on
+// SIGINT. Processes still running after the grace period of WithGracePeriod are killed.
+// Defaults to SIGTERM, and is ignored on Windows and by custom executors.
+//
+//	Example: NewGenerator(WithTerminationSignal(os.Interrupt), WithGracePeriod(10 * time.Second))
+func WithTerminationSignal(sig os.Signal) GeneratorOption {
+	return func(g *Generator) {
+		g.signal = sig
+	}
+}
+
+// WithStrictArgParsing returns a GeneratorOption that rejects a PositionalArgsParam string
+// that cannot be split with shell quoting rules, e.g. because of an unterminated quote, with an
+// error telling the client to fix it. By default such a string is split on whitespace instead,
+// which may not be what the client meant. Array arguments are never parsed.
+func WithStrictArgParsing() GeneratorOption {
+	return func(g *Generator) {
+		g.strictArgs = true
+	}
+}
+
+// WithCommand returns a GeneratorOption that sets the executable and leading arguments used to
+// run the tools, instead of re-executing the current binary, e.g. to iterate with "go run"
+// without rebuilding, or to launch the CLI through a wrapper. The arguments of a tool call are
+// appended to it. An empty command restores the default.
+// It configures the DefaultExecutor, and has no effect if WithExecutor is used.
+//
+//	Example: WithCommand("go", "run", "./cmd/mytool")
+func WithCommand(command ...string) GeneratorOption {
+	return func(g *Generator) {
+		g.command = slices.Clone(command)
+	}
+}
+
+// newExecutor returns the Executor for a generated tool.
+func (g *Generator) newExecutor() Executor {
+	if g.inProcessExecutor != nil {
+		return g.inProcessExecutor
+	}
+	if g.executor != nil {
+		return g.executor
+	}
+
+	return &DefaultExecutor{GracePeriod: g.grace, TerminationSignal: g.signal, Command: g.command, Wrapper: g.wrapper}
+}
+
 // FromRootCmd recursively converts a Cobra command tree into MCP tools.
+// Errors are logged rather than returned; use Generate to handle them.
 func (g *Generator) FromRootCmd(cmd *cobra.Command) []Controller {
-	slog.Debug("starting tool generation from root command", "root_cmd", cmd.Name())
-	tools := g.fromCmd(cmd, "", []Controller{})
-	slog.Info("tool generation completed", "total_tools", len(tools))
+	tools, err := g.Generate(cmd)
+	if err != nil {
+		g.logger.Error("tool generation failed", "root_cmd", cmd.Name(), "error", err)
+	}
+
 	return tools
 }
 
-func (g *Generator) fromCmd(cmd *cobra.Command, parentPath string, tools []Controller) []Controller {
+// Generate recursively converts a Cobra command tree into MCP tools.
+// The command does not need to be the root of the CLI: for a subcommand, only it and its
+// subcommands become tools, named relative to it. It returns an error if tool names collide
+// and the CollisionError policy is in effect.
+func (g *Generator) Generate(cmd *cobra.Command) ([]Controller, error) {
+	return g.GenerateFrom(cmd)
+}
+
+// GenerateFrom converts the command trees of several commands into one set of MCP tools, e.g.
+// the "db" and "cache" subtrees of a CLI. Each tool is named relative to the command it was
+// found under, and tool name collisions across the trees are resolved like within a tree.
+// Commands outside the tree of the executable running the server can only be run in process,
+// see WithInProcessExecution, so it returns an error for commands of different trees otherwise.
+//
+//	Example: generator.GenerateFrom(dbCmd, cacheCmd)
+func (g *Generator) GenerateFrom(roots ...*cobra.Command) ([]Controller, error) {
+	if !g.inProcess {
+		var tree *cobra.Command
+		for _, root := range roots {
+			if root == nil {
+				continue
+			}
+			if tree == nil {
+				tree = root.Root()
+			} else if root.Root() != tree {
+				return nil, fmt.Errorf("%q and %q belong to different command trees, which only WithInProcessExecution can run", tree.CommandPath(), root.CommandPath())
+			}
+		}
+	}
+
+	var tools []Controller
+	executors := map[*cobra.Command]*InProcessExecutor{}
+	for _, root := range roots {
+		if root == nil {
+			continue
+		}
+		g.logger.Debug("starting tool generation from root command", "root_cmd", root.CommandPath())
+		if g.inProcess {
+			// Commands always execute their whole tree, so roots in one tree share its executor
+			if executors[root.Root()] == nil {
+				executors[root.Root()] = &InProcessExecutor{Root: root.Root()}
+			}
+			g.inProcessExecutor = executors[root.Root()]
+		}
+		g.schemaMu = g.schemaMutex()
+		tools = g.fromCmd(root, nil, tools)
+	}
+
+	tools, err := g.resolveCollisions(aliasesLast(tools))
+	if err != nil {
+		return nil, err
+	}
+	if !g.lazySchemas {
+		buildSchemas(tools)
+	}
+
+	g.logger.Info("tool generation completed", "total_tools", len(tools))
+	return tools, nil
+}
+
+// invocationPath returns the names of the commands leading from the root of the tree of cmd
+// to cmd, which are the first arguments of the executable to run cmd.
+func invocationPath(cmd *cobra.Command) []string {
+	path := []string{}
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		path = append(path, cmd.Name())
+	}
+
+	slices.Reverse(path)
+	return path
+}
+
+// fromCmd converts a command and its subcommands into tools.
+// parentPath holds the names of the ancestors of cmd, starting with the command the walk started at.
+func (g *Generator) fromCmd(cmd *cobra.Command, parentPath []string, tools []Controller) []Controller {
 	if cmd == nil {
 		return tools
 	}
 
-	// Create the tool name
-	toolName := cmd.Name()
-	if parentPath != "" {
-		toolName = parentPath + "_" + toolName
-	}
+	// Create the tool name (e.g., ["root", "sub", "command"] -> "root_sub_command")
+	path := append(slices.Clone(parentPath), cmd.Name())
+	toolName := g.nameFunc(slices.Clone(path))
 
-	slog.Debug("processing command", "command", toolName, "has_run", cmd.Run != nil || cmd.RunE != nil)
+	g.logger.Debug("processing command", "command", toolName, "has_run", cmd.Run != nil || cmd.RunE != nil)
 
 	// Register subcommands
 outer:
 	for _, subCmd := range cmd.Commands() {
+		if !g.searchesPath(subCmd) {
+			g.logger.Debug("excluding command outside of allowed paths", "command", subCmd.CommandPath())
+			continue
+		}
+
 		for _, filter := range g.filters {
 			if !filter(subCmd) {
-				// logging should be handled by the filter itself
+				g.logger.Debug("excluding filtered command", "command", subCmd.CommandPath())
 				continue outer
 			}
 		}
 
-		tools = g.fromCmd(subCmd, toolName, tools)
+		tools = g.fromCmd(subCmd, path, tools)
 	}
 
 	// Skip if the command has no runnable function
 	if cmd.Run == nil && cmd.RunE == nil {
-		slog.Debug("skipping command without run function", "command", toolName)
+		g.logger.Debug("skipping command without run function", "command", toolName)
 		return tools
 	}
 
-	toolOptions := toolOptsFromCmd(cmd)
+	// Skip parents that were only searched for allowed subcommands
+	if !g.allowsPath(cmd) {
+		g.logger.Debug("skipping command outside of allowed paths", "command", toolName)
+		return tools
+	}
+
+	// Skip commands that would wait for input from a terminal the client cannot provide
+	if g.requiresTTY(cmd) {
+		g.logger.Debug("skipping command that requires a terminal", "command", toolName)
+		return tools
+	}
+
+	flags := flagsFromCmd(g.logger, cmd, g.includeHidden)
+	spec := argsSpecFromCmd(g.logger, cmd)
+	injected := g.injectedFlagsFor(cmd, flags)
+	passthrough := g.passesThrough(cmd)
+	schemaOptions := func() []mcp.ToolOption {
+		toolOptions := toolOptsFromCmd(g.logger, cmd, g.resolveDefaults(cmd, withoutFlags(flags, injected)), spec)
+		if g.completionEnums {
+			toolOptions = append(toolOptions, completionEnumOption(g.logger, cmd, flags))
+		}
+		if len(g.roots) > 0 {
+			toolOptions = append(toolOptions, cwdToolOption(g.roots))
+		}
+		if g.dryRun {
+			toolOptions = append(toolOptions, dryRunToolOption())
+		}
+		if passthrough {
+			toolOptions = append(toolOptions, passthroughToolOption())
+		}
+		return toolOptions
+	}
+
+	// The schema is built after the walk, in parallel or when first needed, see buildSchemas
+	mcpTool := mcp.NewTool(toolName, mcp.WithDescription(descFromCmd(cmd)), g.annotationToolOption(cmd))
+	lazy := newLazySchema(g.schemaMu, func() (mcp.ToolInputSchema, string) {
+		return mcp.NewTool(toolName, schemaOptions()...).InputSchema, cmd.UsageString()
+	})
 	tool := Controller{
-		Tool:    mcp.NewTool(toolName, toolOptions...),
-		handler: g.handler, // Use the configured handler
+		Tool:           mcpTool,
+		path:           invocationPath(cmd),
+		command:        cmd.CommandPath(),
+		flags:          flags,
+		injected:       injected,
+		args:           spec,
+		handler:        g.handler, // Use the configured handler
+		cmdHandler:     g.commandHandlers[cmd.CommandPath()],
+		formatter:      g.formatterFor(cmd),
+		Timeout:        g.timeout,
+		MaxOutputBytes: g.maxOutput,
+		executor:       g.newExecutor(),
+		limiter:        g.limiter,
+		pool:           g.pool,
+		logger:         g.logger,
+		sensitive:      &g.sensitive,
+		audit:          g.audit,
+		metrics:        g.metrics,
+		tracer:         g.tracer,
+		authorize:      g.authorize,
+		dryRun:         g.dryRun,
+		reportUsage:    g.resourceUsage,
+		passthrough:    passthrough,
+		cache:          g.cacheFor(cmd, mcpTool),
+		flights:        g.flightsFor(cmd, mcpTool),
+		outputs:        g.outputs,
+		compress:       g.compressThreshold,
+		errStderr:      g.errorStderr,
+		matchFlags:     g.matchFlagNames,
+		strictArgs:     g.strictArgs,
+		checkArgs:      g.argsValidator(cmd),
+		paths:          g.pathPolicy(),
+		stream:         g.streams(cmd),
+		keepANSI:       g.keepANSI,
+		structured:     g.structuredOutput(cmd),
+		lazy:           lazy,
+		short:          cmd.Short,
+		example:        cmd.Example,
+		Env:            g.envFor(cmd),
+		env:            g.env,
+		requestEnv:     g.requestEnv,
+		roots:          g.roots,
 	}
 
-	slog.Debug("created tool", "tool_name", toolName, "description", tool.Tool.Description)
-	return append(tools, tool)
+	g.logger.Debug("created tool", "tool_name", toolName, "description", tool.Tool.Description)
+	tools = append(tools, tool)
+	if g.aliases {
+		tools = append(tools, g.aliasTools(cmd, parentPath, tool)...)
+	}
+
+	return tools
 }
@@ -1,12 +1,18 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestGeneratorOptions tests various generator configuration options
@@ -61,14 +67,35 @@ func TestGeneratorOptions(t *testing.T) {
 		// Filters should be replaced by WithFilters (last option)
 		assert.Len(t, gen.filters, 1)
 	})
+
+	t.Run("generator with timeout", func(t *testing.T) {
+		gen := NewGenerator(WithTimeout(time.Minute))
+		tools := gen.FromRootCmd(&cobra.Command{Use: "test", Run: func(_ *cobra.Command, _ []string) {}})
+
+		assert.Len(t, tools, 1)
+		assert.Equal(t, time.Minute, tools[0].Timeout)
+	})
 }
 
 // TestFromRootCmdEdgeCases tests edge cases in command tree traversal
 func TestFromRootCmdEdgeCases(t *testing.T) {
 	t.Run("nil command", func(t *testing.T) {
 		gen := NewGenerator()
-		tools := gen.fromCmd(nil, "", []Controller{})
+		tools := gen.fromCmd(nil, nil, []Controller{})
+		assert.Empty(t, tools)
+
+		tools, err := gen.Generate(nil)
+		require.NoError(t, err)
 		assert.Empty(t, tools)
+		assert.Empty(t, gen.FromRootCmd(nil))
+
+		root := &cobra.Command{Use: "root", Run: func(*cobra.Command, []string) {}}
+		for _, g := range []*Generator{gen, NewGenerator(WithInProcessExecution())} {
+			tools, err = g.GenerateFrom(nil, root, nil)
+			require.NoError(t, err)
+			require.Len(t, tools, 1)
+			assert.Equal(t, "root", tools[0].Tool.Name)
+		}
 	})
 
 	t.Run("command with nil subcommands", func(t *testing.T) {
@@ -126,3 +153,147 @@ func TestFromRootCmdStandaloneFunction(t *testing.T) {
 	assert.Len(t, tools, 1)
 	assert.Equal(t, "test", tools[0].Tool.Name)
 }
+
+// TestWithNameFunc tests that a custom naming scheme is used while dispatch keeps the command path
+func TestWithNameFunc(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get"}
+	get.AddCommand(&cobra.Command{Use: "pods", Run: func(_ *cobra.Command, _ []string) {}})
+	root.AddCommand(get)
+
+	var paths [][]string
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(
+		WithExecutor(executor),
+		WithNameFunc(func(path []string) string {
+			paths = append(paths, path)
+			return strings.Join(path[1:], ".")
+		}),
+	).FromRootCmd(root)
+
+	assert.Contains(t, paths, []string{"cli", "get", "pods"})
+	require.Len(t, tools, 1)
+	assert.Equal(t, "get.pods", tools[0].Tool.Name)
+
+	_, err := tools[0].Execute(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"get", "pods"}, executor.invocations[0].Args)
+
+	tools = NewGenerator(WithNameFunc(nil)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "cli_get_pods", tools[0].Tool.Name)
+}
+
+// TestWithLogger tests that generation and execution log to the configured logger only
+func TestWithLogger(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(_ *cobra.Command, _ []string) {}})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(WithLogger(logger), WithExecutor(executor)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	assert.Contains(t, buf.String(), "tool generation completed")
+
+	buf.Reset()
+	_, err := tools[0].Execute(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "executing command")
+
+	// The zero Controller and a nil logger discard logs rather than using the global logger
+	assert.Equal(t, discardLogger, (&Controller{}).log())
+	assert.Equal(t, discardLogger, NewGenerator(WithLogger(nil)).logger)
+}
+
+// TestCommandPathDispatch tests that tools run the command they were generated from, in deep
+// trees and with underscores in command names
+func TestCommandPathDispatch(t *testing.T) {
+	// "cli cluster node_pool list" and "cli cluster node pool list" both map to "cli_cluster_node_pool_list"
+	root := &cobra.Command{Use: "cli"}
+	runs := func(cmd *cobra.Command, _ []string) { fmt.Fprint(cmd.OutOrStdout(), cmd.CommandPath()) }
+	cluster := &cobra.Command{Use: "cluster"}
+	nodePool := &cobra.Command{Use: "node_pool"}
+	nodePool.AddCommand(&cobra.Command{Use: "list", Run: runs})
+	node := &cobra.Command{Use: "node"}
+	pool := &cobra.Command{Use: "pool"}
+	pool.AddCommand(&cobra.Command{Use: "list", Run: runs})
+	node.AddCommand(pool)
+	cluster.AddCommand(nodePool, node)
+	root.AddCommand(cluster, &cobra.Command{Use: "get_all", Run: runs})
+
+	tools, err := NewGenerator(WithCollisionPolicy(CollisionSuffix), WithInProcessExecution()).Generate(root)
+	require.NoError(t, err)
+
+	expected := map[string][]string{
+		"cli_cluster_node_pool_list":   {"cluster", "node", "pool", "list"},
+		"cli_cluster_node_pool_list_2": {"cluster", "node_pool", "list"},
+		"cli_get_all":                  {"get_all"},
+	}
+	require.Len(t, tools, len(expected))
+
+	for _, tool := range tools {
+		path, ok := expected[tool.Tool.Name]
+		require.True(t, ok, "unexpected tool %s", tool.Tool.Name)
+
+		var request mcp.CallToolRequest
+		args, err := tool.BuildArgs(request)
+		require.NoError(t, err)
+		assert.Equal(t, path, args, tool.Tool.Name)
+
+		result, err := tool.Execute(context.Background(), request)
+		require.NoError(t, err)
+		assert.Equal(t, "cli "+strings.Join(path, " "), string(result.Stdout), tool.Tool.Name)
+	}
+}
+
+// TestGenerateFrom tests that subtrees are named relative to the command they were found under,
+// and still run from the root of the CLI
+func TestGenerateFrom(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	runs := func(cmd *cobra.Command, _ []string) { fmt.Fprint(cmd.OutOrStdout(), cmd.CommandPath()) }
+	db := &cobra.Command{Use: "db"}
+	db.AddCommand(&cobra.Command{Use: "migrate", Run: runs})
+	cache := &cobra.Command{Use: "cache", Run: runs}
+	cache.AddCommand(&cobra.Command{Use: "flush", Run: runs})
+	root.AddCommand(db, cache, &cobra.Command{Use: "version", Run: runs})
+
+	tools, err := NewGenerator(WithInProcessExecution()).GenerateFrom(db, cache)
+	require.NoError(t, err)
+
+	expected := map[string][]string{
+		"db_migrate":  {"db", "migrate"},
+		"cache_flush": {"cache", "flush"},
+		"cache":       {"cache"},
+	}
+	require.Len(t, tools, len(expected))
+
+	for _, tool := range tools {
+		path, ok := expected[tool.Tool.Name]
+		require.True(t, ok, "unexpected tool %s", tool.Tool.Name)
+		assert.Same(t, tools[0].executor, tool.executor, "tools of one tree share the in-process executor")
+
+		result, err := tool.Execute(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "cli "+strings.Join(path, " "), string(result.Stdout), tool.Tool.Name)
+	}
+
+	t.Run("collisions across trees", func(t *testing.T) {
+		other := &cobra.Command{Use: "db"}
+		other.AddCommand(&cobra.Command{Use: "migrate", Run: runs})
+
+		_, err := NewGenerator(WithInProcessExecution(), WithCollisionPolicy(CollisionError)).GenerateFrom(db, other)
+		assert.ErrorIs(t, err, ErrToolNameCollision)
+		assert.ErrorContains(t, err, `"cli db migrate" and "db migrate"`)
+	})
+
+	t.Run("trees require in-process execution", func(t *testing.T) {
+		other := &cobra.Command{Use: "other", Run: runs}
+
+		_, err := NewGenerator().GenerateFrom(db, cache)
+		require.NoError(t, err, "subtrees of one tree run through its executable")
+
+		_, err = NewGenerator().GenerateFrom(db, other)
+		assert.ErrorContains(t, err, `"cli" and "other" belong to different command trees`)
+	})
+}
@@ -2,43 +2,70 @@ package tools
 
 import (
 	"context"
-	"fmt"
-	"log/slog"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // Handler defines a function type for handling tool execution results.
-// It takes the context, request, output data, and any error that occurred during execution,
+// It takes the context, request, combined stdout and stderr output, and any error that occurred during execution,
 // and returns an MCP CallToolResult or an error. Errors should be returned only if there is
 // an issue with the handler itself, not with the tool execution.
 type Handler func(context.Context, mcp.CallToolRequest, []byte, error) (*mcp.CallToolResult, error)
 
 // WithHandler returns a GeneratorOption that sets a custom handler for processing command output.
-// By default, the generator uses DefaultHandler which returns output as plain text.
+// By default, the output is formatted by the Formatter of the tool, PlainText unless set with WithFormatter.
 func WithHandler(handler Handler) GeneratorOption {
 	return func(g *Generator) {
 		g.handler = handler
 	}
 }
 
-// defaultHandler is the default handler that processes command output as plain text.
-func defaultHandler(_ context.Context, request mcp.CallToolRequest, data []byte, err error) (*mcp.CallToolResult, error) {
-	output := string(data)
-	if err != nil {
-		slog.Error("command execution failed",
-			"tool", request.Method,
-			"error", err,
-			"output", output,
-		)
-
-		// Include output in error message if available
-		errMsg := fmt.Sprintf("command execution failed: %s", err.Error())
-		if output != "" {
-			errMsg += fmt.Sprintf("\nOutput: %s", output)
+// ResultHandler defines a function type for handling the result of a single command. Unlike a
+// Handler, it receives the ExecResult, with stdout and stderr apart and the exit code. It returns
+// an MCP CallToolResult, or an error only if there is an issue with the handler itself.
+type ResultHandler func(context.Context, mcp.CallToolRequest, *ExecResult, error) (*mcp.CallToolResult, error)
+
+// WithCommandHandler returns a GeneratorOption that processes the output of the command at the
+// full command path (e.g. "kubectl top pods") with handler, instead of the Handler of the
+// generator, e.g. to turn its JSON output into a table. It only applies to the tool of that
+// command, not to its subcommands. The result of a failed command is passed to it too, and
+// result is nil if the command was not run. Later options replace earlier ones for the same path.
+//
+//	Example: NewGenerator(WithCommandHandler("mytool metrics", metricsTable))
+func WithCommandHandler(path string, handler ResultHandler) GeneratorOption {
+	return func(g *Generator) {
+		if g.commandHandlers == nil {
+			g.commandHandlers = map[string]ResultHandler{}
 		}
-		return mcp.NewToolResultError(errMsg), nil
+		g.commandHandlers[normalizePaths([]string{path})[0]] = handler
 	}
+}
 
-	return mcp.NewToolResultText(output), nil
+// stdoutContent returns the content block for the stdout of a successful command.
+// Valid UTF-8 is returned as text. Anything else is binary data, which is base64 encoded
+// with a MIME type detected from its first bytes: images and audio become image and audio
+// content, and other data an embedded blob resource without a URI, which formatResult sets.
+func stdoutContent(stdout []byte) mcp.Content {
+	if utf8.Valid(stdout) {
+		return mcp.NewTextContent(string(stdout))
+	}
+
+	mimeType := http.DetectContentType(stdout)
+	data := base64.StdEncoding.EncodeToString(stdout)
+
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return mcp.NewImageContent(data, mimeType)
+	case strings.HasPrefix(mimeType, "audio/"):
+		return mcp.NewAudioContent(data, mimeType)
+	default:
+		return mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+			MIMEType: mimeType,
+			Blob:     data,
+		})
+	}
 }
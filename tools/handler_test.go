@@ -0,0 +1,307 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDefaultHandling tests that stdout and stderr are rendered into separate content
+func TestDefaultHandling(t *testing.T) {
+	t.Run("stdout only", func(t *testing.T) {
+		result, err := formatResult("", PlainText{}, &ExecResult{Stdout: []byte(`{"ok":true}`)}, nil)
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		assert.Equal(t, `{"ok":true}`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("stderr in secondary content on success", func(t *testing.T) {
+		execResult := &ExecResult{Stdout: []byte("data"), Stderr: []byte("warning")}
+		result, err := formatResult("", PlainText{}, execResult, nil)
+		require.NoError(t, err)
+		require.Len(t, result.Content, 2)
+		assert.Equal(t, "data", result.Content[0].(mcp.TextContent).Text)
+		assert.Equal(t, "Stderr: warning", result.Content[1].(mcp.TextContent).Text)
+	})
+
+	t.Run("stderr in error text on failure", func(t *testing.T) {
+		execResult := &ExecResult{Stdout: []byte("partial"), Stderr: []byte("boom"), ExitCode: 1}
+		result, err := formatResult("", PlainText{}, execResult, errors.New("exit status 1"))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "exit status 1")
+		assert.Contains(t, text, "Output: partial")
+		assert.Contains(t, text, "Stderr: boom")
+	})
+
+	t.Run("binary output", func(t *testing.T) {
+		png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+		gzip := []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff")
+
+		result, err := formatResult("cli_render", PlainText{}, &ExecResult{Stdout: png, Stderr: []byte("rendered")}, nil)
+		require.NoError(t, err)
+		require.Len(t, result.Content, 2)
+		image := result.Content[0].(mcp.ImageContent)
+		assert.Equal(t, "image/png", image.MIMEType)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(png), image.Data)
+		assert.Equal(t, "Stderr: rendered", result.Content[1].(mcp.TextContent).Text)
+
+		result, err = formatResult("cli_render", PlainText{}, &ExecResult{Stdout: gzip}, nil)
+		require.NoError(t, err)
+		blob := result.Content[0].(mcp.EmbeddedResource).Resource.(mcp.BlobResourceContents)
+		assert.Equal(t, "application/x-gzip", blob.MIMEType)
+		assert.Equal(t, "ophis://tools/cli_render/stdout", blob.URI)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(gzip), blob.Blob)
+	})
+
+	t.Run("utf-8 output stays text", func(t *testing.T) {
+		result, err := formatResult("", PlainText{}, &ExecResult{Stdout: []byte("héllo ✓")}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "héllo ✓", result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("nil result", func(t *testing.T) {
+		result, err := formatResult("", PlainText{}, nil, errors.New("failed to get executable path"))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
+
+// TestHandlerCombinedOutput tests that a Handler receives the output of results built by
+// custom executors, which have no interleaved output
+func TestHandlerCombinedOutput(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(_ *cobra.Command, _ []string) {}})
+
+	var received []byte
+	handler := func(_ context.Context, _ mcp.CallToolRequest, output []byte, _ error) (*mcp.CallToolResult, error) {
+		received = output
+		return mcp.NewToolResultText(string(output)), nil
+	}
+
+	executor := &recordingExecutor{result: &ExecResult{Stdout: []byte("pods\n"), Stderr: []byte("warning")}}
+	tools := NewGenerator(WithExecutor(executor), WithHandler(handler)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	result, err := tools[0].Execute(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	_, err = tools[0].Handle(context.Background(), mcp.CallToolRequest{}, result, err)
+	require.NoError(t, err)
+	assert.Equal(t, "pods\nwarning", string(received))
+}
+
+// TestCommandHandler tests that a command handler replaces the handler of its command only
+func TestCommandHandler(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	metrics := &cobra.Command{Use: "metrics", Run: func(_ *cobra.Command, _ []string) {}}
+	pods := &cobra.Command{Use: "pods", Run: func(_ *cobra.Command, _ []string) {}}
+	metrics.AddCommand(pods)
+	root.AddCommand(metrics, &cobra.Command{Use: "get", Run: func(_ *cobra.Command, _ []string) {}})
+
+	var received *ExecResult
+	table := func(_ context.Context, _ mcp.CallToolRequest, result *ExecResult, _ error) (*mcp.CallToolResult, error) {
+		received = result
+		return mcp.NewToolResultText("| cpu |\n| " + strings.TrimSpace(string(result.Stdout)) + " |"), nil
+	}
+
+	executor := &recordingExecutor{result: &ExecResult{Stdout: []byte("42\n"), Stderr: []byte("warning")}}
+	tools := NewGenerator(WithExecutor(executor), WithCommandHandler("cli  metrics", table)).FromRootCmd(root)
+	require.Len(t, tools, 3)
+
+	for _, tool := range tools {
+		received = nil
+		result, err := tool.Execute(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		toolResult, err := tool.Handle(context.Background(), mcp.CallToolRequest{}, result, err)
+		require.NoError(t, err)
+
+		if tool.Tool.Name == "cli_metrics" {
+			assert.Same(t, executor.result, received, "the handler receives the result apart from stdout and stderr")
+			require.Len(t, toolResult.Content, 1)
+			assert.Equal(t, "| cpu |\n| 42 |", toolResult.Content[0].(mcp.TextContent).Text)
+			assert.Equal(t, 0, toolResult.Meta.AdditionalFields[MetaExitCode])
+		} else {
+			assert.Nil(t, received, "tool %s", tool.Tool.Name)
+			require.Len(t, toolResult.Content, 2)
+			assert.Equal(t, "42\n", toolResult.Content[0].(mcp.TextContent).Text)
+		}
+	}
+}
+
+// TestOutputCapture tests that streams are captured separately and interleaved in order
+func TestOutputCapture(t *testing.T) {
+	capture := &outputCapture{}
+	stdout := capture.stdoutWriter()
+	stderr := capture.stderrWriter()
+
+	_, _ = stdout.Write([]byte("one "))
+	_, _ = stderr.Write([]byte("two "))
+	_, _ = stdout.Write([]byte("three"))
+
+	result := capture.result(nil)
+	assert.Equal(t, -1, result.ExitCode)
+	assert.Equal(t, "one three", string(result.Stdout))
+	assert.Equal(t, "two ", string(result.Stderr))
+	assert.Equal(t, "one two three", string(result.Combined()))
+
+	var nilResult *ExecResult
+	assert.Nil(t, nilResult.Combined())
+}
+
+// TestTruncateOutput tests that output is cut at the limit without splitting UTF-8 runes
+func TestTruncateOutput(t *testing.T) {
+	out, cut := truncateOutput([]byte("short"), 5)
+	assert.False(t, cut)
+	assert.Equal(t, "short", string(out))
+
+	out, cut = truncateOutput([]byte("0123456789"), 4)
+	assert.True(t, cut)
+	assert.Equal(t, "0123\n[output truncated, 6 bytes omitted]", string(out))
+
+	// "é" is two bytes, and would be split by a cut at 2
+	out, _ = truncateOutput([]byte("aéb"), 2)
+	assert.Equal(t, "a\n[output truncated, 3 bytes omitted]", string(out))
+
+	// binary data is cut at the exact limit, without a marker that would corrupt it
+	out, cut = truncateOutput([]byte{0xff, 0x80, 0x80, 0x80, 0x80, 0x80}, 5)
+	assert.True(t, cut)
+	assert.Equal(t, []byte{0xff, 0x80, 0x80, 0x80, 0x80}, out)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+	out, _ = truncateOutput(png, 50)
+	assert.Equal(t, png[:50], out)
+
+	// truncated binary stdout is returned intact, with the truncation in the metadata
+	result := &ExecResult{Stdout: png}
+	result.truncate(50)
+	toolResult, err := formatResult("tool", PlainText{}, result, nil)
+	require.NoError(t, err)
+	image, ok := toolResult.Content[0].(mcp.ImageContent)
+	require.True(t, ok)
+	decoded, err := base64.StdEncoding.DecodeString(image.Data)
+	require.NoError(t, err)
+	assert.Equal(t, png[:50], decoded)
+	assert.Equal(t, true, result.meta()[MetaTruncated])
+
+	// the input is not modified
+	data := []byte("0123456789")
+	_, _ = truncateOutput(data, 4)
+	assert.Equal(t, "0123456789", string(data))
+}
+
+// TestOutputCaptureProcessState tests that the exit code and signal termination are recorded
+func TestOutputCaptureProcessState(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	t.Run("non-zero exit", func(t *testing.T) {
+		cmd := exec.Command("sh", "-c", "exit 2")
+		_ = cmd.Run()
+
+		result := (&outputCapture{}).result(cmd.ProcessState)
+		assert.Equal(t, 2, result.ExitCode)
+		assert.False(t, result.Killed)
+	})
+
+	t.Run("killed by signal", func(t *testing.T) {
+		cmd := exec.Command("sh", "-c", "kill -9 $$")
+		_ = cmd.Run()
+
+		result := (&outputCapture{}).result(cmd.ProcessState)
+		assert.Equal(t, -1, result.ExitCode)
+		assert.True(t, result.Killed)
+	})
+}
+
+// TestHandleMeta tests that execution metadata is attached to the tool result
+func TestHandleMeta(t *testing.T) {
+	ctrl := &Controller{}
+
+	t.Run("non-zero exit", func(t *testing.T) {
+		execResult := &ExecResult{Stdout: []byte("out"), ExitCode: 2}
+		result, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, execResult, errors.New("exit status 2"))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "command exited with code 2")
+		assert.Equal(t, 2, result.Meta.AdditionalFields[MetaExitCode])
+		assert.NotContains(t, result.Meta.AdditionalFields, MetaKilled)
+	})
+
+	t.Run("killed", func(t *testing.T) {
+		execResult := &ExecResult{ExitCode: -1, Killed: true}
+		result, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, execResult, errors.New("signal: killed"))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "terminated by a signal")
+		assert.Equal(t, -1, result.Meta.AdditionalFields[MetaExitCode])
+		assert.Equal(t, true, result.Meta.AdditionalFields[MetaKilled])
+	})
+
+	t.Run("never started", func(t *testing.T) {
+		result, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, nil, errors.New("failed to get executable path"))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Nil(t, result.Meta)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		result, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{Stdout: []byte("ok")}, nil)
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Equal(t, 0, result.Meta.AdditionalFields[MetaExitCode])
+	})
+}
+
+// quietFormatter formats every output as no content.
+type quietFormatter struct{}
+
+func (quietFormatter) Format(*ExecResult, error) ([]mcp.Content, error) { return nil, nil }
+
+// TestHandleQuietFailure tests that a failed command without output is never returned as an
+// empty result, whatever formats it
+func TestHandleQuietFailure(t *testing.T) {
+	emptyText := func(context.Context, mcp.CallToolRequest, []byte, error) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(""), nil
+	}
+
+	tests := []struct {
+		name string
+		ctrl *Controller
+	}{
+		{"default formatter", &Controller{}},
+		{"formatter without content", &Controller{formatter: quietFormatter{}}},
+		{"handler returning blank text", &Controller{handler: emptyText}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execResult := &ExecResult{ExitCode: 3}
+			result, err := tt.ctrl.Handle(context.Background(), mcp.CallToolRequest{}, execResult, errors.New("exit status 3"))
+			require.NoError(t, err)
+			assert.True(t, result.IsError)
+			require.Len(t, result.Content, 1)
+			assert.Equal(t, "command exited with code 3: exit status 3", result.Content[0].(mcp.TextContent).Text)
+			assert.Equal(t, 3, result.Meta.AdditionalFields[MetaExitCode])
+		})
+	}
+
+	t.Run("successful command without output", func(t *testing.T) {
+		ctrl := &Controller{formatter: quietFormatter{}}
+		result, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{}, nil)
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Empty(t, result.Content)
+	})
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// flagInjection holds flags that are set on every call of the selected commands.
+type flagInjection struct {
+	selector Filter
+	flags    map[string]string
+}
+
+// WithInjectedFlags returns a GeneratorOption that sets flags on every call of the selected
+// tools, e.g. to guarantee machine-readable output or to disable interactive prompts. A nil
+// selector selects every tool. Flags are only set on commands that define them, and are
+// removed from the tool inputs. A value sent by the client for an injected flag is replaced
+// by the injected one. Boolean flags are set with "true" or "false". Later options take
+// precedence over earlier ones for the same flag.
+//
+//	Example: WithInjectedFlags(nil, map[string]string{"output": "json", "non-interactive": "true"})
+func WithInjectedFlags(selector Filter, flags map[string]string) GeneratorOption {
+	return func(g *Generator) {
+		g.flagInjections = append(g.flagInjections, flagInjection{selector: selector, flags: flags})
+	}
+}
+
+// injectedFlagsFor returns the injected flags of a generated tool that its command defines,
+// keyed by their long names.
+func (g *Generator) injectedFlagsFor(cmd *cobra.Command, flags *pflag.FlagSet) map[string]string {
+	var injected map[string]string
+	for _, injection := range g.flagInjections {
+		if injection.selector != nil && !injection.selector(cmd) {
+			continue
+		}
+
+		for name, value := range injection.flags {
+			flag := flags.Lookup(strings.TrimLeft(name, "-"))
+			if flag == nil {
+				g.logger.Debug("not injecting flag the command does not define", "command", cmd.CommandPath(), "flag", name)
+				continue
+			}
+
+			if injected == nil {
+				injected = map[string]string{}
+			}
+			injected[flag.Name] = value
+		}
+	}
+
+	return injected
+}
+
+// withoutFlags returns the flags that are not in names, for the tool inputs.
+func withoutFlags(flags *pflag.FlagSet, names map[string]string) *pflag.FlagSet {
+	if len(names) == 0 {
+		return flags
+	}
+
+	visible := pflag.NewFlagSet(flags.Name(), pflag.ContinueOnError)
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if _, ok := names[flag.Name]; !ok {
+			visible.AddFlag(flag)
+		}
+	})
+
+	return visible
+}
+
+// injectFlags returns flagMap with the injected flags of the tool set, replacing the values
+// sent by the client. flagMap must use the long flag names.
+func (c *Controller) injectFlags(flagMap map[string]any) map[string]any {
+	injected := make(map[string]any, len(flagMap)+len(c.injected))
+	maps.Copy(injected, flagMap)
+	for name, value := range c.injected {
+		if sent, ok := flagMap[name]; ok {
+			c.log().Debug("replacing flag sent by the client with the injected value",
+				"tool", c.Tool.Name, "flag_name", name, "value", c.sensitive.value(name, sent))
+		}
+		injected[name] = value
+	}
+
+	return injected
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithInjectedFlags tests that injected flags are set on every call and hidden from clients
+func TestWithInjectedFlags(t *testing.T) {
+	run := func(*cobra.Command, []string) {}
+	root := &cobra.Command{Use: "cli"}
+	root.PersistentFlags().Bool("non-interactive", false, "Never prompt")
+	get := &cobra.Command{Use: "get", Run: run}
+	get.Flags().StringP("output", "o", "table", "Output format")
+	get.Flags().String("selector", "", "Label selector")
+	require.NoError(t, get.MarkFlagRequired("output"))
+	deleteCmd := &cobra.Command{Use: "delete", Run: run}
+	root.AddCommand(get, deleteCmd)
+
+	tools := NewGenerator(
+		WithInjectedFlags(nil, map[string]string{"output": "yaml", "non-interactive": "true"}),
+		WithInjectedFlags(Allow([]string{"get"}), map[string]string{"output": "json"}),
+	).FromRootCmd(root)
+	require.Len(t, tools, 2)
+	byName := map[string]Controller{}
+	for _, tool := range tools {
+		byName[tool.Tool.Name] = tool
+	}
+
+	buildArgs := func(t *testing.T, tool string, flags map[string]any) []string {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = map[string]any{FlagsParam: flags}
+		ctrl := byName[tool]
+		args, err := ctrl.BuildArgs(request)
+		require.NoError(t, err)
+		return args
+	}
+
+	t.Run("removed from the tool inputs", func(t *testing.T) {
+		schema := byName["cli_get"].Tool.InputSchema.Properties[FlagsParam].(map[string]any)
+		properties := schema["properties"].(map[string]any)
+		assert.Contains(t, properties, "selector")
+		assert.NotContains(t, properties, "output")
+		assert.NotContains(t, properties, "non-interactive")
+		assert.NotContains(t, schema, "required", "the injected value satisfies the required flag")
+	})
+
+	t.Run("set on every call", func(t *testing.T) {
+		assert.Equal(t, []string{"get", "--non-interactive", "--output=json"}, buildArgs(t, "cli_get", nil))
+		assert.Equal(t, []string{"get", "--non-interactive", "--output=json", "--selector=app=web"},
+			buildArgs(t, "cli_get", map[string]any{"selector": "app=web"}))
+	})
+
+	t.Run("replace the values of the client", func(t *testing.T) {
+		assert.Equal(t, []string{"get", "--non-interactive", "--output=json"},
+			buildArgs(t, "cli_get", map[string]any{"o": "table", "non-interactive": false}))
+	})
+
+	t.Run("only set on commands that define them", func(t *testing.T) {
+		assert.Equal(t, []string{"delete", "--non-interactive"}, buildArgs(t, "cli_delete", nil))
+	})
+}
+
+// TestInjectedCountFlag tests that count flags can be injected, whose values are strings
+func TestInjectedCountFlag(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}}
+	get.Flags().CountP("verbose", "v", "Verbosity")
+	root.AddCommand(get)
+
+	tools := NewGenerator(WithInjectedFlags(nil, map[string]string{"verbose": "2"})).FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	args, err := tools[0].BuildArgs(mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"get", "--verbose", "--verbose"}, args)
+
+	require.NoError(t, get.Flags().Parse(args[1:]))
+	count, err := get.Flags().GetCount("verbose")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ErrInProcessDir is returned by InProcessExecutor for calls that set a working directory,
+// which cannot be changed for a single command without affecting the whole server.
+var ErrInProcessDir = errors.New("working directories are not supported by the in-process executor")
+
+// WithInProcessExecution returns a GeneratorOption that runs the generated tools with an
+// InProcessExecutor for the tree of the commands passed to Generate, instead of starting a subprocess.
+// It takes precedence over WithExecutor. See InProcessExecutor for the requirements on commands.
+//
+//	Example: NewGenerator(WithInProcessExecution())
+func WithInProcessExecution() GeneratorOption {
+	return func(g *Generator) {
+		g.inProcess = true
+	}
+}
+
+// InProcessExecutor runs commands by executing the cobra command tree of the server directly,
+// saving the cost of starting a subprocess and keeping in-memory state between calls.
+// Subprocesses, run by the DefaultExecutor, remain the safe default, since an in-process command
+// shares the process of the server:
+//
+//   - It must write its output to cmd.OutOrStdout and cmd.ErrOrStderr, and read its input from
+//     cmd.InOrStdin. Writing to os.Stdout corrupts the messages of the stdio transport.
+//   - It must return errors instead of calling os.Exit or log.Fatal, which stop the server.
+//   - It must stop when cmd.Context is cancelled, since it cannot be killed.
+//   - It sees the environment of the server, not the one of the Invocation.
+//
+// Calls are run one at a time. Before each call, the flags of every command are reset to their
+// defaults. Slice and map flags are reset completely, while other custom pflag.Value
+// implementations are reset by setting their default value. A call fails if a flag cannot be
+// reset, rather than running with the values of the previous call.
+type InProcessExecutor struct {
+	// Root is the root of the command tree the tools were generated from.
+	Root *cobra.Command
+
+	mu sync.Mutex
+}
+
+// Run executes the command tree of Root with the invocation arguments.
+func (e *InProcessExecutor) Run(ctx context.Context, inv Invocation) (result *ExecResult, err error) {
+	if inv.Dir != "" {
+		return nil, ErrInProcessDir
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	capture := &outputCapture{}
+	var stdout, stderr io.Writer = capture.stdoutWriter(), capture.stderrWriter()
+	if inv.OnOutput != nil {
+		stdoutLines := &lineWriter{stream: StreamStdout, emit: inv.OnOutput}
+		stderrLines := &lineWriter{stream: StreamStderr, emit: inv.OnOutput}
+		defer stdoutLines.flush()
+		defer stderrLines.flush()
+		stdout = io.MultiWriter(stdout, stdoutLines)
+		stderr = io.MultiWriter(stderr, stderrLines)
+	}
+
+	stdin := inv.Stdin
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+
+	if err := resetFlags(e.Root, setFlagNames(inv.Args)); err != nil {
+		return nil, err
+	}
+	e.Root.SetArgs(inv.Args)
+	e.Root.SetIn(stdin)
+	e.Root.SetOut(stdout)
+	e.Root.SetErr(stderr)
+	defer func() {
+		e.Root.SetArgs(nil)
+		e.Root.SetIn(nil)
+		e.Root.SetOut(nil)
+		e.Root.SetErr(nil)
+	}()
+
+	defer func() {
+		result = capture.result(nil)
+		switch r := recover(); {
+		case r != nil:
+			// Exit like an unrecovered panic would, without taking the server down
+			result.ExitCode = 2
+			err = fmt.Errorf("command panicked: %v", r)
+		case err != nil:
+			result.ExitCode = 1
+		default:
+			result.ExitCode = 0
+		}
+	}()
+
+	return nil, e.Root.ExecuteContext(ctx)
+}
+
+// setFlagNames returns the names of the flags set by the arguments built for a tool call,
+// which are always in the --name or --name=value form.
+func setFlagNames(args []string) map[string]bool {
+	names := map[string]bool{}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		if name, ok := strings.CutPrefix(arg, "--"); ok {
+			name, _, _ = strings.Cut(name, "=")
+			names[name] = true
+		}
+	}
+
+	return names
+}
+
+// resetFlags restores the default value of every flag in the command tree of root, so that
+// values set by a previous call do not leak into the next one. Slice and map flags that are
+// set by the next call are emptied instead, since pflag appends to slices and merges into maps
+// that were set before.
+func resetFlags(root *cobra.Command, set map[string]bool) error {
+	seen := map[*pflag.Flag]bool{}
+	var resetErr error
+	reset := func(flag *pflag.Flag) {
+		if seen[flag] || resetErr != nil {
+			return
+		}
+		seen[flag] = true
+
+		if err := resetFlag(flag, set[flag.Name]); err != nil {
+			resetErr = fmt.Errorf("failed to reset flag %q: %w", flag.Name, err)
+			return
+		}
+		flag.Changed = false
+	}
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		cmd.Flags().VisitAll(reset)
+		cmd.PersistentFlags().VisitAll(reset)
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(root)
+	return resetErr
+}
+
+// resetFlag restores the default value of a flag, or empties it if it is a slice or map flag
+// that the next call sets.
+func resetFlag(flag *pflag.Flag, set bool) error {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		var values []string
+		if !set {
+			values = sliceDefault(flag.DefValue)
+		}
+		return slice.Replace(values)
+	}
+
+	switch flag.Value.Type() {
+	case "stringToString", "stringToInt", "stringToInt64":
+		return resetMap(flag.Value, flag.DefValue, set)
+	case "ip", "ipMask", "ipNet":
+		// A nil default is shown as "<nil>", which does not parse
+		if v := reflect.ValueOf(flag.Value); flag.DefValue == "<nil>" && v.Kind() == reflect.Pointer {
+			v.Elem().SetZero()
+			return nil
+		}
+	}
+
+	return flag.Value.Set(flag.DefValue)
+}
+
+// resetMap empties the map of a map flag, and sets its default unless the next call sets the
+// flag. pflag merges every value set after the first into the map, and offers no way to remove
+// entries, so the map and the state of the value are replaced through reflection.
+func resetMap(value pflag.Value, defValue string, set bool) error {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported map value %T", value)
+	}
+	m := v.Elem().FieldByName("value")
+	changed := v.Elem().FieldByName("changed")
+	if m.Kind() != reflect.Pointer || m.Type().Elem().Kind() != reflect.Map || changed.Kind() != reflect.Bool {
+		return fmt.Errorf("unsupported map value %T", value)
+	}
+
+	// An empty, unchanged value is replaced rather than merged into by the next Set
+	settable(m).Elem().Set(reflect.MakeMap(m.Type().Elem()))
+	settable(changed).SetBool(false)
+
+	defaults := strings.TrimSuffix(strings.TrimPrefix(defValue, "["), "]")
+	if set || defaults == "" {
+		return nil
+	}
+	return value.Set(defaults)
+}
+
+// settable returns a settable alias of an unexported struct field.
+func settable(field reflect.Value) reflect.Value {
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+// sliceDefault splits the DefValue of a slice flag, e.g. "[a,b]", into its elements.
+func sliceDefault(defValue string) []string {
+	list := strings.Trim(defValue, "[]")
+	if list == "" {
+		return nil
+	}
+
+	return strings.Split(list, ",")
+}
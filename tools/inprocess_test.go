@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inProcessRoot builds a command tree that reports its flags, input and failures through cobra.
+func inProcessRoot() *cobra.Command {
+	root := &cobra.Command{Use: "cli", SilenceUsage: true}
+	root.PersistentFlags().Bool("verbose", false, "Verbose output")
+
+	var output string
+	var tags []string
+	get := &cobra.Command{
+		Use: "get",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			fmt.Fprintf(cmd.OutOrStdout(), "output=%s tags=%s verbose=%t args=%s\n", output, strings.Join(tags, ","), verbose, strings.Join(args, ","))
+			return nil
+		},
+	}
+	get.Flags().StringVar(&output, "output", "table", "Output format")
+	get.Flags().StringSliceVar(&tags, "tag", []string{"default"}, "Tags")
+
+	var labels, annotations map[string]string
+	var address net.IP
+	label := &cobra.Command{
+		Use: "label",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			fmt.Fprintf(cmd.OutOrStdout(), "labels=%v annotations=%v address=%v\n", labels, annotations, address)
+			return nil
+		},
+	}
+	label.Flags().IPVar(&address, "address", nil, "Address")
+	label.Flags().StringToStringVar(&labels, "labels", nil, "Labels")
+	label.Flags().StringToStringVar(&annotations, "annotations", map[string]string{"team": "core", "tier": "web"}, "Annotations")
+
+	cat := &cobra.Command{
+		Use: "cat",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, err := io.Copy(cmd.OutOrStdout(), cmd.InOrStdin())
+			return err
+		},
+	}
+	fail := &cobra.Command{
+		Use:  "fail",
+		RunE: func(*cobra.Command, []string) error { return errors.New("boom") },
+	}
+	panics := &cobra.Command{
+		Use: "panic",
+		Run: func(*cobra.Command, []string) { panic("oops") },
+	}
+
+	root.AddCommand(get, label, cat, fail, panics)
+	return root
+}
+
+// unresettable is a flag value that rejects its own default.
+type unresettable struct{}
+
+func (*unresettable) String() string   { return "unset" }
+func (*unresettable) Set(string) error { return errors.New("cannot be set") }
+func (*unresettable) Type() string     { return "mode" }
+
+// TestInProcessExecutor tests running commands without a subprocess
+func TestInProcessExecutor(t *testing.T) {
+	executor := &InProcessExecutor{Root: inProcessRoot()}
+	ctx := context.Background()
+
+	t.Run("flags are reset between calls", func(t *testing.T) {
+		result, err := executor.Run(ctx, Invocation{Args: []string{"get", "--output=json", "--tag=a", "--tag=b", "--verbose", "--", "pods"}})
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.ExitCode)
+		assert.Equal(t, "output=json tags=a,b verbose=true args=pods\n", string(result.Stdout))
+
+		result, err = executor.Run(ctx, Invocation{Args: []string{"get"}})
+		require.NoError(t, err)
+		assert.Equal(t, "output=table tags=default verbose=false args=\n", string(result.Stdout))
+
+		result, err = executor.Run(ctx, Invocation{Args: []string{"get", "--tag=c"}})
+		require.NoError(t, err)
+		assert.Equal(t, "output=table tags=c verbose=false args=\n", string(result.Stdout))
+	})
+
+	t.Run("map flags are reset between calls", func(t *testing.T) {
+		result, err := executor.Run(ctx, Invocation{Args: []string{"label", "--labels=a=1", "--annotations=owner=me", "--address=10.0.0.1"}})
+		require.NoError(t, err)
+		assert.Equal(t, "labels=map[a:1] annotations=map[owner:me] address=10.0.0.1\n", string(result.Stdout))
+
+		result, err = executor.Run(ctx, Invocation{Args: []string{"label", "--labels=b=2"}})
+		require.NoError(t, err)
+		assert.Equal(t, "labels=map[b:2] annotations=map[team:core tier:web] address=<nil>\n", string(result.Stdout))
+
+		result, err = executor.Run(ctx, Invocation{Args: []string{"label"}})
+		require.NoError(t, err)
+		assert.Equal(t, "labels=map[] annotations=map[team:core tier:web] address=<nil>\n", string(result.Stdout))
+	})
+
+	t.Run("calls fail if a flag cannot be reset", func(t *testing.T) {
+		root := &cobra.Command{Use: "cli", Run: func(*cobra.Command, []string) {}}
+		root.Flags().Var(&unresettable{}, "mode", "Mode")
+
+		_, err := (&InProcessExecutor{Root: root}).Run(ctx, Invocation{})
+		assert.ErrorContains(t, err, `failed to reset flag "mode"`)
+	})
+
+	t.Run("reads stdin", func(t *testing.T) {
+		result, err := executor.Run(ctx, Invocation{Args: []string{"cat"}, Stdin: strings.NewReader("hello")})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(result.Stdout))
+
+		result, err = executor.Run(ctx, Invocation{Args: []string{"cat"}})
+		require.NoError(t, err)
+		assert.Empty(t, result.Stdout)
+	})
+
+	t.Run("errors exit with 1", func(t *testing.T) {
+		result, err := executor.Run(ctx, Invocation{Args: []string{"fail"}})
+		require.EqualError(t, err, "boom")
+		assert.Equal(t, 1, result.ExitCode)
+		assert.Contains(t, string(result.Stderr), "Error: boom")
+	})
+
+	t.Run("panics are recovered", func(t *testing.T) {
+		result, err := executor.Run(ctx, Invocation{Args: []string{"panic"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "oops")
+		assert.Equal(t, 2, result.ExitCode)
+	})
+
+	t.Run("working directories are rejected", func(t *testing.T) {
+		_, err := executor.Run(ctx, Invocation{Args: []string{"get"}, Dir: t.TempDir()})
+		assert.ErrorIs(t, err, ErrInProcessDir)
+	})
+}
+
+// TestWithInProcessExecution tests that the generated tools run in process
+func TestWithInProcessExecution(t *testing.T) {
+	tools := NewGenerator(WithInProcessExecution()).FromRootCmd(inProcessRoot())
+
+	var get Controller
+	for _, tool := range tools {
+		if tool.Tool.Name == "cli_get" {
+			get = tool
+		}
+	}
+	require.IsType(t, &InProcessExecutor{}, get.executor)
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		FlagsParam:          map[string]any{"output": "json"},
+		PositionalArgsParam: []any{"pods"},
+	}
+	result, err := get.Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "output=json tags=default verbose=false args=pods\n", string(result.Stdout))
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// WithLazySchemas returns a GeneratorOption that defers building the input schemas of the
+// tools, and the usage texts of their commands, until they are first needed. Generating the
+// tools of a large command tree then only builds their names, descriptions and annotations,
+// which cuts the startup time of servers with hundreds of commands.
+//
+// The Tool of a lazily generated Controller has an empty input schema; Definition returns it
+// with the schema, which the server of ophis lists. Each schema is built once, on the first
+// tools/list request or failed call of the tool, and kept afterwards.
+//
+//	Example: NewGenerator(WithLazySchemas())
+func WithLazySchemas() GeneratorOption {
+	return func(g *Generator) {
+		g.lazySchemas = true
+	}
+}
+
+// lazySchema builds the input schema of a tool and the usage text of its command once, the
+// first time either is needed. It is safe for concurrent use.
+type lazySchema struct {
+	once   sync.Once
+	mu     *sync.Mutex // serializes builds with commands run in process, nil if they run concurrently
+	build  func() (mcp.ToolInputSchema, string)
+	schema mcp.ToolInputSchema
+	usage  string
+}
+
+func newLazySchema(mu *sync.Mutex, build func() (mcp.ToolInputSchema, string)) *lazySchema {
+	return &lazySchema{mu: mu, build: build}
+}
+
+// get returns the schema and usage text, building them on the first call.
+func (s *lazySchema) get() (mcp.ToolInputSchema, string) {
+	s.once.Do(func() {
+		if s.mu != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+		}
+		s.schema, s.usage = s.build()
+		s.build = nil
+	})
+
+	return s.schema, s.usage
+}
+
+// Definition returns the tool with its input schema. It is the Tool of the Controller, unless
+// the tool was generated WithLazySchemas, whose schema is built by the first call.
+func (c *Controller) Definition() mcp.Tool {
+	if c.lazy == nil {
+		return c.Tool
+	}
+
+	tool := c.Tool
+	tool.InputSchema, _ = c.lazy.get()
+	return tool
+}
+
+// usageText returns the usage text of the command, given to clients that called it wrongly.
+func (c *Controller) usageText() string {
+	if c.lazy == nil {
+		return c.usage
+	}
+
+	_, usage := c.lazy.get()
+	return usage
+}
+
+// schemaMutex returns the mutex that serializes the schema builds of lazily generated tools
+// with the commands executed in process, which modify the command tree the builds read. It is
+// nil for subprocesses, since the builds only read the tree once it was walked.
+func (g *Generator) schemaMutex() *sync.Mutex {
+	if g.lazySchemas && g.inProcessExecutor != nil {
+		return &g.inProcessExecutor.mu
+	}
+
+	return nil
+}
+
+// buildSchemas builds the deferred input schemas and usage texts of eagerly generated tools,
+// in parallel on up to GOMAXPROCS goroutines. The walk of the command tree has merged the flags
+// of every command, so the builds only read the shared state of the tree. Alias tools share the
+// build of their canonical tool.
+func buildSchemas(tools []Controller) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(tools)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				tools[i].Tool.InputSchema, tools[i].usage = tools[i].lazy.get()
+				tools[i].lazy = nil
+			}
+		}()
+	}
+
+	for i := range tools {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
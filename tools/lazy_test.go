@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// largeCommandTree returns a root command with n subcommands, each with a few flags.
+func largeCommandTree(n int) *cobra.Command {
+	root := &cobra.Command{Use: "cli"}
+	root.PersistentFlags().String("kubeconfig", "", "Path to the kubeconfig file")
+	for i := range n {
+		cmd := &cobra.Command{Use: fmt.Sprintf("cmd%d [name]", i), Short: fmt.Sprintf("Run command %d", i), Run: func(*cobra.Command, []string) {}}
+		cmd.Flags().StringP("output", "o", "", "Output format")
+		cmd.Flags().Bool("all", false, "Include all resources")
+		cmd.Flags().StringSlice("label", nil, "Label selectors")
+		cmd.Flags().Int("limit", 10, "Maximum number of results")
+		root.AddCommand(cmd)
+	}
+
+	return root
+}
+
+// TestLazySchemas tests that lazily generated tools build the same schema on first use
+func TestLazySchemas(t *testing.T) {
+	root := largeCommandTree(3)
+	eager, err := NewGenerator(WithAliases()).Generate(root)
+	require.NoError(t, err)
+	lazy, err := NewGenerator(WithAliases(), WithLazySchemas()).Generate(root)
+	require.NoError(t, err)
+	require.Len(t, lazy, len(eager))
+
+	for i := range lazy {
+		assert.Empty(t, lazy[i].Tool.InputSchema.Properties, "the schema of %s is not built", lazy[i].Tool.Name)
+		assert.Equal(t, eager[i].Tool.Description, lazy[i].Tool.Description)
+		assert.Equal(t, eager[i].Tool, lazy[i].Definition())
+		assert.Equal(t, eager[i].Tool, eager[i].Definition())
+	}
+
+	t.Run("usage", func(t *testing.T) {
+		ctrl := lazy[0]
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{FlagsParam: map[string]any{"bogus": true}}
+
+		result, err := ctrl.Execute(context.Background(), request)
+		toolResult, err := ctrl.Handle(context.Background(), request, result, err)
+		require.NoError(t, err)
+		assert.Contains(t, toolResult.Content[len(toolResult.Content)-1].(mcp.TextContent).Text, "Usage:")
+	})
+
+	t.Run("concurrent first use", func(t *testing.T) {
+		lazy, err := NewGenerator(WithLazySchemas()).Generate(largeCommandTree(3))
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		definitions := make([]mcp.Tool, 10)
+		for i := range definitions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				definitions[i] = lazy[i%len(lazy)].Definition()
+			}()
+		}
+		wg.Wait()
+
+		for i, definition := range definitions {
+			assert.Equal(t, lazy[i%len(lazy)].Definition(), definition)
+			assert.NotEmpty(t, definition.InputSchema.Properties)
+		}
+	})
+}
+
+// BenchmarkGenerate measures generating the tools of a large command tree
+func BenchmarkGenerate(b *testing.B) {
+	root := largeCommandTree(500)
+
+	b.Run("eager", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = NewGenerator().Generate(root)
+		}
+	})
+
+	b.Run("lazy", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = NewGenerator(WithLazySchemas()).Generate(root)
+		}
+	})
+}
+
+// TestBuildSchemas tests that the schemas of eagerly generated tools are built in parallel
+// without changing the order of the tools
+func TestBuildSchemas(t *testing.T) {
+	root := largeCommandTree(50)
+	root.Commands()[0].AddCommand(&cobra.Command{Use: "nested", Run: func(*cobra.Command, []string) {}})
+
+	tools, err := NewGenerator().Generate(root)
+	require.NoError(t, err)
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Tool.Name)
+		assert.Nil(t, tool.lazy, "the schema of %s is built", tool.Tool.Name)
+		assert.Contains(t, tool.Tool.InputSchema.Properties, FlagsParam)
+		assert.Contains(t, tool.usage, "Usage:")
+	}
+	assert.Equal(t, []string{"cli_cmd0_nested", "cli_cmd0", "cli_cmd1", "cli_cmd10"}, names[:4], "tools keep the order of the walk")
+	assert.Len(t, names, 51)
+}
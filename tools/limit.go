@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrQueueFull is returned by Execute when the concurrency limit is reached and
+// too many calls are already waiting for a free slot.
+var ErrQueueFull = errors.New("too many commands waiting to run")
+
+// WithMaxConcurrent returns a GeneratorOption that limits how many commands of the generated tools
+// run at the same time. Calls beyond the limit wait for a running command to finish, or until
+// their context is cancelled. A zero limit means no limit.
+//
+//	Example: NewGenerator(WithMaxConcurrent(4))
+func WithMaxConcurrent(limit int) GeneratorOption {
+	return func(g *Generator) {
+		g.maxConcurrent = limit
+	}
+}
+
+// WithMaxQueue returns a GeneratorOption that limits how many calls may wait for a free slot when
+// WithMaxConcurrent is in effect. Calls beyond the queue depth fail immediately with ErrQueueFull.
+// A zero depth means calls always wait.
+func WithMaxQueue(depth int) GeneratorOption {
+	return func(g *Generator) {
+		g.maxQueue = depth
+	}
+}
+
+// limiter bounds the number of concurrently running commands.
+// It is shared by every tool of a Generator.
+type limiter struct {
+	slots    chan struct{}
+	maxQueue int
+	waiting  atomic.Int64
+	rejected atomic.Uint64
+}
+
+// newLimiter returns a limiter allowing limit concurrent commands, or nil if limit is not positive.
+func newLimiter(limit int, maxQueue int) *limiter {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &limiter{slots: make(chan struct{}, limit), maxQueue: maxQueue}
+}
+
+// acquire waits for a free slot. The returned function releases it.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	release := func() { <-l.slots }
+
+	// Take a free slot without queueing
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if waiting := l.waiting.Add(1); l.maxQueue > 0 && waiting > int64(l.maxQueue) {
+		l.waiting.Add(-1)
+		l.rejected.Add(1)
+		return nil, fmt.Errorf("%w: %d running, %d queued", ErrQueueFull, cap(l.slots), l.maxQueue)
+	}
+	defer l.waiting.Add(-1)
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("cancelled while waiting to run: %w", ctx.Err())
+	}
+}
+
+func (l *limiter) stats() PoolStats {
+	return PoolStats{
+		Workers:  cap(l.slots),
+		Busy:     len(l.slots),
+		Queued:   int(l.waiting.Load()),
+		Rejected: l.rejected.Load(),
+	}
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingExecutor records the peak number of concurrent runs and blocks until released
+type blockingExecutor struct {
+	running atomic.Int64
+	peak    atomic.Int64
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *blockingExecutor) Run(ctx context.Context, _ Invocation) (*ExecResult, error) {
+	n := e.running.Add(1)
+	defer e.running.Add(-1)
+	for {
+		peak := e.peak.Load()
+		if n <= peak || e.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	e.started <- struct{}{}
+	select {
+	case <-e.release:
+	case <-ctx.Done():
+	}
+	return &ExecResult{}, nil
+}
+
+// TestLimiter tests queueing, fail fast and cancellation
+func TestLimiter(t *testing.T) {
+	assert.Nil(t, newLimiter(0, 0))
+
+	t.Run("queue full", func(t *testing.T) {
+		l := newLimiter(1, 1)
+		release, err := l.acquire(context.Background())
+		require.NoError(t, err)
+
+		queued := make(chan error)
+		go func() {
+			release, err := l.acquire(context.Background())
+			if err == nil {
+				release()
+			}
+			queued <- err
+		}()
+		require.Eventually(t, func() bool { return l.waiting.Load() == 1 }, time.Second, time.Millisecond)
+
+		_, err = l.acquire(context.Background())
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		release()
+		assert.NoError(t, <-queued)
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		l := newLimiter(1, 0)
+		release, err := l.acquire(context.Background())
+		require.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = l.acquire(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Zero(t, l.waiting.Load())
+	})
+}
+
+// TestWithMaxConcurrent tests that tools of a generator share the concurrency limit
+func TestWithMaxConcurrent(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	root.AddCommand(
+		&cobra.Command{Use: "a", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "b", Run: func(*cobra.Command, []string) {}},
+	)
+
+	executor := &blockingExecutor{started: make(chan struct{}, 10), release: make(chan struct{})}
+	tools := NewGenerator(WithExecutor(executor), WithMaxConcurrent(2)).FromRootCmd(root)
+	require.Len(t, tools, 2)
+
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tools[i%2].Execute(context.Background(), mcp.CallToolRequest{})
+			assert.NoError(t, err)
+		}()
+	}
+
+	// two commands start, the others queue
+	<-executor.started
+	<-executor.started
+	assert.Equal(t, int64(2), executor.running.Load())
+
+	for i := range 6 {
+		executor.release <- struct{}{}
+		if i < 4 {
+			<-executor.started
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, int64(2), executor.peak.Load())
+}
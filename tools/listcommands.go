@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/pflag"
+)
+
+// ListCommandsToolName is the name of the tool created by ListCommandsTool.
+const ListCommandsToolName = "list_commands"
+
+// commandInfo describes a generated tool in the output of the list commands tool.
+type commandInfo struct {
+	Tool        string     `json:"tool"`
+	Command     string     `json:"command"`
+	Description string     `json:"description,omitempty"`
+	ReadOnly    bool       `json:"readOnly"`
+	Destructive bool       `json:"destructive"`
+	Flags       []flagInfo `json:"flags,omitempty"`
+}
+
+// flagInfo describes a flag accepted by a generated tool.
+type flagInfo struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// ListCommandsTool returns a tool that lists the commands of the given tools, with their short
+// descriptions, flags and whether they are read-only or destructive. It gives clients a compact
+// overview of a large command tree before choosing a tool, which tools/list does not, since it
+// returns the full description and input schema of every tool.
+//
+// Only the given tools are listed, so the list reflects the filters of the Generator that
+// created them. Flags are listed as the tools accept them, without injected flags.
+func ListCommandsTool(ctrls []Controller) server.ServerTool {
+	commands := make([]commandInfo, 0, len(ctrls))
+	for i := range ctrls {
+		commands = append(commands, ctrls[i].commandInfo())
+	}
+
+	tool := mcp.NewTool(ListCommandsToolName,
+		mcp.WithDescription("List the available commands with their descriptions and flags, and whether they are read-only or destructive. Call this first to find the tool for a task."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(false),
+	)
+
+	return server.ServerTool{
+		Tool: tool,
+		Handler: func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			list := map[string]any{"commands": commands}
+			text, err := json.Marshal(list)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode the commands: %w", err)
+			}
+
+			return mcp.NewToolResultStructured(list, string(text)), nil
+		},
+	}
+}
+
+// commandInfo describes the tool of c for the list commands tool.
+func (c *Controller) commandInfo() commandInfo {
+	info := commandInfo{
+		Tool:        c.Tool.Name,
+		Command:     strings.Join(c.path, " "),
+		Description: c.short,
+		ReadOnly:    hint(c.Tool.Annotations.ReadOnlyHint, false),
+		Destructive: hint(c.Tool.Annotations.DestructiveHint, true),
+	}
+	if info.Description == "" {
+		// Fall back to the first line of the long help
+		info.Description, _, _ = strings.Cut(c.Tool.Description, "\n")
+	}
+
+	if c.flags == nil {
+		return info
+	}
+
+	required := requiredFlags(c.flags)
+	withoutFlags(c.flags, c.injected).VisitAll(func(flag *pflag.Flag) {
+		fi := flagInfo{
+			Name:        flag.Name,
+			Type:        flag.Value.Type(),
+			Description: flag.Usage,
+			Required:    slices.Contains(required, flag.Name),
+		}
+		if !defaultIsZero(flag) {
+			fi.Default = flag.DefValue
+		}
+		info.Flags = append(info.Flags, fi)
+	})
+
+	return info
+}
+
+// hint returns the value of a tool annotation hint, or fallback if it is not set.
+func hint(value *bool, fallback bool) bool {
+	if value == nil {
+		return fallback
+	}
+
+	return *value
+}
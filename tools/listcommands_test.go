@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListCommandsTool tests that the list commands tool describes the generated tools
+func TestListCommandsTool(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get", Short: "Get a resource", Run: func(*cobra.Command, []string) {},
+		Annotations: map[string]string{AnnotationReadOnly: "true"}}
+	get.Flags().String("output", "text", "Output format")
+	get.Flags().Bool("non-interactive", false, "Never prompt")
+	get.Flags().String("name", "", "Resource name")
+	require.NoError(t, get.MarkFlagRequired("name"))
+	remove := &cobra.Command{Use: "delete", Long: "Delete a resource.\n\nIt cannot be undone.", Run: func(*cobra.Command, []string) {}}
+	hidden := &cobra.Command{Use: "internal", Hidden: true, Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(get, remove, hidden)
+
+	ctrls, err := NewGenerator(WithInjectedFlags(nil, map[string]string{"non-interactive": "true"})).Generate(root)
+	require.NoError(t, err)
+
+	tool := ListCommandsTool(ctrls)
+	assert.Equal(t, ListCommandsToolName, tool.Tool.Name)
+	assert.True(t, *tool.Tool.Annotations.ReadOnlyHint)
+
+	result, err := tool.Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	var list struct {
+		Commands []commandInfo `json:"commands"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &list))
+	assert.Equal(t, map[string]any{"commands": list.Commands}, result.StructuredContent)
+	assert.ElementsMatch(t, []commandInfo{
+		{
+			Tool:        "cli_get",
+			Command:     "get",
+			Description: "Get a resource",
+			ReadOnly:    true,
+			Flags: []flagInfo{
+				{Name: "name", Type: "string", Description: "Resource name", Required: true},
+				{Name: "output", Type: "string", Description: "Output format", Default: "text"},
+			},
+		},
+		{
+			Tool:        "cli_delete",
+			Command:     "delete",
+			Description: "Delete a resource.",
+			Destructive: true,
+		},
+	}, list.Commands, "hidden commands and injected flags are not listed")
+}
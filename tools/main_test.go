@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// helperEnv makes the test binary act as the command executed by Controller.Execute.
+const helperEnv = "OPHIS_TEST_HELPER"
+
+// TestMain runs the helper command instead of the tests when helperEnv is set,
+// so Execute can be tested end to end against os.Executable().
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) == "1" {
+		os.Exit(runHelper(os.Args[1:]))
+	}
+
+	os.Exit(m.Run())
+}
+
+// runHelper implements the subcommands used by the execution tests.
+func runHelper(args []string) int {
+	if len(args) == 0 {
+		return 2
+	}
+
+	// drop the terminator placed before positional arguments, like cobra does
+	if i := slices.Index(args, "--"); i != -1 {
+		args = slices.Delete(args, i, i+1)
+	}
+
+	switch args[0] {
+	case "echo":
+		// print each argument on its own line, and a marker on stderr
+		fmt.Println(strings.Join(args[1:], "\n"))
+		fmt.Fprintln(os.Stderr, "stderr")
+		return 0
+	case "cat":
+		// copy stdin to stdout
+		_, _ = io.Copy(os.Stdout, os.Stdin)
+		return 0
+	case "sleep":
+		fmt.Println("started")
+		d, _ := time.ParseDuration(args[len(args)-1])
+		time.Sleep(d)
+		return 0
+	case "spawn":
+		// start a grandchild that outlives this process unless the group is killed
+		exe, _ := os.Executable()
+		child := exec.Command(exe, "sleep", "30s")
+		if err := child.Start(); err != nil {
+			return 1
+		}
+		fmt.Println(child.Process.Pid)
+		time.Sleep(30 * time.Second)
+		return 0
+	case "spawn-detached":
+		// start a grandchild that ignores SIGTERM and holds none of the output pipes
+		exe, _ := os.Executable()
+		child := exec.Command(exe, "ignore-term")
+		if err := child.Start(); err != nil {
+			return 1
+		}
+		fmt.Println(child.Process.Pid)
+		time.Sleep(30 * time.Second)
+		return 0
+	case "ignore-term":
+		signal.Ignore(syscall.SIGTERM)
+		fmt.Println("started")
+		time.Sleep(30 * time.Second)
+		return 0
+	case "trap-int":
+		// clean up on SIGINT only, like commands that expect to be interrupted from a terminal
+		signal.Ignore(syscall.SIGTERM)
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		fmt.Println("started")
+		select {
+		case <-interrupted:
+			fmt.Println("cleaned up")
+			return 130
+		case <-time.After(30 * time.Second):
+			return 0
+		}
+	case "env":
+		// print the environment, one variable per line
+		fmt.Println(strings.Join(os.Environ(), "\n"))
+		return 0
+	case "pwd":
+		dir, _ := os.Getwd()
+		fmt.Println(dir)
+		return 0
+	case "color":
+		// print colored output, as CLIs forcing color do
+		fmt.Println("\x1b[1;31merror\x1b[0m: failed")
+		fmt.Fprintln(os.Stderr, "\x1b[33mwarning\x1b[0m")
+		return 0
+	case "prompt":
+		// ask for confirmation on stdin, then on the terminal, like interactive CLIs do
+		if _, err := fmt.Scanln(new(string)); err == io.EOF {
+			fmt.Println("stdin: EOF")
+		}
+		if tty, err := os.Open("/dev/tty"); err != nil {
+			fmt.Println("tty: unavailable")
+		} else {
+			_ = tty.Close()
+		}
+		return 1
+	case "write":
+		// write the arguments to the output file, as commands writing artifacts do
+		if err := os.WriteFile(os.Getenv(OutputFileEnv), []byte(strings.Join(args[1:], "\n")), 0o600); err != nil {
+			return 1
+		}
+		return 0
+	case "exit":
+		code, _ := strconv.Atoi(args[len(args)-1])
+		return code
+	}
+
+	return 2
+}
+
+// helperController returns a Controller that executes the named helper subcommand.
+func helperController(t *testing.T, name string) *Controller {
+	t.Helper()
+	return &Controller{
+		Tool: mcp.NewTool("helper_" + name),
+		path: []string{name},
+		Env:  map[string]string{helperEnv: "1"},
+	}
+}
+
+// helperRequest returns a request with the given positional argument string.
+func helperRequest(args string) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		PositionalArgsParam: args,
+	}
+	return request
+}
@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics receives the executions of the generated tools, e.g. to count them and record their
+// duration and output size with Prometheus. The tool name is the label of every call. Methods
+// are called synchronously from concurrent executions, so implementations must be safe for
+// concurrent use and should return quickly.
+//
+// Gauges of the executions in flight are kept by counting ExecStarted and ExecFinished. The
+// queue of WithMaxConcurrent or WithWorkerPool is read with Generator.PoolStats.
+type Metrics interface {
+	// ExecStarted is called when the command of a tool call starts running, after it waited
+	// for a free slot.
+	ExecStarted(ctx context.Context, tool string)
+	// ExecFinished is called when the command of ExecStarted has finished.
+	ExecFinished(ctx context.Context, tool string, exec ExecMetrics)
+	// CallRejected is called for a tool call whose command was not run because of err, e.g.
+	// invalid arguments, a denied authorization, or a full queue. Dry runs and cached output
+	// are neither rejected nor executed.
+	CallRejected(ctx context.Context, tool string, err error)
+}
+
+// ExecMetrics describes a finished execution for Metrics.
+type ExecMetrics struct {
+	// Duration is the wall-clock time the command ran for.
+	Duration time.Duration
+	// ExitCode is the exit code of the process, or -1 if it did not exit normally.
+	ExitCode int
+	// Failed reports whether the execution returned an error, e.g. a non-zero exit code.
+	Failed bool
+	// TimedOut reports whether the command was killed because a deadline passed.
+	TimedOut bool
+	// StdoutBytes and StderrBytes are the sizes of the output, before truncation.
+	StdoutBytes int
+	StderrBytes int
+}
+
+// WithMetrics returns a GeneratorOption that reports every execution of a generated tool to
+// metrics. Nothing is reported by default.
+//
+//	Example: NewGenerator(WithMetrics(myPrometheusMetrics))
+func WithMetrics(metrics Metrics) GeneratorOption {
+	return func(g *Generator) {
+		g.metrics = metrics
+	}
+}
+
+// execMetrics returns the metrics of an execution that ran for duration.
+func execMetrics(result *ExecResult, err error, duration time.Duration) ExecMetrics {
+	exec := ExecMetrics{Duration: duration, ExitCode: -1, Failed: err != nil}
+	if result != nil {
+		exec.ExitCode = result.ExitCode
+		exec.TimedOut = result.TimedOut
+		exec.StdoutBytes = len(result.Stdout)
+		exec.StderrBytes = len(result.Stderr)
+		if result.Duration > 0 {
+			exec.Duration = result.Duration
+		}
+	}
+
+	return exec
+}
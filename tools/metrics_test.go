@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMetrics records the calls of the Metrics hooks.
+type recordingMetrics struct {
+	mu       sync.Mutex
+	events   []string
+	finished []ExecMetrics
+	rejected []error
+}
+
+func (m *recordingMetrics) ExecStarted(_ context.Context, tool string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, "started "+tool)
+}
+
+func (m *recordingMetrics) ExecFinished(_ context.Context, tool string, exec ExecMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, "finished "+tool)
+	m.finished = append(m.finished, exec)
+}
+
+func (m *recordingMetrics) CallRejected(_ context.Context, tool string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, "rejected "+tool)
+	m.rejected = append(m.rejected, err)
+}
+
+// TestMetrics tests that the hooks are called around executions and for rejected calls
+func TestMetrics(t *testing.T) {
+	t.Run("failed execution", func(t *testing.T) {
+		metrics := &recordingMetrics{}
+		ctrl := helperController(t, "exit")
+		ctrl.metrics = metrics
+
+		_, err := ctrl.Execute(context.Background(), helperRequest("3"))
+		require.Error(t, err)
+		assert.Equal(t, []string{"started helper_exit", "finished helper_exit"}, metrics.events)
+		require.Len(t, metrics.finished, 1)
+		assert.Equal(t, 3, metrics.finished[0].ExitCode)
+		assert.True(t, metrics.finished[0].Failed)
+		assert.Positive(t, metrics.finished[0].Duration)
+	})
+
+	t.Run("output size", func(t *testing.T) {
+		metrics := &recordingMetrics{}
+		ctrl := helperController(t, "echo")
+		ctrl.metrics = metrics
+		ctrl.MaxOutputBytes = 2
+
+		_, err := ctrl.Execute(context.Background(), helperRequest("abcdef"))
+		require.NoError(t, err)
+		require.Len(t, metrics.finished, 1)
+		assert.False(t, metrics.finished[0].Failed)
+		assert.Equal(t, 7, metrics.finished[0].StdoutBytes)
+	})
+
+	t.Run("rejected call", func(t *testing.T) {
+		metrics := &recordingMetrics{}
+		ctrl := &Controller{Tool: mcp.NewTool("test"), metrics: metrics, executor: &recordingExecutor{}}
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{PositionalArgsParam: []any{1}}
+
+		_, err := ctrl.Execute(context.Background(), request)
+		require.Error(t, err)
+		assert.Equal(t, []string{"rejected test"}, metrics.events)
+		assert.ErrorIs(t, metrics.rejected[0], ErrInvalidArguments)
+	})
+}
+
+// TestPoolStatsLimiter tests that the state of the concurrency limit is reported like a pool
+func TestPoolStatsLimiter(t *testing.T) {
+	g := NewGenerator(WithMaxConcurrent(1), WithMaxQueue(1))
+	release, err := g.limiter.acquire(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	assert.Equal(t, PoolStats{Workers: 1, Busy: 1}, g.PoolStats())
+}
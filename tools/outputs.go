@@ -0,0 +1,238 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// OutputFileEnv is the environment variable holding the path of a file that a command may write
+// an artifact to, for tools of a Generator configured with WithOutputResources. The file does not
+// exist when the command starts. If the command creates it, it is returned as a resource link.
+const OutputFileEnv = "OPHIS_OUTPUT_FILE"
+
+// outputURIPrefix starts the URIs of the stored outputs, which end with their ID.
+const outputURIPrefix = "ophis://output/"
+
+// WithOutputResources returns a GeneratorOption that returns large outputs as MCP resource
+// links, which clients read with resources/read, instead of inlining them in the tool result.
+// The stdout of a successful command longer than threshold bytes is stored in a temporary file,
+// and the tool result holds a link to it instead. Commands can also write an artifact, such as a
+// report, to the file named by OutputFileEnv, which is linked whatever its size. A zero
+// threshold only links artifacts.
+//
+// The outputs are only readable by the client session that called the tool, and are removed when
+// the session ends, or when the server stops. They are served by the MCP server of ophis. A
+// custom Handler, and a Formatter other than PlainText, ErrorAware or JSONPassthrough, receive
+// the output inline, and commands run by WithInProcessExecution cannot
+// see OutputFileEnv.
+//
+//	Example: NewGenerator(WithOutputResources(64 * 1024))
+func WithOutputResources(threshold int) GeneratorOption {
+	return func(g *Generator) {
+		g.outputs = &OutputResources{threshold: threshold, files: map[string]outputFile{}}
+	}
+}
+
+// OutputResources stores the outputs of the tools of a Generator configured with
+// WithOutputResources in temporary files, and serves them as MCP resources. It is safe for
+// concurrent use.
+type OutputResources struct {
+	threshold int // stdout longer than this is stored, 0 to only store artifacts
+
+	mu    sync.Mutex
+	dir   string                // holds the files, created with the first one
+	files map[string]outputFile // stored outputs by ID
+}
+
+// outputFile is a stored output.
+type outputFile struct {
+	path     string
+	session  string // ID of the client session allowed to read it, or empty for any
+	mimeType string
+}
+
+// OutputResources returns the store of the outputs of the tool, or nil if it does not return
+// outputs as resources.
+func (c *Controller) OutputResources() *OutputResources {
+	return c.outputs
+}
+
+// ResourceTemplate returns the resource template that serves the stored outputs. A client can
+// only read the outputs of its own session.
+func (r *OutputResources) ResourceTemplate() server.ServerResourceTemplate {
+	template := mcp.NewResourceTemplate(outputURIPrefix+"{id}", "Tool output",
+		mcp.WithTemplateDescription("Output of a tool call that was too large to return inline"),
+	)
+
+	return server.ServerResourceTemplate{Template: template, Handler: r.read}
+}
+
+// read returns the contents of a stored output, as text if it is valid UTF-8.
+func (r *OutputResources) read(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := strings.TrimPrefix(request.Params.URI, outputURIPrefix)
+	r.mu.Lock()
+	file, ok := r.files[id]
+	r.mu.Unlock()
+	if !ok || (file.session != "" && file.session != sessionID(ctx)) {
+		return nil, fmt.Errorf("%w: %s", server.ErrResourceNotFound, request.Params.URI)
+	}
+
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output: %w", err)
+	}
+
+	if utf8.Valid(data) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: request.Params.URI, MIMEType: file.mimeType, Text: string(data)}}, nil
+	}
+	return []mcp.ResourceContents{mcp.BlobResourceContents{URI: request.Params.URI, MIMEType: file.mimeType, Blob: base64.StdEncoding.EncodeToString(data)}}, nil
+}
+
+// RemoveSession removes the outputs of a client session.
+func (r *OutputResources) RemoveSession(session string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, file := range r.files {
+		if file.session == session {
+			_ = os.Remove(file.path)
+			delete(r.files, id)
+		}
+	}
+}
+
+// Close removes every stored output. Outputs stored afterwards are kept until the next Close.
+func (r *OutputResources) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.dir == "" {
+		return nil
+	}
+
+	err := os.RemoveAll(r.dir)
+	r.dir = ""
+	clear(r.files)
+	return err
+}
+
+// outputPath returns the path of a new file in the output directory, which is not created.
+func (r *OutputResources) outputPath() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.dir == "" {
+		dir, err := os.MkdirTemp("", "ophis-output-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+		r.dir = dir
+	}
+
+	return filepath.Join(r.dir, rand.Text()), nil
+}
+
+// add registers the file at path as an output of the session of ctx, and returns a link to it.
+// Its MIME type is detected from head, the first bytes of the file.
+func (r *OutputResources) add(ctx context.Context, name, description, path string, head []byte) mcp.ResourceLink {
+	id := rand.Text()
+	mimeType := http.DetectContentType(head)
+
+	r.mu.Lock()
+	r.files[id] = outputFile{path: path, session: sessionID(ctx), mimeType: mimeType}
+	r.mu.Unlock()
+
+	return mcp.NewResourceLink(outputURIPrefix+id, name, description, mimeType)
+}
+
+// storeStdout replaces the stdout of a successful tool result with a link to a stored copy, if
+// it is longer than the threshold. The output stays inline if it cannot be stored. It reports
+// whether the output was stored.
+func (r *OutputResources) storeStdout(ctx context.Context, logger *slog.Logger, toolName string, toolResult *mcp.CallToolResult, stdout []byte) bool {
+	if r.threshold <= 0 || len(stdout) <= r.threshold || toolResult.IsError || len(toolResult.Content) == 0 {
+		return false
+	}
+
+	path, err := r.outputPath()
+	if err == nil {
+		err = os.WriteFile(path, stdout, 0o600)
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "failed to store output, returning it inline", "tool", toolName, "error", err)
+		return false
+	}
+
+	link := r.add(ctx, toolName+" output", fmt.Sprintf("Output of %s, %d bytes", toolName, len(stdout)), path, stdout)
+	note := mcp.NewTextContent(fmt.Sprintf("The output is %d bytes, too large to return inline. Read it from the resource %s.", len(stdout), link.URI))
+	toolResult.Content = append([]mcp.Content{note, link}, toolResult.Content[1:]...)
+	return true
+}
+
+// addArtifact appends a link to the artifact a command wrote to path to the tool result, if the
+// command created it. Only the head of the file is read, which may be large.
+func (r *OutputResources) addArtifact(ctx context.Context, logger *slog.Logger, toolName string, toolResult *mcp.CallToolResult, path string) {
+	size, head, err := readHead(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return
+	case err != nil:
+		logger.WarnContext(ctx, "failed to read the output file of the command", "tool", toolName, "error", err)
+		return
+	}
+
+	link := r.add(ctx, toolName+" artifact", fmt.Sprintf("File written by %s, %d bytes", toolName, size), path, head)
+	toolResult.Content = append(toolResult.Content, link)
+}
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// readHead returns the size of the regular file at path, and its first bytes to detect its
+// MIME type from.
+func readHead(path string) (int64, []byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Nothing was written, so closing cannot lose data
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, nil, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return 0, nil, err
+	}
+
+	return info.Size(), head[:n], nil
+}
+
+// sessionID returns the ID of the client session of ctx, or empty if there is none.
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+
+	return ""
+}
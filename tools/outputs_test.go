@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readOutput reads the resource of a link from outputs.
+func readOutput(ctx context.Context, outputs *OutputResources, link mcp.ResourceLink) ([]mcp.ResourceContents, error) {
+	var request mcp.ReadResourceRequest
+	request.Params.URI = link.URI
+	return outputs.ResourceTemplate().Handler(ctx, request)
+}
+
+// TestOutputResources tests that large outputs and artifacts are returned as resource links
+func TestOutputResources(t *testing.T) {
+	ctx := server.NewMCPServer("test", "1.0.0").WithContext(context.Background(), newTestSession())
+
+	t.Run("large stdout", func(t *testing.T) {
+		outputs := &OutputResources{threshold: 16, files: map[string]outputFile{}}
+		defer outputs.Close()
+		ctrl := helperController(t, "echo")
+		ctrl.outputs = outputs
+		request := helperRequest(strings.Repeat("x", 32))
+
+		result, err := ctrl.Execute(ctx, request)
+		toolResult, err := ctrl.Handle(ctx, request, result, err)
+		require.NoError(t, err)
+		require.Len(t, toolResult.Content, 3, "the stderr stays inline")
+		assert.Contains(t, toolResult.Content[0].(mcp.TextContent).Text, "too large to return inline")
+		link, ok := toolResult.Content[1].(mcp.ResourceLink)
+		require.True(t, ok)
+		assert.True(t, strings.HasPrefix(link.URI, outputURIPrefix))
+		assert.Equal(t, "helper_echo output", link.Name)
+
+		contents, err := readOutput(ctx, outputs, link)
+		require.NoError(t, err)
+		require.Len(t, contents, 1)
+		assert.Equal(t, strings.Repeat("x", 32)+"\n", contents[0].(mcp.TextResourceContents).Text)
+
+		_, err = readOutput(context.Background(), outputs, link)
+		assert.ErrorIs(t, err, server.ErrResourceNotFound, "other sessions cannot read the output")
+
+		outputs.RemoveSession("test")
+		_, err = readOutput(ctx, outputs, link)
+		assert.ErrorIs(t, err, server.ErrResourceNotFound)
+	})
+
+	t.Run("small stdout", func(t *testing.T) {
+		outputs := &OutputResources{threshold: 1024, files: map[string]outputFile{}}
+		defer outputs.Close()
+		ctrl := helperController(t, "echo")
+		ctrl.outputs = outputs
+		request := helperRequest("small")
+
+		result, err := ctrl.Execute(ctx, request)
+		toolResult, err := ctrl.Handle(ctx, request, result, err)
+		require.NoError(t, err)
+		assert.Equal(t, "small\n", toolResult.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("other formatter", func(t *testing.T) {
+		outputs := &OutputResources{threshold: 16, files: map[string]outputFile{}}
+		defer outputs.Close()
+		ctrl := helperController(t, "echo")
+		ctrl.outputs = outputs
+		ctrl.formatter = Markdown{}
+		request := helperRequest(strings.Repeat("x", 32))
+
+		result, err := ctrl.Execute(ctx, request)
+		toolResult, err := ctrl.Handle(ctx, request, result, err)
+		require.NoError(t, err)
+		assert.Contains(t, toolResult.Content[0].(mcp.TextContent).Text, strings.Repeat("x", 32), "the formatted stdout stays inline")
+		for _, content := range toolResult.Content {
+			assert.IsType(t, mcp.TextContent{}, content)
+		}
+	})
+
+	t.Run("artifact", func(t *testing.T) {
+		outputs := &OutputResources{files: map[string]outputFile{}}
+		ctrl := helperController(t, "write")
+		ctrl.outputs = outputs
+		request := helperRequest("report")
+
+		result, err := ctrl.Execute(ctx, request)
+		toolResult, err := ctrl.Handle(ctx, request, result, err)
+		require.NoError(t, err)
+		link, ok := toolResult.Content[len(toolResult.Content)-1].(mcp.ResourceLink)
+		require.True(t, ok, "the artifact is linked")
+		assert.Equal(t, "helper_write artifact", link.Name)
+
+		contents, err := readOutput(ctx, outputs, link)
+		require.NoError(t, err)
+		assert.Equal(t, "report", contents[0].(mcp.TextResourceContents).Text)
+
+		dir := outputs.dir
+		require.NoError(t, outputs.Close())
+		assert.NoDirExists(t, dir)
+		_, err = readOutput(ctx, outputs, link)
+		assert.ErrorIs(t, err, server.ErrResourceNotFound)
+	})
+
+	t.Run("generator option", func(t *testing.T) {
+		root := &cobra.Command{Use: "cli"}
+		root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+		ctrls, err := NewGenerator(WithOutputResources(64)).Generate(root)
+		require.NoError(t, err)
+		require.Len(t, ctrls, 1)
+		assert.NotNil(t, ctrls[0].OutputResources())
+
+		ctrls, err = NewGenerator().Generate(root)
+		require.NoError(t, err)
+		assert.Nil(t, ctrls[0].OutputResources())
+	})
+}
+
+// TestOutputResourcesDisabled tests that commands do not get an output file by default
+func TestOutputResourcesDisabled(t *testing.T) {
+	ctrl := helperController(t, "env")
+
+	result, err := ctrl.Execute(context.Background(), helperRequest(""))
+	require.NoError(t, err)
+	assert.NotContains(t, string(result.Stdout), OutputFileEnv)
+	assert.Empty(t, result.outputFile)
+}
+
+// TestReadHead tests that only the start of an artifact is read, and its size taken from the file
+func TestReadHead(t *testing.T) {
+	dir := t.TempDir()
+	large := filepath.Join(dir, "large.png")
+	require.NoError(t, os.WriteFile(large, append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 4096)...), 0o600))
+	small := filepath.Join(dir, "small.txt")
+	require.NoError(t, os.WriteFile(small, []byte("report"), 0o600))
+
+	size, head, err := readHead(large)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4104), size)
+	assert.Len(t, head, sniffLen)
+
+	size, head, err = readHead(small)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), size)
+	assert.Equal(t, "report", string(head))
+
+	_, _, err = readHead(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, _, err = readHead(dir)
+	assert.ErrorContains(t, err, "not a regular file")
+}
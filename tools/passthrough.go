@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+)
+
+// WithPassthroughArgs returns a GeneratorOption that adds an optional PassthroughArgsParam
+// parameter to the tools matched by selector, for wrapper commands that forward arguments to
+// another program, e.g. "cli exec pod -- ls -la". Passthrough arguments are placed verbatim after
+// a "--" separator, following the positional arguments, so that cmd.ArgsLenAtDash tells them
+// apart. Positional arguments are then placed before the separator, and must not begin with a dash.
+//
+// Flags, including injected ones, are always placed before the separator, so they apply to the
+// command itself and never to the program it forwards to.
+//
+//	Example: NewGenerator(WithPassthroughArgs(Allow([]string{"exec", "run"})))
+func WithPassthroughArgs(selector Filter) GeneratorOption {
+	return func(g *Generator) {
+		g.passthrough = selector
+	}
+}
+
+// passesThrough reports whether the tool of cmd accepts passthrough arguments.
+func (g *Generator) passesThrough(cmd *cobra.Command) bool {
+	return g.passthrough != nil && g.passthrough(cmd)
+}
+
+// passthroughToolOption returns a ToolOption that adds the passthrough arguments parameter.
+func passthroughToolOption() mcp.ToolOption {
+	return mcp.WithArray(PassthroughArgsParam,
+		mcp.Description(`Optional arguments passed verbatim after a "--" separator, to the program the command runs`),
+		mcp.WithStringItems(),
+	)
+}
+
+// passthroughArgs returns the passthrough arguments of a request.
+func (c *Controller) passthroughArgs(message map[string]any) ([]string, error) {
+	value, ok := message[PassthroughArgsParam]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	if !c.passthrough {
+		return nil, fmt.Errorf("%s is not supported by this tool", PassthroughArgsParam)
+	}
+
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings, got %T", PassthroughArgsParam, value)
+	}
+
+	args := make([]string, 0, len(items))
+	for i, item := range items {
+		arg, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("passthrough argument %d must be a string, got %T", i, item)
+		}
+		args = append(args, arg)
+	}
+
+	return args, nil
+}
+
+// checkBeforeSeparator rejects positional arguments that would be parsed as flags when placed
+// before the "--" separator.
+func checkBeforeSeparator(args []string) error {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("positional argument %q must not begin with a dash when %s are given", arg, PassthroughArgsParam)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithPassthroughArgs tests that passthrough arguments are forwarded verbatim after the separator
+func TestWithPassthroughArgs(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	exec := &cobra.Command{Use: "exec POD -- COMMAND [args...]", Args: cobra.MinimumNArgs(2), Run: func(*cobra.Command, []string) {}}
+	exec.Flags().String("container", "", "Container name")
+	get := &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(exec, get)
+
+	tools := NewGenerator(
+		WithPassthroughArgs(Allow([]string{"exec"})),
+		WithInjectedFlags(nil, map[string]string{"container": "app"}),
+	).FromRootCmd(root)
+	byName := map[string]*Controller{}
+	for i := range tools {
+		byName[tools[i].Tool.Name] = &tools[i]
+	}
+	require.Contains(t, byName["cli_exec"].Tool.InputSchema.Properties, PassthroughArgsParam)
+	require.NotContains(t, byName["cli_get"].Tool.InputSchema.Properties, PassthroughArgsParam)
+
+	buildArgs := func(tool string, arguments map[string]any) ([]string, error) {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = arguments
+		return byName[tool].BuildArgs(request)
+	}
+
+	t.Run("after the separator", func(t *testing.T) {
+		args, err := buildArgs("cli_exec", map[string]any{
+			PositionalArgsParam:  []any{"web"},
+			PassthroughArgsParam: []any{"ls", "-la", "--", "--color"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"exec", "--container=app", "web", "--", "ls", "-la", "--", "--color"}, args)
+	})
+
+	t.Run("without passthrough arguments", func(t *testing.T) {
+		args, err := buildArgs("cli_exec", map[string]any{PositionalArgsParam: []any{"web", "ls"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"exec", "--container=app", "--", "web", "ls"}, args)
+	})
+
+	t.Run("counted as positional arguments", func(t *testing.T) {
+		_, err := buildArgs("cli_exec", map[string]any{PassthroughArgsParam: []any{"ls"}})
+		assert.EqualError(t, err, "invalid tool arguments: expected at least 2 positional arguments, got 1")
+	})
+
+	t.Run("positional arguments must not look like flags", func(t *testing.T) {
+		_, err := buildArgs("cli_exec", map[string]any{
+			PositionalArgsParam:  []any{"-web"},
+			PassthroughArgsParam: []any{"ls"},
+		})
+		assert.ErrorContains(t, err, `positional argument "-web" must not begin with a dash`)
+	})
+
+	t.Run("rejected by other tools", func(t *testing.T) {
+		_, err := buildArgs("cli_get", map[string]any{PassthroughArgsParam: []any{"ls"}})
+		assert.ErrorIs(t, err, ErrInvalidArguments)
+		assert.ErrorContains(t, err, "passthrough_args is not supported by this tool")
+	})
+
+	t.Run("must be strings", func(t *testing.T) {
+		_, err := buildArgs("cli_exec", map[string]any{PassthroughArgsParam: []any{"ls", 1}})
+		assert.ErrorContains(t, err, "passthrough argument 1 must be a string, got int")
+	})
+}
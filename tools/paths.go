@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// pathPolicy confines the values of path flags to root directories.
+type pathPolicy struct {
+	roots     []string
+	flags     []string // additional path flags, besides those marked by cobra
+	mustExist bool
+}
+
+// WithPathRoots returns a GeneratorOption that confines the values of path flags to roots.
+// A relative path is resolved against the working directory of the call, or the first root,
+// and passed to the command as an absolute path. A path that resolves outside of every root,
+// after cleaning and following symlinks, is rejected before the command runs.
+//
+// Path flags are the flags marked with cobra's MarkFlagFilename or MarkFlagDirname, and those
+// named by WithPathFlags. If WithPathRoots is not used, the roots of WithWorkingDirRoots apply.
+//
+// The MCP roots a client may offer are not considered, since the server cannot request them.
+//
+//	Example: NewGenerator(WithPathRoots("/src/monorepo"))
+func WithPathRoots(roots ...string) GeneratorOption {
+	return func(g *Generator) {
+		g.paths.roots = append(g.paths.roots, roots...)
+	}
+}
+
+// WithPathFlags returns a GeneratorOption that treats the flags with these names as path flags,
+// in addition to those marked with cobra's MarkFlagFilename or MarkFlagDirname.
+//
+//	Example: NewGenerator(WithPathFlags("kubeconfig", "output-dir"), WithPathRoots("/src"))
+func WithPathFlags(names ...string) GeneratorOption {
+	return func(g *Generator) {
+		g.paths.flags = append(g.paths.flags, names...)
+	}
+}
+
+// RequireExistingPaths returns a GeneratorOption that rejects the values of path flags that do
+// not exist. Without it, paths that do not exist are allowed, e.g. for output files.
+func RequireExistingPaths() GeneratorOption {
+	return func(g *Generator) {
+		g.paths.mustExist = true
+	}
+}
+
+// pathPolicy returns the path policy of the generated tools, nil if there are no roots.
+func (g *Generator) pathPolicy() *pathPolicy {
+	policy := g.paths
+	if len(policy.roots) == 0 {
+		policy.roots = g.roots
+	}
+	if len(policy.roots) == 0 {
+		return nil
+	}
+
+	return &policy
+}
+
+// isPathFlag reports whether the values of a flag are paths.
+func (p *pathPolicy) isPathFlag(flag *pflag.Flag) bool {
+	if _, ok := flag.Annotations[cobra.BashCompFilenameExt]; ok {
+		return true
+	}
+	if _, ok := flag.Annotations[cobra.BashCompSubdirsInDir]; ok {
+		return true
+	}
+
+	return slices.Contains(p.flags, flag.Name)
+}
+
+// resolveFlags replaces the values of path flags in flagMap by absolute paths inside the roots.
+// Relative paths are resolved against dir, the working directory of the command, if it is set.
+func (p *pathPolicy) resolveFlags(flagMap map[string]any, flags *pflag.FlagSet, dir string) (map[string]any, error) {
+	flagMap, err := normalizeFlagNames(flagMap, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range flagMap {
+		flag := flags.Lookup(name)
+		if flag == nil || value == nil || !p.isPathFlag(flag) {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			if flagMap[name], err = p.resolve(v, dir); err != nil {
+				return nil, fmt.Errorf("flag %q: %w", name, err)
+			}
+		case []any:
+			paths := make([]any, len(v))
+			for i, item := range v {
+				path, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("flag %q: path %d must be a string, got %T", name, i, item)
+				}
+				if paths[i], err = p.resolve(path, dir); err != nil {
+					return nil, fmt.Errorf("flag %q: %w", name, err)
+				}
+			}
+			flagMap[name] = paths
+		default:
+			return nil, fmt.Errorf("flag %q: path must be a string, got %T", name, value)
+		}
+	}
+
+	return flagMap, nil
+}
+
+// resolve returns the absolute form of path, with symlinks followed, if it is inside one of the roots.
+func (p *pathPolicy) resolve(path, dir string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	abs := path
+	if !filepath.IsAbs(abs) {
+		base := dir
+		if base == "" {
+			base = p.roots[0]
+		}
+		abs = filepath.Join(base, abs)
+	}
+
+	resolved, err := evalExistingSymlinks(filepath.Clean(abs))
+	if err != nil {
+		return "", fmt.Errorf("path %q: %w", path, err)
+	}
+
+	if p.mustExist {
+		if _, err := os.Stat(resolved); err != nil {
+			return "", fmt.Errorf("path %q: %w", path, err)
+		}
+	}
+
+	for _, root := range p.roots {
+		root, err := filepath.EvalSymlinks(filepath.Clean(root))
+		if err != nil {
+			continue
+		}
+
+		if isWithin(resolved, root) {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("path %q is outside of the allowed directories: %s", path, strings.Join(p.roots, ", "))
+}
+
+// evalExistingSymlinks follows the symlinks of the longest existing ancestor of a clean absolute
+// path, so that paths of files that are yet to be created can be checked too. Dangling symlinks
+// are rejected, since the command would create their target, wherever it is.
+func evalExistingSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	// The path exists but cannot be followed, so it is a symlink to a missing target
+	if _, err := os.Lstat(path); err == nil {
+		return "", fmt.Errorf("%s is a symlink that does not resolve", path)
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+
+	resolvedParent, err := evalExistingSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
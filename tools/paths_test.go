@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithPathRoots tests that path flags are resolved inside the roots
+func TestWithPathRoots(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "config.yaml"), nil, 0o600))
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "etc")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	cli := &cobra.Command{Use: "cli"}
+	apply := &cobra.Command{Use: "apply", Run: func(*cobra.Command, []string) {}}
+	apply.Flags().String("file", "", "File to apply")
+	apply.Flags().StringSlice("include", nil, "Files to include")
+	apply.Flags().String("kubeconfig", "", "Kubeconfig")
+	apply.Flags().String("name", "", "Name")
+	require.NoError(t, apply.MarkFlagFilename("file", "yaml"))
+	require.NoError(t, apply.MarkFlagDirname("include"))
+	cli.AddCommand(apply)
+
+	execute := func(t *testing.T, tool Controller, executor *recordingExecutor, args map[string]any) ([]string, error) {
+		t.Helper()
+		var request mcp.CallToolRequest
+		request.Params.Arguments = args
+		if _, err := tool.Execute(context.Background(), request); err != nil {
+			return nil, err
+		}
+		require.NotEmpty(t, executor.invocations)
+		return executor.invocations[len(executor.invocations)-1].Args, nil
+	}
+
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(
+		WithExecutor(executor),
+		WithWorkingDirRoots(root),
+		WithPathFlags("kubeconfig"),
+	).FromRootCmd(cli)
+	require.Len(t, tools, 1)
+
+	t.Run("relative paths are resolved against the root", func(t *testing.T) {
+		args, err := execute(t, tools[0], executor, map[string]any{FlagsParam: map[string]any{
+			"file":       "sub/config.yaml",
+			"include":    []any{"sub", "new"},
+			"kubeconfig": filepath.Join(root, "kube"),
+			"name":       "../outside",
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"apply",
+			"--file=" + filepath.Join(root, "sub", "config.yaml"),
+			"--include=" + filepath.Join(root, "sub"),
+			"--include=" + filepath.Join(root, "new"),
+			"--kubeconfig=" + filepath.Join(root, "kube"),
+			"--name=../outside",
+		}, args)
+	})
+
+	t.Run("relative paths are resolved against the working directory", func(t *testing.T) {
+		args, err := execute(t, tools[0], executor, map[string]any{
+			CwdParam:   "sub",
+			FlagsParam: map[string]any{"file": "config.yaml"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"apply", "--file=" + filepath.Join(root, "sub", "config.yaml")}, args)
+	})
+
+	t.Run("paths outside of the roots are rejected", func(t *testing.T) {
+		for _, path := range []string{"../../etc/shadow", filepath.Join(outside, "shadow"), "etc/shadow", "sub/../../x"} {
+			_, err := execute(t, tools[0], executor, map[string]any{FlagsParam: map[string]any{"file": path}})
+			require.Error(t, err, path)
+			assert.Contains(t, err.Error(), "outside of the allowed directories")
+		}
+	})
+
+	t.Run("dangling symlinks are rejected", func(t *testing.T) {
+		require.NoError(t, os.Symlink(filepath.Join(outside, "created"), filepath.Join(root, "dangling")))
+		for _, path := range []string{"dangling", "dangling/config.yaml"} {
+			_, err := execute(t, tools[0], executor, map[string]any{FlagsParam: map[string]any{"file": path}})
+			require.Error(t, err, path)
+			assert.Contains(t, err.Error(), "symlink that does not resolve")
+		}
+	})
+
+	t.Run("existence is checked if required", func(t *testing.T) {
+		strict := NewGenerator(WithExecutor(executor), WithPathRoots(root), RequireExistingPaths()).FromRootCmd(cli)
+		_, err := execute(t, strict[0], executor, map[string]any{FlagsParam: map[string]any{"file": "missing.yaml"}})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = execute(t, strict[0], executor, map[string]any{FlagsParam: map[string]any{"file": "sub/config.yaml"}})
+		require.NoError(t, err)
+	})
+
+	t.Run("paths are not checked without roots", func(t *testing.T) {
+		unchecked := NewGenerator(WithExecutor(executor)).FromRootCmd(cli)
+		args, err := execute(t, unchecked[0], executor, map[string]any{FlagsParam: map[string]any{"file": "../shadow"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"apply", "--file=../shadow"}, args)
+	})
+}
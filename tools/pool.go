@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrOverloaded is returned by Execute when every worker of the pool set by WithWorkerPool is
+// busy and its queue is full.
+var ErrOverloaded = errors.New("server overloaded")
+
+// WithWorkerPool returns a GeneratorOption that runs the commands of every generated tool on a
+// fixed number of workers, which take calls from a queue holding up to queueDepth calls. A call
+// arriving while the queue is full is rejected immediately with ErrOverloaded, rather than
+// waiting, so that the server pushes back under load. A zero queueDepth rejects every call that
+// finds no idle worker. The timeout of a call starts when a worker picks it up.
+//
+// It replaces WithMaxConcurrent and WithMaxQueue, whose calls wait for a free slot. Read the
+// state of the pool with Generator.PoolStats. A zero workers count means no pool.
+//
+//	Example: NewGenerator(WithWorkerPool(4, 16))
+func WithWorkerPool(workers, queueDepth int) GeneratorOption {
+	return func(g *Generator) {
+		g.workers = workers
+		g.workerQueue = queueDepth
+	}
+}
+
+// PoolStats is the state of the worker pool of a Generator, or of its concurrency limit.
+type PoolStats struct {
+	// Workers is the number of workers, or the concurrency limit. It is zero if the Generator
+	// has neither.
+	Workers int
+	// Busy is the number of workers running a command.
+	Busy int
+	// Queued is the number of calls waiting for a worker.
+	Queued int
+	// Rejected is the number of calls rejected with ErrOverloaded or ErrQueueFull since the
+	// Generator was created.
+	Rejected uint64
+}
+
+// PoolStats returns the state of the worker pool set by WithWorkerPool, or of the concurrency
+// limit set by WithMaxConcurrent, e.g. to export the queue length and the number of rejected
+// calls as metrics.
+func (g *Generator) PoolStats() PoolStats {
+	switch {
+	case g.pool != nil:
+		return g.pool.stats()
+	case g.limiter != nil:
+		return g.limiter.stats()
+	}
+
+	return PoolStats{}
+}
+
+// workerPool runs jobs on a fixed number of goroutines, which are started by the first job.
+// It is shared by every tool of a Generator.
+type workerPool struct {
+	workers  int
+	depth    int
+	jobs     chan *poolJob // holds the accepted jobs, and abandoned ones until a worker skips them
+	start    sync.Once
+	pending  atomic.Int64 // jobs accepted and neither done nor abandoned yet
+	busy     atomic.Int64
+	rejected atomic.Uint64
+}
+
+// poolJob is a call waiting for or running on a worker.
+type poolJob struct {
+	run  func()
+	done chan struct{}
+	// state is jobQueued until a worker takes the job, or the caller abandons it
+	state atomic.Int32
+}
+
+const (
+	jobQueued int32 = iota
+	jobRunning
+	jobAbandoned
+)
+
+// newWorkerPool returns a pool of workers with a queue of depth jobs, or nil if workers is
+// not positive.
+func newWorkerPool(workers, depth int) *workerPool {
+	if workers <= 0 {
+		return nil
+	}
+
+	depth = max(depth, 0)
+	return &workerPool{workers: workers, depth: depth, jobs: make(chan *poolJob, workers+depth)}
+}
+
+// run runs fn on a worker and waits for it to return. It returns ErrOverloaded if the queue is
+// full, or the error of ctx if it is cancelled before a worker took the call. Once fn runs, it
+// is waited for, so fn must respect the cancellation of ctx itself.
+func (p *workerPool) run(ctx context.Context, fn func()) error {
+	p.start.Do(func() {
+		for range p.workers {
+			go p.work()
+		}
+	})
+
+	if p.pending.Add(1) > int64(p.workers+p.depth) {
+		p.pending.Add(-1)
+		p.rejected.Add(1)
+		return fmt.Errorf("%w: %d commands running, %d queued", ErrOverloaded, p.workers, p.depth)
+	}
+
+	job := &poolJob{run: fn, done: make(chan struct{})}
+	// Sending only blocks while abandoned jobs fill the queue, until a worker is free to skip them
+	select {
+	case p.jobs <- job:
+	case <-ctx.Done():
+		p.pending.Add(-1)
+		return fmt.Errorf("cancelled while waiting to run: %w", ctx.Err())
+	}
+
+	select {
+	case <-job.done:
+		return nil
+	case <-ctx.Done():
+		if job.state.CompareAndSwap(jobQueued, jobAbandoned) {
+			// The job is skipped by the worker that takes it, so its slot is free right away
+			p.pending.Add(-1)
+			return fmt.Errorf("cancelled while waiting to run: %w", ctx.Err())
+		}
+		<-job.done
+		return nil
+	}
+}
+
+// work runs the jobs of the queue that were not abandoned. Abandoned jobs already released
+// their slot.
+func (p *workerPool) work() {
+	for job := range p.jobs {
+		if !job.state.CompareAndSwap(jobQueued, jobRunning) {
+			continue
+		}
+
+		p.busy.Add(1)
+		job.run()
+		p.busy.Add(-1)
+		// Free the slot before waking the caller, so that its next call is accepted
+		p.pending.Add(-1)
+		close(job.done)
+	}
+}
+
+func (p *workerPool) stats() PoolStats {
+	busy := p.busy.Load()
+	return PoolStats{
+		Workers:  p.workers,
+		Busy:     int(busy),
+		Queued:   int(max(p.pending.Load()-busy, 0)),
+		Rejected: p.rejected.Load(),
+	}
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorkerPool tests that calls beyond the workers and the queue are rejected immediately
+func TestWorkerPool(t *testing.T) {
+	executor := &blockingExecutor{started: make(chan struct{}, 4), release: make(chan struct{})}
+	g := NewGenerator(WithWorkerPool(1, 1), WithExecutor(executor), WithMaxConcurrent(8))
+	ctrl := &Controller{Tool: mcp.NewTool("cli_get"), executor: executor, pool: g.pool, limiter: g.limiter}
+	assert.Nil(t, g.limiter)
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		errs <- err
+	}()
+	<-executor.started
+	go func() {
+		_, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return g.PoolStats().Queued == 1 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, PoolStats{Workers: 1, Busy: 1, Queued: 1}, g.PoolStats())
+
+	_, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+	require.ErrorIs(t, err, ErrOverloaded)
+	assert.Contains(t, err.Error(), "server overloaded: 1 commands running, 1 queued")
+	assert.Equal(t, uint64(1), g.PoolStats().Rejected)
+
+	close(executor.release)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	// The workers are free again
+	go func() { <-executor.started }()
+	_, err = ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, PoolStats{Workers: 1, Rejected: 1}, g.PoolStats())
+}
+
+// TestWorkerPoolCancelled tests that a call cancelled while queued is never run
+func TestWorkerPoolCancelled(t *testing.T) {
+	pool := newWorkerPool(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = pool.run(context.Background(), func() {
+			close(started)
+			<-release
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	ran := false
+	go func() { errs <- pool.run(ctx, func() { ran = true }) }()
+	require.Eventually(t, func() bool { return pool.stats().Queued == 1 }, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	assert.Equal(t, PoolStats{Workers: 1, Busy: 1}, pool.stats(), "the abandoned call frees its slot")
+
+	// The slot is taken by the next call, before the worker skipped the abandoned one
+	next := make(chan error)
+	go func() { next <- pool.run(context.Background(), func() {}) }()
+	require.Eventually(t, func() bool { return pool.stats().Queued == 1 }, 5*time.Second, time.Millisecond)
+
+	close(release)
+	require.NoError(t, <-next)
+	require.Eventually(t, func() bool { return pool.stats() == PoolStats{Workers: 1} }, 5*time.Second, time.Millisecond)
+	assert.False(t, ran)
+
+	assert.Nil(t, newWorkerPool(0, 10))
+	assert.Equal(t, PoolStats{}, NewGenerator().PoolStats())
+}
@@ -0,0 +1,59 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// configureProcessGroup starts the command in its own session, and so in its own process group,
+// so that cancellation reaches every process it spawned, not just the direct child. The session
+// has no controlling terminal, so a command prompting on /dev/tty fails instead of waiting for
+// the terminal the server was started from.
+//
+// When the context is cancelled the group receives sig, or SIGTERM if it is nil. Any process
+// still running after the grace period is sent SIGKILL, even if the command itself exited
+// before, so that descendants ignoring the signal do not outlive it. A zero grace period sends
+// SIGKILL immediately.
+func configureProcessGroup(cmd *exec.Cmd, grace time.Duration, sig os.Signal) {
+	terminate, ok := sig.(syscall.Signal)
+	if !ok {
+		terminate = syscall.SIGTERM
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.Cancel = func() error {
+		// A negative PID signals the whole process group
+		pgid := -cmd.Process.Pid
+		if grace <= 0 {
+			return syscall.Kill(pgid, syscall.SIGKILL)
+		}
+
+		// The group ID is not reused while any member is alive, and a gone group is not found
+		time.AfterFunc(grace, func() {
+			_ = syscall.Kill(pgid, syscall.SIGKILL)
+		})
+		return syscall.Kill(pgid, terminate)
+	}
+
+	// Bound how long Wait blocks on pipes held open by orphaned descendants
+	cmd.WaitDelay = grace + killWaitDelay
+}
+
+// maxRSS returns the maximum resident set size of an exited process in bytes. The kernel
+// reports it in kilobytes, except on macOS.
+func maxRSS(state *os.ProcessState) int64 {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(rusage.Maxrss)
+	}
+	return int64(rusage.Maxrss) * 1024
+}
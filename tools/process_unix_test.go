@@ -0,0 +1,106 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProcessGroupKill tests that descendants of a timed-out command are killed too
+func TestProcessGroupKill(t *testing.T) {
+	ctrl := helperController(t, "spawn")
+	ctrl.Timeout = 500 * time.Millisecond
+
+	result, err := ctrl.Execute(context.Background(), helperRequest(""))
+	require.ErrorIs(t, err, ErrTimeout)
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(result.Stdout)))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return processGone(pid) }, 5*time.Second, 50*time.Millisecond,
+		"grandchild process %d should be killed with its process group", pid)
+}
+
+// TestGracePeriodDescendants tests that descendants ignoring SIGTERM are killed once the grace
+// period ends, even though the command exited before and released its output
+func TestGracePeriodDescendants(t *testing.T) {
+	ctrl := helperController(t, "spawn-detached")
+	ctrl.Timeout = 300 * time.Millisecond
+	ctrl.executor = &DefaultExecutor{GracePeriod: 300 * time.Millisecond}
+
+	result, err := ctrl.Execute(context.Background(), helperRequest(""))
+	require.ErrorIs(t, err, ErrTimeout)
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(result.Stdout)))
+	require.NoError(t, err)
+	assert.False(t, processGone(pid), "the grandchild survives SIGTERM")
+
+	assert.Eventually(t, func() bool { return processGone(pid) }, 5*time.Second, 50*time.Millisecond,
+		"grandchild process %d should be killed after the grace period", pid)
+}
+
+// TestGracePeriod tests that a command ignoring SIGTERM is killed once the grace period ends
+func TestGracePeriod(t *testing.T) {
+	ctrl := helperController(t, "ignore-term")
+	ctrl.Timeout = 300 * time.Millisecond
+	ctrl.executor = &DefaultExecutor{GracePeriod: 300 * time.Millisecond}
+
+	start := time.Now()
+	result, err := ctrl.Execute(context.Background(), helperRequest(""))
+	elapsed := time.Since(start)
+
+	require.ErrorIs(t, err, ErrTimeout)
+	assert.True(t, result.Killed)
+	assert.GreaterOrEqual(t, elapsed, 600*time.Millisecond)
+	assert.Less(t, elapsed, 5*time.Second)
+}
+
+// TestTerminationSignal tests that cancelled commands are sent the configured signal
+func TestTerminationSignal(t *testing.T) {
+	ctrl := helperController(t, "trap-int")
+	ctrl.Timeout = 300 * time.Millisecond
+	ctrl.executor = &DefaultExecutor{GracePeriod: 5 * time.Second, TerminationSignal: os.Interrupt}
+
+	start := time.Now()
+	result, err := ctrl.Execute(context.Background(), helperRequest(""))
+	elapsed := time.Since(start)
+
+	require.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, "started\ncleaned up\n", string(result.Stdout))
+	assert.False(t, result.Killed, "the command exits on SIGINT within the grace period")
+	assert.Less(t, elapsed, 3*time.Second)
+}
+
+// TestNoTerminal tests that prompting commands see EOF on stdin and cannot open a terminal
+func TestNoTerminal(t *testing.T) {
+	ctrl := helperController(t, "prompt")
+	ctrl.Timeout = 5 * time.Second
+
+	result, err := ctrl.Execute(context.Background(), helperRequest(""))
+	require.Error(t, err)
+	assert.Equal(t, "stdin: EOF\ntty: unavailable\n", string(result.Stdout))
+}
+
+// processGone reports whether pid no longer refers to a running process.
+// Zombies count as gone, since an orphan may not be reaped promptly in a container.
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(stat), ") Z ")
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// configureProcessGroup terminates the whole process tree of the command when
+// the context is cancelled, using taskkill /T.
+//
+// Windows has no SIGTERM equivalent for console processes, so taskkill is first
+// asked to close the tree politely and, if anything is still running after the
+// grace period, to force it closed, even if the command itself exited before. A
+// zero grace period forces it immediately. The termination signal is ignored, since
+// Windows cannot send signals to other processes.
+func configureProcessGroup(cmd *exec.Cmd, grace time.Duration, _ os.Signal) {
+	cmd.Cancel = func() error {
+		pid := strconv.Itoa(cmd.Process.Pid)
+		if grace <= 0 {
+			return exec.Command("taskkill", "/T", "/F", "/PID", pid).Run()
+		}
+
+		time.AfterFunc(grace, func() {
+			_ = exec.Command("taskkill", "/T", "/F", "/PID", pid).Run()
+		})
+		return exec.Command("taskkill", "/T", "/PID", pid).Run()
+	}
+
+	// Bound how long Wait blocks on pipes held open by orphaned descendants
+	cmd.WaitDelay = grace + killWaitDelay
+}
+
+// maxRSS returns zero, since Windows does not report the memory usage of exited processes.
+func maxRSS(*os.ProcessState) int64 {
+	return 0
+}
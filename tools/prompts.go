@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/pflag"
+)
+
+// ExamplePrompts returns an MCP prompt for each of the given tools whose command has an
+// Example, named after the tool. A prompt asks the model to run the examples of the command,
+// with the tool arguments of every example invocation pre-filled, so that a client's prompt
+// picker can offer them. Aliases of commands get no prompt of their own.
+//
+// Example lines are parsed with shell quoting rules, after stripping a leading "$ " prompt, and
+// must contain the command path, e.g. "kubectl get pods -o json" for "kubectl get". Lines
+// starting with "#" are kept as descriptions of the examples, and lines with flags the tool
+// does not accept are shown to the model without arguments.
+func ExamplePrompts(ctrls []Controller) []server.ServerPrompt {
+	var prompts []server.ServerPrompt
+	for i := range ctrls {
+		ctrl := &ctrls[i]
+		if ctrl.alias || strings.TrimSpace(ctrl.example) == "" {
+			continue
+		}
+
+		prompts = append(prompts, ctrl.examplePrompt())
+	}
+
+	return prompts
+}
+
+// examplePrompt returns the prompt that runs the examples of c.
+func (c *Controller) examplePrompt() server.ServerPrompt {
+	command := strings.Join(c.path, " ")
+	description := fmt.Sprintf("Run the examples of the %q command", command)
+	if c.short != "" {
+		description += ": " + c.short
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Run the examples of the %q command with the %s tool.\n", command, c.Tool.Name)
+	for _, line := range strings.Split(c.example, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			// Comments usually describe the next example
+			fmt.Fprintf(&text, "\n%s\n", strings.TrimSpace(strings.TrimLeft(line, "#")))
+			continue
+		}
+
+		fmt.Fprintf(&text, "\nExample:\n```\n%s\n```\n", line)
+		if arguments, ok := c.exampleArguments(line); ok {
+			data, _ := json.Marshal(arguments)
+			fmt.Fprintf(&text, "Tool arguments:\n```json\n%s\n```\n", data)
+		}
+	}
+
+	result := mcp.NewGetPromptResult(description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text.String())),
+	})
+
+	return server.ServerPrompt{
+		Prompt: mcp.NewPrompt(c.Tool.Name, mcp.WithPromptDescription(description)),
+		Handler: func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return result, nil
+		},
+	}
+}
+
+// exampleArguments returns the tool arguments of an example invocation of the command, and
+// false if the line is not one.
+func (c *Controller) exampleArguments(line string) (map[string]any, bool) {
+	words, err := splitArgumentString(strings.TrimPrefix(strings.TrimSpace(line), "$ "))
+	if err != nil {
+		return nil, false
+	}
+
+	// Skip the root command, and anything else in front of the command path
+	start := commandPathIndex(words, c.path)
+	if start < 0 {
+		return nil, false
+	}
+
+	flags := c.flags
+	if flags == nil {
+		flags = pflag.NewFlagSet("", pflag.ContinueOnError)
+	}
+
+	flagMap, args, ok := parseExampleArgs(words[start+len(c.path):], flags, c.injected)
+	if !ok {
+		return nil, false
+	}
+
+	arguments := map[string]any{}
+	if len(flagMap) > 0 {
+		arguments[FlagsParam] = flagMap
+	}
+	if len(args) > 0 {
+		arguments[PositionalArgsParam] = args
+	}
+
+	return arguments, true
+}
+
+// commandPathIndex returns the index at which path starts in words, or -1 if it does not.
+func commandPathIndex(words, path []string) int {
+	for i := 0; i+len(path) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(path)], path) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseExampleArgs splits the words after the command path into flags, keyed by their long
+// names, and positional arguments. Injected flags are dropped, since they are always set. It
+// returns false if a flag is not in flags, or is missing its value.
+func parseExampleArgs(words []string, flags *pflag.FlagSet, injected map[string]string) (map[string]any, []string, bool) {
+	flagMap := map[string]any{}
+	var args []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case word == "--":
+			return flagMap, append(args, words[i+1:]...), true
+		case !strings.HasPrefix(word, "-") || word == "-":
+			args = append(args, word)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		if name == "" {
+			return nil, nil, false
+		}
+
+		var flag *pflag.Flag
+		if strings.HasPrefix(word, "--") {
+			flag = flags.Lookup(name)
+		} else if flag = flags.ShorthandLookup(name[:1]); flag != nil && len(name) > 1 && !hasValue {
+			if isCountFlag(flag) && strings.Count(name, name[:1]) == len(name) {
+				// A repeated count shorthand, e.g. -vvv
+				if _, ok := injected[flag.Name]; !ok {
+					addExampleCount(flagMap, flag, len(name))
+				}
+				continue
+			}
+			if flag.NoOptDefVal != "" {
+				// Combined shorthands, e.g. -rf, are not mapped
+				return nil, nil, false
+			}
+			// A shorthand with its value attached, e.g. -ojson
+			value, hasValue = name[1:], true
+		}
+		if flag == nil {
+			return nil, nil, false
+		}
+
+		switch {
+		case hasValue:
+		case flag.NoOptDefVal != "":
+			value = flag.NoOptDefVal
+		case i+1 < len(words):
+			i++
+			value = words[i]
+		default:
+			return nil, nil, false
+		}
+
+		if _, ok := injected[flag.Name]; !ok {
+			addExampleFlag(flagMap, flag, value)
+		}
+	}
+
+	return flagMap, args, true
+}
+
+// addExampleFlag sets the value of a flag in flagMap. Boolean flags get a boolean, and repeated
+// flags an array of their values.
+func addExampleFlag(flagMap map[string]any, flag *pflag.Flag, value string) {
+	if flag.Value.Type() == "bool" {
+		flagMap[flag.Name] = value != "false"
+		return
+	}
+	if isCountFlag(flag) {
+		// Like pflag, "+1" increments the count and a number replaces it
+		if count, err := strconv.Atoi(value); err == nil && value != "+1" {
+			flagMap[flag.Name] = count
+		} else {
+			addExampleCount(flagMap, flag, 1)
+		}
+		return
+	}
+
+	switch existing := flagMap[flag.Name].(type) {
+	case nil:
+		flagMap[flag.Name] = value
+	case []any:
+		flagMap[flag.Name] = append(existing, value)
+	default:
+		flagMap[flag.Name] = []any{existing, value}
+	}
+}
+
+// addExampleCount adds n to the value of a count flag in flagMap.
+func addExampleCount(flagMap map[string]any, flag *pflag.Flag, n int) {
+	count, _ := flagMap[flag.Name].(int)
+	flagMap[flag.Name] = count + n
+}
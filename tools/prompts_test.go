@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExamplePrompts tests that command examples become prompts with pre-filled tool arguments
+func TestExamplePrompts(t *testing.T) {
+	root := &cobra.Command{Use: "kubectl"}
+	get := &cobra.Command{Use: "get", Short: "Display resources", Aliases: []string{"g"}, Run: func(*cobra.Command, []string) {},
+		Example: `  # List all pods in JSON
+  $ kubectl get pods -o json --all-namespaces
+
+  kubectl get pods "my pod" --selector=app=web -l tier=db -ojson
+  kubectl get pods --bogus`}
+	get.Flags().StringP("output", "o", "", "Output format")
+	get.Flags().StringArrayP("selector", "l", nil, "Label selector")
+	get.Flags().BoolP("all-namespaces", "A", false, "All namespaces")
+	root.AddCommand(get, &cobra.Command{Use: "version", Run: func(*cobra.Command, []string) {}})
+
+	ctrls, err := NewGenerator(WithAliases()).Generate(root)
+	require.NoError(t, err)
+	require.Len(t, ctrls, 3)
+
+	prompts := ExamplePrompts(ctrls)
+	require.Len(t, prompts, 1, "commands without examples and aliases get no prompt")
+	assert.Equal(t, "kubectl_get", prompts[0].Prompt.Name)
+	assert.Equal(t, `Run the examples of the "get" command: Display resources`, prompts[0].Prompt.Description)
+
+	result, err := prompts[0].Handler(context.Background(), mcp.GetPromptRequest{})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, mcp.RoleUser, result.Messages[0].Role)
+	assert.Equal(t, `Run the examples of the "get" command with the kubectl_get tool.
+
+List all pods in JSON
+
+Example:
+`+"```"+`
+$ kubectl get pods -o json --all-namespaces
+`+"```"+`
+Tool arguments:
+`+"```json"+`
+{"args":["pods"],"flags":{"all-namespaces":true,"output":"json"}}
+`+"```"+`
+
+Example:
+`+"```"+`
+kubectl get pods "my pod" --selector=app=web -l tier=db -ojson
+`+"```"+`
+Tool arguments:
+`+"```json"+`
+{"args":["pods","my pod"],"flags":{"output":"json","selector":["app=web","tier=db"]}}
+`+"```"+`
+
+Example:
+`+"```"+`
+kubectl get pods --bogus
+`+"```"+`
+`, result.Messages[0].Content.(mcp.TextContent).Text)
+}
+
+// TestParseExampleArgs tests the mapping of example invocations to tool arguments
+func TestParseExampleArgs(t *testing.T) {
+	cmd := &cobra.Command{Use: "run"}
+	cmd.Flags().StringP("image", "i", "", "Image")
+	cmd.Flags().BoolP("rm", "r", false, "Remove")
+	cmd.Flags().BoolP("force", "f", false, "Force")
+	cmd.Flags().String("output", "", "Output")
+	cmd.Flags().CountP("verbose", "v", "Verbosity")
+	ctrl := &Controller{path: []string{"run"}, flags: cmd.Flags(), injected: map[string]string{"output": "json"}}
+
+	tests := map[string]struct {
+		line     string
+		expected map[string]any
+		ok       bool
+	}{
+		"separator":     {"cli run -i alpine -- ls -la", map[string]any{FlagsParam: map[string]any{"image": "alpine"}, PositionalArgsParam: []string{"ls", "-la"}}, true},
+		"bool value":    {"cli run --rm=false -i=alpine", map[string]any{FlagsParam: map[string]any{"rm": false, "image": "alpine"}}, true},
+		"injected flag": {"cli run --output yaml pods", map[string]any{PositionalArgsParam: []string{"pods"}}, true},
+		"no arguments":  {"cli run", map[string]any{}, true},
+		"other command": {"cli build -i alpine", nil, false},
+		"missing value": {"cli run -i", nil, false},
+		"combined":      {"cli run -rf", nil, false},
+		"count":         {"cli run -vv --verbose", map[string]any{FlagsParam: map[string]any{"verbose": 3}}, true},
+		"count value":   {"cli run --verbose=2", map[string]any{FlagsParam: map[string]any{"verbose": 2}}, true},
+		"malformed":     {"cli run 'alpine", nil, false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			arguments, ok := ctrl.exampleArguments(tt.line)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, arguments)
+		})
+	}
+}
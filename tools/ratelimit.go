@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MetaRetryAfter holds the number of seconds after which a rate limited tool call may succeed.
+const MetaRetryAfter = "retryAfter"
+
+// RateLimit is a token bucket limit: calls are allowed at Rate per second on average, with
+// bursts of up to Burst calls. A zero Rate means no limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter returns middleware that limits how often each tool may be called. Tools are
+// looked up by name in limits, and use defaultLimit otherwise; every tool has its own bucket.
+// No tool is limited by default.
+//
+// Calls beyond the limit are not executed. They return an error result stating when to retry,
+// with the number of seconds in the MetaRetryAfter metadata.
+//
+//	Example: ophis.Config{Middleware: []server.ToolHandlerMiddleware{
+//		tools.RateLimiter(tools.RateLimit{Rate: 10, Burst: 20}, map[string]tools.RateLimit{
+//			"cli_build": {Rate: 1.0 / 60, Burst: 1}, // one build a minute
+//		}),
+//	}}
+func RateLimiter(defaultLimit RateLimit, limits map[string]RateLimit) server.ToolHandlerMiddleware {
+	return newRateLimiter(defaultLimit, limits, time.Now).middleware
+}
+
+// rateLimiter holds the token buckets of the tools.
+type rateLimiter struct {
+	defaultLimit RateLimit
+	limits       map[string]RateLimit
+	now          func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is the token bucket of a single tool.
+type bucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(defaultLimit RateLimit, limits map[string]RateLimit, now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		defaultLimit: defaultLimit,
+		limits:       limits,
+		now:          now,
+		buckets:      map[string]*bucket{},
+	}
+}
+
+func (l *rateLimiter) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.Params.Name
+		if wait, ok := l.allow(name); !ok {
+			result := mcp.NewToolResultError(fmt.Sprintf("rate limit exceeded for tool %q, retry in %s", name, wait.Round(time.Millisecond)))
+			addMeta(result, map[string]any{MetaRetryAfter: wait.Seconds()})
+			return result, nil
+		}
+
+		return next(ctx, request)
+	}
+}
+
+// allow takes a token from the bucket of a tool. Without a token, it returns how long it
+// takes until one is available.
+func (l *rateLimiter) allow(tool string) (time.Duration, bool) {
+	limit, ok := l.limits[tool]
+	if !ok {
+		limit = l.defaultLimit
+	}
+	if limit.Rate <= 0 {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[tool]
+	if !ok {
+		b = &bucket{limit: limit, tokens: limit.burst(), last: now}
+		l.buckets[tool] = b
+	}
+
+	b.tokens = math.Min(b.limit.burst(), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second)), false
+}
+
+// burst returns the capacity of the bucket, which holds at least one call.
+func (r RateLimit) burst() float64 {
+	return math.Max(1, float64(r.Burst))
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRateLimiter tests the token buckets of tools
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter(RateLimit{Rate: 1, Burst: 2}, map[string]RateLimit{
+		"slow":      {Rate: 0.5},
+		"unlimited": {},
+	}, func() time.Time { return now })
+
+	// The default burst is available immediately, then calls refill at the rate
+	for range 2 {
+		_, ok := limiter.allow("fast")
+		assert.True(t, ok)
+	}
+	wait, ok := limiter.allow("fast")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	now = now.Add(time.Second)
+	_, ok = limiter.allow("fast")
+	assert.True(t, ok)
+
+	// Tools have separate buckets, and a zero Burst holds a single call
+	_, ok = limiter.allow("slow")
+	assert.True(t, ok)
+	wait, ok = limiter.allow("slow")
+	assert.False(t, ok)
+	assert.Equal(t, 2*time.Second, wait)
+
+	for range 100 {
+		_, ok = limiter.allow("unlimited")
+		assert.True(t, ok)
+	}
+}
+
+// TestRateLimiterMiddleware tests that limited calls are not executed and carry a retry hint
+func TestRateLimiterMiddleware(t *testing.T) {
+	calls := 0
+	handler := RateLimiter(RateLimit{}, map[string]RateLimit{"cli_build": {Rate: 0.1}})(
+		func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			calls++
+			return mcp.NewToolResultText("built"), nil
+		},
+	)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "cli_build"
+
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	result, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "rate limit exceeded")
+	assert.InDelta(t, 10, result.Meta.AdditionalFields[MetaRetryAfter], 0.1)
+	assert.Equal(t, 1, calls)
+
+	request.Params.Name = "cli_get"
+	for range 5 {
+		result, err = handler(context.Background(), request)
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+	}
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Redacted replaces the values of sensitive flags in log lines.
+const Redacted = "***"
+
+// DefaultSensitiveFlagPattern matches the flag names whose values are redacted by default,
+// e.g. "password", "api-token", "client-secret" and "ssh-key".
+var DefaultSensitiveFlagPattern = regexp.MustCompile(`(?i)passw(or)?d|token|secret|key`)
+
+// sensitiveFlags selects the flags whose values are never logged.
+// The values are still passed to the command.
+type sensitiveFlags struct {
+	names   []string       // long flag names marked explicitly
+	pattern *regexp.Regexp // flag names matching the pattern, nil for none
+}
+
+// defaultSensitiveFlags is used by controllers that were not built by a Generator.
+var defaultSensitiveFlags = &sensitiveFlags{pattern: DefaultSensitiveFlagPattern}
+
+// WithSensitiveFlags returns a GeneratorOption that marks flags as sensitive by their long
+// name, in addition to those matching the sensitive flag pattern. The values of sensitive
+// flags are replaced with Redacted in every log line, but still passed to the command.
+// It can be used multiple times, and the names accumulate.
+//
+//	Example: NewGenerator(WithSensitiveFlags("dsn", "auth"))
+func WithSensitiveFlags(names ...string) GeneratorOption {
+	return func(g *Generator) {
+		g.sensitive.names = append(g.sensitive.names, names...)
+	}
+}
+
+// WithSensitiveFlagPattern returns a GeneratorOption that replaces DefaultSensitiveFlagPattern
+// as the pattern of sensitive flag names. A nil pattern only treats the flags named with
+// WithSensitiveFlags as sensitive.
+//
+//	Example: NewGenerator(WithSensitiveFlagPattern(regexp.MustCompile(`(?i)password|credential`)))
+func WithSensitiveFlagPattern(pattern *regexp.Regexp) GeneratorOption {
+	return func(g *Generator) {
+		g.sensitive.pattern = pattern
+	}
+}
+
+// matches reports whether the value of the named flag must not be logged.
+// A nil sensitiveFlags applies the defaults.
+func (s *sensitiveFlags) matches(name string) bool {
+	if s == nil {
+		s = defaultSensitiveFlags
+	}
+
+	name = strings.TrimLeft(name, "-")
+	return slices.Contains(s.names, name) || (s.pattern != nil && s.pattern.MatchString(name))
+}
+
+// value returns the flag value for logging.
+func (s *sensitiveFlags) value(name string, value any) any {
+	if s.matches(name) {
+		return Redacted
+	}
+
+	return value
+}
+
+// args returns a copy of command line arguments for logging, with the values of sensitive
+// flags in the --name=value form replaced. Positional arguments after "--" are kept as is.
+func (s *sensitiveFlags) args(args []string) []string {
+	redacted := slices.Clone(args)
+	for i, arg := range redacted {
+		if arg == "--" {
+			break
+		}
+
+		name, _, ok := strings.Cut(arg, "=")
+		if ok && strings.HasPrefix(name, "--") && s.matches(name) {
+			redacted[i] = name + "=" + Redacted
+		}
+	}
+
+	return redacted
+}
+
+// RedactedArguments returns a copy of the request arguments for logging, with the values of
+// sensitive flags replaced with Redacted.
+func (c *Controller) RedactedArguments(request mcp.CallToolRequest) map[string]any {
+	arguments := maps.Clone(request.GetArguments())
+	flagMap, ok := arguments[FlagsParam].(map[string]any)
+	if !ok {
+		return arguments
+	}
+
+	redacted := make(map[string]any, len(flagMap))
+	for name, value := range flagMap {
+		// Shorthands are redacted if their long flag is sensitive
+		longName := strings.TrimLeft(name, "-")
+		if c.flags != nil && len(longName) == 1 {
+			if flag := c.flags.ShorthandLookup(longName); flag != nil {
+				longName = flag.Name
+			}
+		}
+		redacted[name] = c.sensitive.value(longName, value)
+	}
+	arguments[FlagsParam] = redacted
+
+	return arguments
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSensitiveFlags tests which flag names are redacted
+func TestSensitiveFlags(t *testing.T) {
+	var defaults *sensitiveFlags
+	for _, name := range []string{"password", "db-passwd", "api-token", "client-secret", "ssh-key", "--PASSWORD"} {
+		assert.True(t, defaults.matches(name), name)
+	}
+	for _, name := range []string{"output", "verbose", "namespace"} {
+		assert.False(t, defaults.matches(name), name)
+	}
+
+	custom := &sensitiveFlags{names: []string{"dsn"}}
+	assert.True(t, custom.matches("dsn"))
+	assert.False(t, custom.matches("password"))
+
+	assert.Equal(t,
+		[]string{"get", "--token=***", "--output=json", "--verbose", "--", "--token=literal"},
+		defaults.args([]string{"get", "--token=abc", "--output=json", "--verbose", "--", "--token=literal"}),
+	)
+}
+
+// TestBuildFlagArgsRedaction tests that sensitive values are passed to the command but never logged
+func TestBuildFlagArgsRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	args, err := buildFlagArgs(logger, map[string]any{
+		"password": "hunter2",
+		"api-key":  []any{"k1", "k2"},
+		"output":   "json",
+	}, nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"--api-key=k1", "--api-key=k2", "--output=json", "--password=hunter2"}, args)
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.NotContains(t, buf.String(), "k1")
+	assert.Contains(t, buf.String(), Redacted)
+	assert.Contains(t, buf.String(), "json")
+}
+
+// TestSensitiveFlagOptions tests that the generator options configure redaction of every tool
+func TestSensitiveFlagOptions(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	login := &cobra.Command{Use: "login", Run: func(*cobra.Command, []string) {}}
+	login.Flags().StringP("auth", "a", "", "credentials")
+	login.Flags().String("password", "", "password")
+	root.AddCommand(login)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	executor := &recordingExecutor{result: &ExecResult{}}
+	tools := NewGenerator(
+		WithLogger(logger),
+		WithExecutor(executor),
+		WithSensitiveFlags("auth"),
+		WithSensitiveFlagPattern(nil),
+	).FromRootCmd(root)
+	require.Len(t, tools, 1)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		FlagsParam: map[string]any{"a": "s3cr3t", "password": "visible"},
+	}
+
+	_, err := tools[0].Execute(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"login", "--auth=s3cr3t", "--password=visible"}, executor.invocations[0].Args)
+	assert.NotContains(t, buf.String(), "s3cr3t")
+	assert.Contains(t, buf.String(), "visible")
+
+	assert.Equal(t,
+		map[string]any{FlagsParam: map[string]any{"a": Redacted, "password": "visible"}},
+		tools[0].RedactedArguments(request),
+	)
+	assert.Equal(t, "s3cr3t", request.GetArguments()[FlagsParam].(map[string]any)["a"])
+
+	gen := NewGenerator(WithSensitiveFlagPattern(regexp.MustCompile("^auth$")))
+	assert.True(t, gen.sensitive.matches("auth"))
+	assert.False(t, gen.sensitive.matches("password"))
+}
+
+// TestRedactedArgumentsDefaults tests redaction by a controller without generator configuration
+func TestRedactedArgumentsDefaults(t *testing.T) {
+	ctrl := &Controller{flags: pflag.NewFlagSet("test", pflag.ContinueOnError)}
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		PositionalArgsParam: "a b",
+		FlagsParam:          map[string]any{"token": "abc", "verbose": true},
+	}
+
+	assert.Equal(t, map[string]any{
+		PositionalArgsParam: "a b",
+		FlagsParam:          map[string]any{"token": Redacted, "verbose": true},
+	}, ctrl.RedactedArguments(request))
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RequestValue selects metadata of a tool call that WithRequestEnv passes to the command.
+type RequestValue string
+
+// Metadata of a tool call. Values that are unknown for a call, e.g. the progress token of a
+// request without one, are not set.
+const (
+	// RequestSessionID is the ID of the MCP client session.
+	RequestSessionID RequestValue = "session_id"
+	// RequestToolName is the name of the called tool.
+	RequestToolName RequestValue = "tool_name"
+	// RequestProgressToken is the progress token the client sent with the request.
+	RequestProgressToken RequestValue = "progress_token"
+	// RequestClientName and RequestClientVersion are the name and version the client reported.
+	RequestClientName    RequestValue = "client_name"
+	RequestClientVersion RequestValue = "client_version"
+)
+
+// defaultRequestEnv holds the variables set by WithRequestEnv(nil).
+var defaultRequestEnv = map[string]RequestValue{
+	"OPHIS_SESSION_ID":     RequestSessionID,
+	"OPHIS_TOOL_NAME":      RequestToolName,
+	"OPHIS_PROGRESS_TOKEN": RequestProgressToken,
+	"OPHIS_CLIENT_NAME":    RequestClientName,
+	"OPHIS_CLIENT_VERSION": RequestClientVersion,
+}
+
+// WithRequestEnv returns a GeneratorOption that passes metadata of each tool call to the
+// command as environment variables, keyed by variable name, so that commands can correlate
+// their own logs and telemetry with the conversation that triggered them. A nil env sets every
+// value, as OPHIS_SESSION_ID, OPHIS_TOOL_NAME, OPHIS_PROGRESS_TOKEN, OPHIS_CLIENT_NAME and
+// OPHIS_CLIENT_VERSION. The variables take precedence over all others.
+//
+//	Example: NewGenerator(WithRequestEnv(map[string]RequestValue{"MYCLI_TRACE_SESSION": RequestSessionID}))
+func WithRequestEnv(env map[string]RequestValue) GeneratorOption {
+	return func(g *Generator) {
+		if env == nil {
+			env = defaultRequestEnv
+		}
+		g.requestEnv = maps.Clone(env)
+	}
+}
+
+// requestEnviron returns the environment variables of the request metadata in key=value form.
+func (c *Controller) requestEnviron(ctx context.Context, request mcp.CallToolRequest) []string {
+	if len(c.requestEnv) == 0 {
+		return nil
+	}
+
+	var env []string
+	for _, name := range slices.Sorted(maps.Keys(c.requestEnv)) {
+		if value := c.requestValue(ctx, request, c.requestEnv[name]); value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	return env
+}
+
+// requestValue returns the metadata of a tool call selected by value, or empty if it is unknown.
+func (c *Controller) requestValue(ctx context.Context, request mcp.CallToolRequest, value RequestValue) string {
+	session := server.ClientSessionFromContext(ctx)
+	var client mcp.Implementation
+	if info, ok := session.(server.SessionWithClientInfo); ok {
+		client = info.GetClientInfo()
+	}
+
+	switch value {
+	case RequestSessionID:
+		if session != nil {
+			return session.SessionID()
+		}
+	case RequestToolName:
+		return c.Tool.Name
+	case RequestProgressToken:
+		if request.Params.Meta != nil && request.Params.Meta.ProgressToken != nil {
+			return fmt.Sprint(request.Params.Meta.ProgressToken)
+		}
+	case RequestClientName:
+		return client.Name
+	case RequestClientVersion:
+		return client.Version
+	}
+
+	return ""
+}
+
+// keyEnviron returns env without the variables that differ between otherwise identical calls,
+// the progress token and the trace context, for the keys of cached and coalesced calls. Other
+// request metadata, such as the session ID, is kept, so that calls of different sessions never
+// share an output.
+func (c *Controller) keyEnviron(env []string) []string {
+	return slices.DeleteFunc(slices.Clone(env), func(entry string) bool {
+		name, _, _ := strings.Cut(entry, "=")
+		switch name {
+		case "TRACEPARENT", "TRACESTATE":
+			return c.tracer != nil
+		}
+		return c.requestEnv[name] == RequestProgressToken
+	})
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clientInfoSession is a client session that reports client info.
+type clientInfoSession struct {
+	*testSession
+	info mcp.Implementation
+}
+
+func (s *clientInfoSession) GetClientInfo() mcp.Implementation     { return s.info }
+func (s *clientInfoSession) SetClientInfo(info mcp.Implementation) { s.info = info }
+func (s *clientInfoSession) GetClientCapabilities() mcp.ClientCapabilities {
+	return mcp.ClientCapabilities{}
+}
+func (s *clientInfoSession) SetClientCapabilities(mcp.ClientCapabilities) {}
+
+// TestWithRequestEnv tests that the metadata of a tool call reaches the command environment
+func TestWithRequestEnv(t *testing.T) {
+	session := &clientInfoSession{testSession: newTestSession(), info: mcp.Implementation{Name: "claude", Version: "1.2.3"}}
+	ctx := server.NewMCPServer("test", "1.0.0").WithContext(context.Background(), session)
+	request := helperRequest("")
+	request.Params.Meta = &mcp.Meta{ProgressToken: 7}
+
+	ctrl := helperController(t, "env")
+	ctrl.requestEnv = defaultRequestEnv
+	result, err := ctrl.Execute(ctx, request)
+	require.NoError(t, err)
+	env := strings.Split(strings.TrimSpace(string(result.Stdout)), "\n")
+	assert.Subset(t, env, []string{
+		"OPHIS_SESSION_ID=test",
+		"OPHIS_TOOL_NAME=helper_env",
+		"OPHIS_PROGRESS_TOKEN=7",
+		"OPHIS_CLIENT_NAME=claude",
+		"OPHIS_CLIENT_VERSION=1.2.3",
+	})
+
+	// Unknown values are not set, and the request metadata replaces variables of the same name
+	ctrl.requestEnv = map[string]RequestValue{"TRACE_SESSION": RequestSessionID, "TRACE_TOKEN": RequestProgressToken}
+	ctrl.Env["TRACE_SESSION"] = "static"
+	result, err = ctrl.Execute(ctx, helperRequest(""))
+	require.NoError(t, err)
+	env = strings.Split(strings.TrimSpace(string(result.Stdout)), "\n")
+	assert.Contains(t, env, "TRACE_SESSION=test")
+	assert.NotContains(t, env, "TRACE_SESSION=static")
+	assert.NotContains(t, string(result.Stdout), "TRACE_TOKEN")
+}
+
+// TestRequestEnvOption tests that request metadata is only passed if enabled
+func TestRequestEnvOption(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+	tests := map[string]struct {
+		opts     []GeneratorOption
+		expected []string
+	}{
+		"disabled": {},
+		"defaults": {
+			opts:     []GeneratorOption{WithRequestEnv(nil)},
+			expected: []string{"OPHIS_TOOL_NAME=cli_get"},
+		},
+		"selected": {
+			opts:     []GeneratorOption{WithRequestEnv(map[string]RequestValue{"TOOL": RequestToolName})},
+			expected: []string{"TOOL=cli_get"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			executor := &recordingExecutor{result: &ExecResult{}}
+			tools, err := NewGenerator(append(tt.opts, WithExecutor(executor))...).Generate(root)
+			require.NoError(t, err)
+			require.Len(t, tools, 1)
+
+			_, err = tools[0].Execute(context.Background(), mcp.CallToolRequest{})
+			require.NoError(t, err)
+			require.Len(t, executor.invocations, 1)
+			assert.Equal(t, append(slices.Clone(defaultMachineEnv), tt.expected...), executor.invocations[0].Env)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"os"
+	"time"
+)
+
+// MetaUsage holds the resource usage of an execution, for tools of a Generator configured with
+// WithResourceUsage. It is a map with the keys of the ResourceUsage fields that were measured:
+// "wallSeconds", "userSeconds", "systemSeconds" and "maxRssBytes".
+const MetaUsage = "usage"
+
+// ResourceUsage is the CPU and memory cost of running a command. The wall-clock time is in
+// ExecResult.Duration.
+type ResourceUsage struct {
+	// UserTime and SystemTime are the CPU time spent by the process in user and kernel mode.
+	UserTime   time.Duration
+	SystemTime time.Duration
+	// MaxRSS is the maximum resident set size of the process in bytes, or zero if the platform
+	// does not report it, e.g. on Windows.
+	MaxRSS int64
+}
+
+// WithResourceUsage returns a GeneratorOption that reports the resource usage of every
+// execution in the MetaUsage metadata of the tool result, which helps clients understand the
+// cost of tools and spot runaway commands. The DefaultExecutor measures the CPU time and, where
+// the platform reports it, the peak memory of the process. For other executors that do not
+// set ExecResult.Usage, only the wall-clock duration of the run is reported.
+func WithResourceUsage() GeneratorOption {
+	return func(g *Generator) {
+		g.resourceUsage = true
+	}
+}
+
+// processUsage returns the resource usage of an exited process.
+func processUsage(state *os.ProcessState) *ResourceUsage {
+	return &ResourceUsage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+		MaxRSS:     maxRSS(state),
+	}
+}
+
+// usageMeta returns the resource usage metadata of an execution, omitting what was not
+// measured. Dry runs and cached output have none.
+func (r *ExecResult) usageMeta() map[string]any {
+	if !r.started() || r.DryRun || r.Cached {
+		return nil
+	}
+
+	usage := map[string]any{"wallSeconds": r.Duration.Seconds()}
+	if r.Usage != nil {
+		usage["userSeconds"] = r.Usage.UserTime.Seconds()
+		usage["systemSeconds"] = r.Usage.SystemTime.Seconds()
+		if r.Usage.MaxRSS > 0 {
+			usage["maxRssBytes"] = r.Usage.MaxRSS
+		}
+	}
+
+	return map[string]any{MetaUsage: usage}
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResourceUsage tests that the resource usage of executions is reported in the metadata
+func TestResourceUsage(t *testing.T) {
+	t.Run("subprocess", func(t *testing.T) {
+		ctrl := helperController(t, "sleep")
+		ctrl.reportUsage = true
+		request := helperRequest("50ms")
+
+		result, err := ctrl.Execute(context.Background(), request)
+		require.NoError(t, err)
+		require.NotNil(t, result.Usage)
+		assert.GreaterOrEqual(t, result.Duration, 50*time.Millisecond)
+		assert.Positive(t, result.Usage.UserTime+result.Usage.SystemTime)
+		if runtime.GOOS != "windows" {
+			assert.Positive(t, result.Usage.MaxRSS)
+		}
+
+		toolResult, err := ctrl.Handle(context.Background(), request, result, err)
+		require.NoError(t, err)
+		usage, ok := toolResult.Meta.AdditionalFields[MetaUsage].(map[string]any)
+		require.True(t, ok)
+		assert.GreaterOrEqual(t, usage["wallSeconds"], 0.05)
+		assert.Contains(t, usage, "userSeconds")
+		assert.Contains(t, usage, "systemSeconds")
+	})
+
+	t.Run("custom executor", func(t *testing.T) {
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get"), reportUsage: true, executor: &recordingExecutor{result: NewExecResult([]byte("ok"), nil, 0)}}
+
+		result, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		assert.Positive(t, result.Duration, "the controller measures executors that do not")
+		toolResult, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, result, err)
+		require.NoError(t, err)
+		usage := toolResult.Meta.AdditionalFields[MetaUsage].(map[string]any)
+		assert.Contains(t, usage, "wallSeconds")
+		assert.NotContains(t, usage, "userSeconds", "only the duration is measured")
+		assert.NotContains(t, usage, "maxRssBytes")
+	})
+
+	t.Run("generator option", func(t *testing.T) {
+		root := &cobra.Command{Use: "cli"}
+		root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+		executor := &recordingExecutor{result: NewExecResult(nil, nil, 0)}
+
+		for _, enabled := range []bool{true, false} {
+			opts := []GeneratorOption{WithExecutor(executor)}
+			if enabled {
+				opts = append(opts, WithResourceUsage())
+			}
+			tools, err := NewGenerator(opts...).Generate(root)
+			require.NoError(t, err)
+			require.Len(t, tools, 1)
+
+			result, err := tools[0].Execute(context.Background(), mcp.CallToolRequest{})
+			toolResult, err := tools[0].Handle(context.Background(), mcp.CallToolRequest{}, result, err)
+			require.NoError(t, err)
+			assert.Equal(t, enabled, toolResult.Meta.AdditionalFields[MetaUsage] != nil)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Metadata keys attached to the CallToolResult of an executed command.
+const (
+	// MetaExitCode holds the exit code of the process. It is omitted if the process never started.
+	MetaExitCode = "exitCode"
+	// MetaKilled is set to true if the process was terminated by a signal instead of exiting.
+	MetaKilled = "killed"
+	// MetaTimedOut is set to true if the process was killed because it exceeded its timeout.
+	MetaTimedOut = "timedOut"
+	// MetaTruncated is set to true if stdout or stderr was cut to the Controller's MaxOutputBytes.
+	MetaTruncated = "truncated"
+	// MetaDryRun is set to true if the command was previewed rather than executed.
+	MetaDryRun = "dryRun"
+	// MetaArgs holds the arguments the executable would have been run with in a dry run.
+	MetaArgs = "args"
+	// MetaCached is set to true if the output was returned from the cache instead of running the command.
+	MetaCached = "cached"
+	// MetaShared is set to true if the output was shared with an identical call made at the same time.
+	MetaShared = "shared"
+)
+
+// ExecResult holds the captured output of a tool execution.
+// Stdout and Stderr are captured separately so that machine-readable output on
+// stdout is not corrupted by progress or warning messages written to stderr.
+type ExecResult struct {
+	// Stdout is everything the command wrote to standard output.
+	Stdout []byte
+	// Stderr is everything the command wrote to standard error.
+	Stderr []byte
+	// ExitCode is the exit code of the process, or -1 if it did not exit normally.
+	ExitCode int
+	// Duration is the wall-clock time the command ran for, zero if it was not started.
+	// It is zero for dry runs and cached output.
+	Duration time.Duration
+	// Killed reports whether the process was terminated by a signal rather than exiting.
+	// A killed process always has an ExitCode of -1.
+	Killed bool
+	// TimedOut reports whether the process was killed because it exceeded the Controller's Timeout.
+	// Stdout and Stderr still hold any output written before the process was killed.
+	TimedOut bool
+	// Truncated reports whether Stdout or Stderr was cut to the Controller's MaxOutputBytes.
+	// A truncated stream ends with a marker stating how many bytes were omitted.
+	Truncated bool
+	// DryRun reports that the command was not executed because the call asked for a dry run.
+	// Stdout holds the shell-quoted command line, and Args the arguments of the executable.
+	DryRun bool
+	// Args holds the arguments of the executable in a dry run.
+	Args []string
+	// Cached reports that the output was returned from the cache of a tool selected by WithCache,
+	// without running the command.
+	Cached bool
+	// Shared reports that the output is that of an identical call that was running already, of a
+	// tool selected by WithCoalescing.
+	Shared bool
+	// Usage is the resource usage of the process. It is nil if the executor does not measure it,
+	// and for dry runs and cached output.
+	Usage *ResourceUsage
+
+	combined   []byte
+	outputFile string // path of OutputFileEnv, empty if not set
+}
+
+// NewExecResult creates the result of a command that exited with exitCode, for use by custom
+// Executors that do not capture the interleaved output. Combined returns stdout followed by stderr.
+func NewExecResult(stdout, stderr []byte, exitCode int) *ExecResult {
+	return &ExecResult{
+		Stdout:   stdout,
+		Stderr:   stderr,
+		ExitCode: exitCode,
+		combined: slices.Concat(stdout, stderr),
+	}
+}
+
+// Combined returns stdout and stderr interleaved in the order they were written,
+// matching the output of exec.Cmd.CombinedOutput. For results built by custom Executors,
+// which cannot record the order, it returns stdout followed by stderr.
+func (r *ExecResult) Combined() []byte {
+	if r == nil {
+		return nil
+	}
+	if r.combined == nil {
+		return slices.Concat(r.Stdout, r.Stderr)
+	}
+
+	return r.combined
+}
+
+// started reports whether the process was launched at all.
+func (r *ExecResult) started() bool {
+	return r != nil && (r.ExitCode >= 0 || r.Killed)
+}
+
+// meta returns the execution metadata to attach to a tool result.
+func (r *ExecResult) meta() map[string]any {
+	if r != nil && r.DryRun {
+		return map[string]any{MetaDryRun: true, MetaArgs: r.Args}
+	}
+
+	if !r.started() {
+		return nil
+	}
+
+	fields := map[string]any{
+		MetaExitCode: r.ExitCode,
+	}
+	if r.Killed {
+		fields[MetaKilled] = true
+	}
+	if r.TimedOut {
+		fields[MetaTimedOut] = true
+	}
+	if r.Truncated {
+		fields[MetaTruncated] = true
+	}
+	if r.Cached {
+		fields[MetaCached] = true
+	}
+	if r.Shared {
+		fields[MetaShared] = true
+	}
+
+	return fields
+}
+
+// truncate cuts Stdout and Stderr independently to limit bytes. The combined output,
+// which holds both streams, is cut to twice the limit.
+func (r *ExecResult) truncate(limit int) {
+	var stdoutCut, stderrCut bool
+	r.Stdout, stdoutCut = truncateOutput(r.Stdout, limit)
+	r.Stderr, stderrCut = truncateOutput(r.Stderr, limit)
+	r.combined, _ = truncateOutput(r.combined, 2*limit)
+	r.Truncated = r.Truncated || stdoutCut || stderrCut
+}
+
+// truncateOutput cuts data to at most limit bytes. The cut is moved back to a rune boundary so
+// that text stays valid UTF-8, and a marker with the number of omitted bytes is appended to text.
+// Binary data, which is returned as an image or blob, is only cut, since the marker would become
+// part of its bytes; its truncation is reported by ExecResult.Truncated and the metadata.
+func truncateOutput(data []byte, limit int) ([]byte, bool) {
+	if len(data) <= limit {
+		return data, false
+	}
+
+	cut := limit
+	for i := 0; i < utf8.UTFMax && cut > 0 && !utf8.RuneStart(data[cut]); i++ {
+		cut--
+	}
+	if !utf8.RuneStart(data[cut]) {
+		// not UTF-8 text, cut at the exact limit
+		cut = limit
+	}
+
+	if !utf8.Valid(data[:cut]) {
+		return data[:cut:cut], true
+	}
+
+	truncated := append(data[:cut:cut], fmt.Sprintf("\n[output truncated, %d bytes omitted]", len(data)-cut)...)
+	return truncated, true
+}
+
+// outputCapture collects stdout and stderr into separate buffers while also
+// recording the interleaved output. exec.Cmd copies each stream in its own
+// goroutine, so writes are serialized with a shared lock.
+type outputCapture struct {
+	mu       sync.Mutex
+	stdout   bytes.Buffer
+	stderr   bytes.Buffer
+	combined bytes.Buffer
+}
+
+// captureWriter writes to a single stream of an outputCapture.
+type captureWriter struct {
+	capture *outputCapture
+	stream  *bytes.Buffer
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.capture.mu.Lock()
+	defer w.capture.mu.Unlock()
+
+	w.stream.Write(p)
+	w.capture.combined.Write(p)
+	return len(p), nil
+}
+
+func (c *outputCapture) stdoutWriter() *captureWriter {
+	return &captureWriter{capture: c, stream: &c.stdout}
+}
+
+func (c *outputCapture) stderrWriter() *captureWriter {
+	return &captureWriter{capture: c, stream: &c.stderr}
+}
+
+// result builds an ExecResult from the captured output and the process state.
+// A nil state means the process was never started.
+func (c *outputCapture) result(state *os.ProcessState) *ExecResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := &ExecResult{
+		Stdout:   bytes.Clone(c.stdout.Bytes()),
+		Stderr:   bytes.Clone(c.stderr.Bytes()),
+		ExitCode: -1,
+		combined: bytes.Clone(c.combined.Bytes()),
+	}
+
+	if state != nil {
+		result.ExitCode = state.ExitCode()
+		result.Killed = !state.Exited()
+	}
+
+	return result
+}
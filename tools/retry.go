@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MetaRetries holds the number of times a failed tool call was retried.
+const MetaRetries = "retries"
+
+// Default backoff of a RetryPolicy.
+const (
+	DefaultRetryBackoff    = 200 * time.Millisecond
+	DefaultRetryMaxBackoff = 5 * time.Second
+)
+
+// RetryPolicy decides which failed executions of a tool are retried. A zero MaxRetries means
+// no retries.
+type RetryPolicy struct {
+	// MaxRetries is how often a failed execution is retried, after the first attempt.
+	MaxRetries int
+	// ExitCodes limits retries to these exit codes. If empty, every non-zero exit code is retried.
+	ExitCodes []int
+	// StderrPattern limits retries to executions whose stderr matches, e.g. "connection reset|locked".
+	// If nil, stderr is not checked.
+	StderrPattern *regexp.Regexp
+	// Backoff is the delay before the first retry, which doubles for every further one.
+	// If zero, DefaultRetryBackoff is used.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries. If zero, DefaultRetryMaxBackoff is used.
+	MaxBackoff time.Duration
+}
+
+// Retry returns middleware that retries tool calls whose command exited with a non-zero exit
+// code, with exponential backoff. Tools are looked up by name in policies, and use
+// defaultPolicy otherwise. Nothing is retried by default.
+//
+// Only executions of tools annotated as idempotent are retried, since running any other command
+// twice may repeat its side effects. Calls rejected before running, timed out or killed
+// commands, and hand-written tools are never retried. A retry is skipped if the backoff would
+// outlast the deadline of the call. The result of the last attempt is returned, with the number
+// of retries in the MetaRetries metadata.
+//
+//	Example: ophis.Config{Middleware: []server.ToolHandlerMiddleware{
+//		tools.Retry(tools.RetryPolicy{}, map[string]tools.RetryPolicy{
+//			"cli_get": {MaxRetries: 3, StderrPattern: regexp.MustCompile("connection refused")},
+//		}),
+//	}}
+func Retry(defaultPolicy RetryPolicy, policies map[string]RetryPolicy) server.ToolHandlerMiddleware {
+	return (&retrier{defaultPolicy: defaultPolicy, policies: policies, sleep: sleepContext}).middleware
+}
+
+// retrier holds the retry policies of the tools.
+type retrier struct {
+	defaultPolicy RetryPolicy
+	policies      map[string]RetryPolicy
+	sleep         func(ctx context.Context, d time.Duration) error
+}
+
+func (r *retrier) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		policy, ok := r.policies[request.Params.Name]
+		if !ok {
+			policy = r.defaultPolicy
+		}
+
+		for retries := 0; ; retries++ {
+			attempt := &retryAttempt{}
+			result, err := next(context.WithValue(ctx, retryAttemptKey{}, attempt), request)
+			if retries == policy.MaxRetries || !policy.retries(attempt) || !r.backoff(ctx, policy.delay(retries)) {
+				if result != nil && retries > 0 {
+					addMeta(result, map[string]any{MetaRetries: retries})
+				}
+				return result, err
+			}
+		}
+	}
+}
+
+// backoff waits for delay, and reports whether the call may be retried afterwards.
+func (r *retrier) backoff(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+
+	return r.sleep(ctx, delay) == nil
+}
+
+// retries reports whether the execution of an attempt is retried.
+func (p RetryPolicy) retries(attempt *retryAttempt) bool {
+	result := attempt.result
+	switch {
+	case !attempt.idempotent, attempt.err == nil, result == nil:
+		return false
+	case errors.Is(attempt.err, ErrInvalidArguments), result.TimedOut, result.Killed, result.ExitCode <= 0:
+		return false
+	case len(p.ExitCodes) > 0 && !slices.Contains(p.ExitCodes, result.ExitCode):
+		return false
+	case p.StderrPattern != nil && !p.StderrPattern.Match(result.Stderr):
+		return false
+	}
+
+	return true
+}
+
+// delay returns the backoff before the retry following the given number of retries.
+func (p RetryPolicy) delay(retries int) time.Duration {
+	delay, maxDelay := p.Backoff, p.MaxBackoff
+	if delay <= 0 {
+		delay = DefaultRetryBackoff
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxBackoff
+	}
+
+	for range retries {
+		if delay >= maxDelay/2 {
+			return maxDelay
+		}
+		delay *= 2
+	}
+
+	return min(delay, maxDelay)
+}
+
+// retryAttempt receives the execution of a tool call made by the Retry middleware. Controllers
+// record their executions in it, so that hand-written tools are never retried.
+type retryAttempt struct {
+	idempotent bool
+	result     *ExecResult
+	err        error
+}
+
+type retryAttemptKey struct{}
+
+// recordAttempt records an execution of c in the retry attempt of ctx, if there is one.
+func (c *Controller) recordAttempt(ctx context.Context, result *ExecResult, err error) {
+	attempt, ok := ctx.Value(retryAttemptKey{}).(*retryAttempt)
+	if !ok {
+		return
+	}
+
+	hint := c.Tool.Annotations.IdempotentHint
+	attempt.idempotent = hint != nil && *hint
+	attempt.result = result
+	attempt.err = err
+}
+
+// sleepContext waits for d, or returns the error of ctx if it is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyExecutor fails with exitCode and stderr until it was run failures times.
+type flakyExecutor struct {
+	failures int
+	exitCode int
+	stderr   string
+	calls    int
+}
+
+func (e *flakyExecutor) Run(context.Context, Invocation) (*ExecResult, error) {
+	e.calls++
+	if e.calls <= e.failures {
+		return NewExecResult(nil, []byte(e.stderr), e.exitCode), errors.New("exit status 1")
+	}
+
+	return NewExecResult([]byte("ok"), nil, 0), nil
+}
+
+// TestRetry tests that failed executions of idempotent tools are retried with backoff
+func TestRetry(t *testing.T) {
+	call := func(t *testing.T, executor Executor, idempotent bool, policy RetryPolicy) (*mcp.CallToolResult, []time.Duration) {
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get", mcp.WithIdempotentHintAnnotation(idempotent)), path: []string{"get"}, executor: executor}
+		handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := ctrl.Execute(ctx, request)
+			return ctrl.Handle(ctx, request, result, err)
+		}
+
+		var delays []time.Duration
+		r := &retrier{policies: map[string]RetryPolicy{"cli_get": policy}, sleep: func(_ context.Context, d time.Duration) error {
+			delays = append(delays, d)
+			return nil
+		}}
+
+		var request mcp.CallToolRequest
+		request.Params.Name = "cli_get"
+		result, err := r.middleware(handler)(context.Background(), request)
+		require.NoError(t, err)
+		return result, delays
+	}
+
+	t.Run("retried until success", func(t *testing.T) {
+		executor := &flakyExecutor{failures: 2, exitCode: 1}
+		result, delays := call(t, executor, true, RetryPolicy{MaxRetries: 3})
+		assert.False(t, result.IsError)
+		assert.Equal(t, 3, executor.calls)
+		assert.Equal(t, []time.Duration{200 * time.Millisecond, 400 * time.Millisecond}, delays)
+		assert.Equal(t, 2, result.Meta.AdditionalFields[MetaRetries])
+	})
+
+	t.Run("last attempt is returned", func(t *testing.T) {
+		executor := &flakyExecutor{failures: 5, exitCode: 1}
+		result, _ := call(t, executor, true, RetryPolicy{MaxRetries: 2})
+		assert.True(t, result.IsError)
+		assert.Equal(t, 3, executor.calls)
+		assert.Equal(t, 2, result.Meta.AdditionalFields[MetaRetries])
+		assert.Equal(t, 1, result.Meta.AdditionalFields[MetaExitCode])
+	})
+
+	t.Run("not idempotent", func(t *testing.T) {
+		executor := &flakyExecutor{failures: 1, exitCode: 1}
+		result, delays := call(t, executor, false, RetryPolicy{MaxRetries: 3})
+		assert.True(t, result.IsError)
+		assert.Equal(t, 1, executor.calls)
+		assert.Empty(t, delays)
+		assert.NotContains(t, result.Meta.AdditionalFields, MetaRetries)
+	})
+
+	t.Run("exit codes", func(t *testing.T) {
+		executor := &flakyExecutor{failures: 1, exitCode: 2}
+		call(t, executor, true, RetryPolicy{MaxRetries: 3, ExitCodes: []int{75}})
+		assert.Equal(t, 1, executor.calls)
+
+		executor = &flakyExecutor{failures: 1, exitCode: 75}
+		call(t, executor, true, RetryPolicy{MaxRetries: 3, ExitCodes: []int{75}})
+		assert.Equal(t, 2, executor.calls)
+	})
+
+	t.Run("stderr pattern", func(t *testing.T) {
+		pattern := regexp.MustCompile("connection (refused|reset)")
+		executor := &flakyExecutor{failures: 1, exitCode: 1, stderr: "Error: not found"}
+		call(t, executor, true, RetryPolicy{MaxRetries: 3, StderrPattern: pattern})
+		assert.Equal(t, 1, executor.calls)
+
+		executor = &flakyExecutor{failures: 1, exitCode: 1, stderr: "Error: connection refused"}
+		call(t, executor, true, RetryPolicy{MaxRetries: 3, StderrPattern: pattern})
+		assert.Equal(t, 2, executor.calls)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		executor := &recordingExecutor{}
+		var request mcp.CallToolRequest
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get", mcp.WithIdempotentHintAnnotation(true)), executor: executor}
+		handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			request.Params.Arguments = map[string]any{StdinParam: 1}
+			result, err := ctrl.Execute(ctx, request)
+			return ctrl.Handle(ctx, request, result, err)
+		}
+		calls := 0
+		counted := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			calls++
+			return handler(ctx, request)
+		}
+
+		result, err := Retry(RetryPolicy{MaxRetries: 3}, nil)(counted)(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("hand-written tools", func(t *testing.T) {
+		calls := 0
+		var handler server.ToolHandlerFunc = func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			calls++
+			return mcp.NewToolResultError("failed"), nil
+		}
+
+		result, err := Retry(RetryPolicy{MaxRetries: 3}, nil)(handler)(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+// TestRetryBackoff tests the delays between retries and the deadline of the call
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, policy.delay(0))
+	assert.Equal(t, 2*time.Second, policy.delay(1))
+	assert.Equal(t, 4*time.Second, policy.delay(2))
+	assert.Equal(t, 5*time.Second, policy.delay(3))
+	assert.Equal(t, 5*time.Second, policy.delay(100))
+	assert.Equal(t, DefaultRetryBackoff, RetryPolicy{}.delay(0))
+
+	r := &retrier{sleep: sleepContext}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.False(t, r.backoff(ctx, time.Second), "the backoff outlasts the deadline")
+	assert.True(t, r.backoff(ctx, time.Millisecond))
+}
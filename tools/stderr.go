@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultErrorStderrBytes is how many bytes of stderr the error of a failed command includes,
+// unless changed with WithErrorStderr.
+const DefaultErrorStderrBytes = 4096
+
+// WithErrorStderr returns a GeneratorOption that sets how many bytes of stderr the error of a
+// failed command includes, after a "Stderr:" label. An "exit status 1" alone does not tell a
+// client what went wrong, while the stderr of the command usually does. Longer stderr keeps
+// its end, where the cause is usually printed. Defaults to DefaultErrorStderrBytes.
+//
+// The error is returned to the client in place of the separate stderr of the result, and is
+// passed to custom handlers and logged. A zero limit leaves stderr out of the error, and
+// returns it in full after the output instead.
+//
+//	Example: NewGenerator(WithErrorStderr(1024))
+func WithErrorStderr(limit int) GeneratorOption {
+	return func(g *Generator) {
+		g.errorStderr = limit
+	}
+}
+
+// stderrError is the error of a failed command, with an excerpt of its stderr.
+type stderrError struct {
+	err    error
+	stderr string
+}
+
+func (e *stderrError) Error() string {
+	return fmt.Sprintf("%s\nStderr: %s", e.err, e.stderr)
+}
+
+func (e *stderrError) Unwrap() error {
+	return e.err
+}
+
+// stderrExcerpt returns the last limit bytes of stderr, with a marker of the omitted bytes.
+// The cut is moved forward to a rune boundary so that text stays valid UTF-8.
+func stderrExcerpt(stderr []byte, limit int) string {
+	stderr = []byte(strings.TrimRight(string(stderr), "\n"))
+	if len(stderr) <= limit {
+		return string(stderr)
+	}
+
+	cut := len(stderr) - limit
+	for i := 0; i < utf8.UTFMax && cut < len(stderr) && !utf8.RuneStart(stderr[cut]); i++ {
+		cut++
+	}
+
+	return fmt.Sprintf("[%d bytes omitted]\n%s", cut, stderr[cut:])
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestErrorStderr tests that the errors of failed commands include their stderr
+func TestErrorStderr(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+	stderr := []byte("Error: unknown flag: --foo\n")
+
+	t.Run("included", func(t *testing.T) {
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get"), errStderr: DefaultErrorStderrBytes, executor: &recordingExecutor{result: NewExecResult([]byte("partial"), stderr, 1), err: exitErr}}
+
+		result, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		require.ErrorIs(t, err, exitErr)
+		assert.Equal(t, "exit status 1\nStderr: Error: unknown flag: --foo", err.Error())
+
+		toolResult, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, result, err)
+		require.NoError(t, err)
+		text := toolResult.Content[0].(mcp.TextContent).Text
+		assert.Equal(t, "command exited with code 1: exit status 1\nStderr: Error: unknown flag: --foo\nOutput: partial", text)
+	})
+
+	t.Run("excluded", func(t *testing.T) {
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get"), executor: &recordingExecutor{result: NewExecResult(nil, stderr, 1), err: exitErr}}
+
+		result, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		assert.Equal(t, exitErr, err)
+
+		toolResult, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, result, err)
+		require.NoError(t, err)
+		assert.Equal(t, "command exited with code 1: exit status 1\nStderr: Error: unknown flag: --foo\n", toolResult.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("empty stderr", func(t *testing.T) {
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get"), errStderr: DefaultErrorStderrBytes, executor: &recordingExecutor{result: NewExecResult(nil, []byte("\n"), 1), err: exitErr}}
+
+		_, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		assert.Equal(t, exitErr, err)
+	})
+
+	t.Run("generator option", func(t *testing.T) {
+		root := &cobra.Command{Use: "cli"}
+		root.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+
+		ctrls, err := NewGenerator().Generate(root)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultErrorStderrBytes, ctrls[0].errStderr)
+
+		ctrls, err = NewGenerator(WithErrorStderr(0)).Generate(root)
+		require.NoError(t, err)
+		assert.Zero(t, ctrls[0].errStderr)
+	})
+}
+
+// TestStderrExcerpt tests that long stderr keeps its end
+func TestStderrExcerpt(t *testing.T) {
+	assert.Equal(t, "short", stderrExcerpt([]byte("short\n"), 10))
+	assert.Equal(t, "[10 bytes omitted]\nError: boom", stderrExcerpt([]byte(strings.Repeat("x", 10)+"Error: boom\n"), 11))
+
+	excerpt := stderrExcerpt([]byte("ééééé"), 3)
+	assert.True(t, utf8.ValidString(excerpt))
+	assert.Equal(t, "[8 bytes omitted]\né", excerpt)
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+)
+
+// Stream names passed to Invocation.OnOutput.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// WithStreaming returns a GeneratorOption that streams the output of the selected tools to the
+// client while the command is running. The full output is still returned when the command exits.
+//
+// Each line is sent as a progress notification if the client asked for progress on the tool
+// call, and as a logging notification otherwise. Streaming is off by default, so that quick
+// commands do not pay for the notifications.
+//
+//	Example: NewGenerator(WithStreaming(Allow([]string{"build", "deploy"})))
+func WithStreaming(selector Filter) GeneratorOption {
+	return func(g *Generator) {
+		g.streaming = selector
+	}
+}
+
+// streams reports whether the output of a command should be streamed.
+func (g *Generator) streams(cmd *cobra.Command) bool {
+	return g.streaming != nil && g.streaming(cmd)
+}
+
+// lineWriter splits written data into lines and passes each complete line to emit.
+// A trailing partial line is held back until more data arrives or flush is called.
+type lineWriter struct {
+	stream string
+	emit   func(stream, line string)
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.emit(w.stream, strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush emits any buffered partial line.
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emit(w.stream, string(w.buf))
+		w.buf = nil
+	}
+}
+
+// outputNotifier sends lines of command output to the MCP client of a tool call.
+type outputNotifier struct {
+	ctx    context.Context
+	server *server.MCPServer
+	logger *slog.Logger
+	tool   string
+	token  mcp.ProgressToken
+	strip  bool // whether ANSI escape sequences are removed from lines
+
+	mu    sync.Mutex
+	lines int
+}
+
+// newOutputNotifier returns a notifier for the tool call, or nil if ctx does not belong to
+// an MCP server and the output cannot be sent anywhere.
+func newOutputNotifier(ctx context.Context, logger *slog.Logger, tool string, request mcp.CallToolRequest) *outputNotifier {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		logger.Debug("output streaming unavailable: no MCP server in context", "tool", tool)
+		return nil
+	}
+
+	n := &outputNotifier{ctx: ctx, server: srv, logger: logger, tool: tool}
+	if request.Params.Meta != nil {
+		n.token = request.Params.Meta.ProgressToken
+	}
+
+	return n
+}
+
+// send notifies the client of a single line of output.
+// Stdout and stderr are copied concurrently, so sends are serialized to keep the progress increasing.
+func (n *outputNotifier) send(stream, line string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.lines++
+	if n.strip {
+		line = string(stripANSI([]byte(line)))
+	}
+
+	var err error
+	if n.token != nil {
+		err = n.server.SendNotificationToClient(n.ctx, "notifications/progress", map[string]any{
+			"progressToken": n.token,
+			"progress":      n.lines,
+			"message":       line,
+		})
+	} else {
+		level := mcp.LoggingLevelInfo
+		if stream == StreamStderr {
+			level = mcp.LoggingLevelNotice
+		}
+		err = n.server.SendLogMessageToClient(n.ctx, mcp.NewLoggingMessageNotification(level, n.tool, line))
+	}
+
+	if err != nil {
+		n.logger.Debug("failed to stream command output", "tool", n.tool, "stream", stream, "error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSession is a client session that buffers the notifications sent to it.
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+	level         mcp.LoggingLevel
+}
+
+func newTestSession() *testSession {
+	return &testSession{notifications: make(chan mcp.JSONRPCNotification, 100), level: mcp.LoggingLevelDebug}
+}
+
+func (s *testSession) Initialize()                                         {}
+func (s *testSession) Initialized() bool                                   { return true }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *testSession) SessionID() string                                   { return "test" }
+func (s *testSession) SetLogLevel(level mcp.LoggingLevel)                  { s.level = level }
+func (s *testSession) GetLogLevel() mcp.LoggingLevel                       { return s.level }
+
+// drain returns the notifications received so far.
+func (s *testSession) drain() []mcp.JSONRPCNotification {
+	var received []mcp.JSONRPCNotification
+	for {
+		select {
+		case n := <-s.notifications:
+			received = append(received, n)
+		default:
+			return received
+		}
+	}
+}
+
+// TestLineWriter tests that output is split into lines across writes
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{stream: StreamStdout, emit: func(stream, line string) {
+		assert.Equal(t, StreamStdout, stream)
+		lines = append(lines, line)
+	}}
+
+	_, _ = w.Write([]byte("one\ntw"))
+	_, _ = w.Write([]byte("o\r\n\nthree"))
+	assert.Equal(t, []string{"one", "two", ""}, lines)
+
+	w.flush()
+	assert.Equal(t, []string{"one", "two", "", "three"}, lines)
+}
+
+// TestStreaming tests that a streaming tool sends its output to the client while running
+func TestStreaming(t *testing.T) {
+	ctrl := helperController(t, "echo")
+	ctrl.stream = true
+
+	srv := server.NewMCPServer("test", "1.0.0")
+	srv.AddTool(ctrl.Tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := ctrl.Execute(ctx, request)
+		return ctrl.Handle(ctx, request, result, err)
+	})
+
+	call := func(t *testing.T, meta string) []mcp.JSONRPCNotification {
+		session := newTestSession()
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"helper_echo","arguments":{"args":["a","b"]}` + meta + `}}`
+		response := srv.HandleMessage(srv.WithContext(context.Background(), session), json.RawMessage(message))
+
+		resp, ok := response.(mcp.JSONRPCResponse)
+		require.True(t, ok, "unexpected response: %#v", response)
+		result := resp.Result.(mcp.CallToolResult)
+		assert.False(t, result.IsError)
+		assert.Equal(t, "a\nb\n", result.Content[0].(mcp.TextContent).Text)
+		return session.drain()
+	}
+
+	t.Run("progress notifications", func(t *testing.T) {
+		notifications := call(t, `,"_meta":{"progressToken":"tok"}`)
+
+		var messages []string
+		for i, n := range notifications {
+			assert.Equal(t, "notifications/progress", n.Method)
+			assert.Equal(t, "tok", n.Params.AdditionalFields["progressToken"])
+			assert.Equal(t, i+1, n.Params.AdditionalFields["progress"])
+			messages = append(messages, n.Params.AdditionalFields["message"].(string))
+		}
+		assert.ElementsMatch(t, []string{"a", "b", "stderr"}, messages)
+	})
+
+	t.Run("logging notifications", func(t *testing.T) {
+		notifications := call(t, "")
+
+		levels := map[any]any{}
+		for _, n := range notifications {
+			assert.Equal(t, "notifications/message", n.Method)
+			assert.Equal(t, "helper_echo", n.Params.AdditionalFields["logger"])
+			levels[n.Params.AdditionalFields["data"]] = n.Params.AdditionalFields["level"]
+		}
+		assert.Equal(t, map[any]any{
+			"a":      mcp.LoggingLevelInfo,
+			"b":      mcp.LoggingLevelInfo,
+			"stderr": mcp.LoggingLevelNotice,
+		}, levels)
+	})
+
+	t.Run("not streaming", func(t *testing.T) {
+		ctrl.stream = false
+		defer func() { ctrl.stream = true }()
+
+		assert.Empty(t, call(t, `,"_meta":{"progressToken":"tok"}`))
+	})
+}
+
+// TestWithStreaming tests that streaming is only enabled for the selected tools
+func TestWithStreaming(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	root.AddCommand(
+		&cobra.Command{Use: "build", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "version", Run: func(*cobra.Command, []string) {}},
+	)
+
+	streamed := map[string]bool{}
+	for _, ctrl := range NewGenerator(WithStreaming(Allow([]string{"build"}))).FromRootCmd(root) {
+		streamed[ctrl.Tool.Name] = ctrl.stream
+	}
+	assert.Equal(t, map[string]bool{"root_build": true, "root_version": false}, streamed)
+
+	for _, ctrl := range NewGenerator().FromRootCmd(root) {
+		assert.False(t, ctrl.stream, ctrl.Tool.Name)
+	}
+}
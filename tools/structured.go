@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+)
+
+// WithStructuredOutput returns a GeneratorOption that returns the stdout of the selected tools as
+// structured content when it is a JSON object, as written by commands run with flags such as
+// --output json. The raw text is still returned as text content for clients that do not read
+// structured content.
+//
+// Detection is conservative: the whole of stdout must be a single JSON object, and anything
+// else, including JSON arrays, is returned as text only. It is off by default so that text
+// commands are never misinterpreted.
+//
+//	Example: NewGenerator(WithStructuredOutput(Allow([]string{"get"})))
+func WithStructuredOutput(selector Filter) GeneratorOption {
+	return func(g *Generator) {
+		g.structured = selector
+	}
+}
+
+// structuredOutput reports whether the stdout of a command should be parsed as JSON.
+func (g *Generator) structuredOutput(cmd *cobra.Command) bool {
+	return g.structured != nil && g.structured(cmd)
+}
+
+// parseJSONObject parses data as a single JSON object. Numbers are kept as json.Number
+// so that they are returned without losing precision.
+func parseJSONObject(data []byte) (map[string]any, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, false
+	}
+
+	// Reject trailing data, such as a second object or text after the JSON
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, false
+	}
+
+	return object, true
+}
+
+// addStructuredContent sets the structured content of a successful tool result from stdout.
+func addStructuredContent(logger *slog.Logger, toolResult *mcp.CallToolResult, stdout []byte) {
+	if toolResult.IsError || toolResult.StructuredContent != nil {
+		return
+	}
+
+	object, ok := parseJSONObject(stdout)
+	if !ok {
+		logger.Debug("stdout is not a JSON object, returning text only")
+		return
+	}
+
+	toolResult.StructuredContent = object
+}
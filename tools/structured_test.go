@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseJSONObject tests that only a single complete JSON object is detected
+func TestParseJSONObject(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{"object", `{"items": [1, 2]}`, true},
+		{"surrounding whitespace", "\n  {\"a\": 1}\n", true},
+		{"array", `[{"a": 1}]`, false},
+		{"scalar", `42`, false},
+		{"string", `"text"`, false},
+		{"text", `NAME   READY`, false},
+		{"empty", ``, false},
+		{"invalid", `{"a": }`, false},
+		{"two objects", `{"a": 1}{"b": 2}`, false},
+		{"trailing text", `{"a": 1} done`, false},
+		{"json lines", "{\"a\": 1}\n{\"b\": 2}\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := parseJSONObject([]byte(tt.input))
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+
+	t.Run("keeps number precision", func(t *testing.T) {
+		object, ok := parseJSONObject([]byte(`{"id": 12345678901234567890}`))
+		require.True(t, ok)
+		data, err := json.Marshal(object)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id": 12345678901234567890}`, string(data))
+	})
+}
+
+// TestHandleStructuredOutput tests that JSON output becomes structured content only when enabled
+func TestHandleStructuredOutput(t *testing.T) {
+	execResult := &ExecResult{Stdout: []byte(`{"name": "nginx", "ready": true}`)}
+
+	t.Run("disabled", func(t *testing.T) {
+		result, err := (&Controller{}).Handle(context.Background(), mcp.CallToolRequest{}, execResult, nil)
+		require.NoError(t, err)
+		assert.Nil(t, result.StructuredContent)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		ctrl := &Controller{structured: true}
+		result, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, execResult, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"name": "nginx", "ready": true}, result.StructuredContent)
+		assert.Equal(t, `{"name": "nginx", "ready": true}`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("text output", func(t *testing.T) {
+		ctrl := &Controller{structured: true}
+		result, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{Stdout: []byte("nginx ready")}, nil)
+		require.NoError(t, err)
+		assert.Nil(t, result.StructuredContent)
+	})
+
+	t.Run("failed command", func(t *testing.T) {
+		ctrl := &Controller{structured: true}
+		result, err := ctrl.Handle(context.Background(), mcp.CallToolRequest{}, &ExecResult{Stdout: execResult.Stdout, ExitCode: 1}, errors.New("exit status 1"))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Nil(t, result.StructuredContent)
+	})
+}
+
+// TestWithStructuredOutput tests that structured output is only enabled for the selected tools
+func TestWithStructuredOutput(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	root.AddCommand(
+		&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "logs", Run: func(*cobra.Command, []string) {}},
+	)
+
+	structured := map[string]bool{}
+	for _, ctrl := range NewGenerator(WithStructuredOutput(Allow([]string{"get"}))).FromRootCmd(root) {
+		structured[ctrl.Tool.Name] = ctrl.structured
+	}
+	assert.Equal(t, map[string]bool{"root_get": true, "root_logs": false}, structured)
+}
@@ -0,0 +1,159 @@
+// Package toolstest provides utilities for testing the tools generated from a Cobra command
+// tree without running any commands.
+//
+// A Harness generates the tools with a fake Executor, which records the arguments every call
+// would have run the binary with and returns canned output:
+//
+//	func TestGetPods(t *testing.T) {
+//	    h, err := toolstest.New(newRootCmd())
+//	    require.NoError(t, err)
+//
+//	    args, err := h.Args("kubectl_get", map[string]any{
+//	        "flags": map[string]any{"output": "json"},
+//	        "args":  []any{"pods"},
+//	    })
+//	    require.NoError(t, err)
+//	    assert.Equal(t, []string{"get", "--output=json", "--", "pods"}, args)
+//	}
+package toolstest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/njayp/ophis/tools"
+	"github.com/spf13/cobra"
+)
+
+// Executor is a tools.Executor that records invocations instead of running them.
+// It is safe for concurrent use.
+type Executor struct {
+	// Respond returns the result of an invocation. If it is nil, every invocation returns
+	// Stdout and Stderr with ExitCode.
+	Respond func(inv tools.Invocation) (*tools.ExecResult, error)
+	// Stdout and Stderr are the canned output of every invocation, unless Respond is set.
+	Stdout, Stderr string
+	// ExitCode is the canned exit code of every invocation, unless Respond is set.
+	// A non-zero ExitCode is returned with an error, like a failed subprocess.
+	ExitCode int
+
+	mu          sync.Mutex
+	invocations []tools.Invocation
+}
+
+// Run records inv and returns the canned result.
+func (e *Executor) Run(ctx context.Context, inv tools.Invocation) (*tools.ExecResult, error) {
+	e.mu.Lock()
+	e.invocations = append(e.invocations, inv)
+	e.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if e.Respond != nil {
+		return e.Respond(inv)
+	}
+
+	result := tools.NewExecResult([]byte(e.Stdout), []byte(e.Stderr), e.ExitCode)
+	if e.ExitCode != 0 {
+		return result, fmt.Errorf("exit status %d", e.ExitCode)
+	}
+
+	return result, nil
+}
+
+// Invocations returns the invocations recorded so far, oldest first.
+func (e *Executor) Invocations() []tools.Invocation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return slices.Clone(e.invocations)
+}
+
+// Reset forgets the recorded invocations.
+func (e *Executor) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.invocations = nil
+}
+
+// Harness holds the tools generated from a command tree, run by a fake Executor.
+type Harness struct {
+	// Executor records the invocations of every tool.
+	Executor *Executor
+	// Tools are the generated tools, in the order of the Generator.
+	Tools []tools.Controller
+}
+
+// New generates the tools of root with the options of a tools.Generator, and runs them with a
+// fake Executor. It returns the error of Generate, e.g. if tool names collide. The Executor
+// replaces any executor set by opts, except the one of tools.WithInProcessExecution.
+func New(root *cobra.Command, opts ...tools.GeneratorOption) (*Harness, error) {
+	executor := &Executor{}
+	generated, err := tools.NewGenerator(append(slices.Clone(opts), tools.WithExecutor(executor))...).Generate(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Harness{Executor: executor, Tools: generated}, nil
+}
+
+// Tool returns the tool with the given name, or nil if there is none.
+func (h *Harness) Tool(name string) *tools.Controller {
+	for i := range h.Tools {
+		if h.Tools[i].Tool.Name == name {
+			return &h.Tools[i]
+		}
+	}
+
+	return nil
+}
+
+// Request builds a call of the named tool with the given arguments, as sent by an MCP client.
+func Request(name string, arguments map[string]any) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	return request
+}
+
+// Call calls the named tool with the given arguments like the MCP server would, and returns
+// the result sent to the client.
+func (h *Harness) Call(ctx context.Context, name string, arguments map[string]any) (*mcp.CallToolResult, error) {
+	tool := h.Tool(name)
+	if tool == nil {
+		return nil, fmt.Errorf("no tool named %q", name)
+	}
+
+	request := Request(name, arguments)
+	result, err := tool.Execute(ctx, request)
+	return tool.Handle(ctx, request, result, err)
+}
+
+// Args returns the arguments the named tool would run the binary with for the given arguments,
+// starting with the command path below the root command. It returns the error of
+// tools.Controller.Execute if the arguments are rejected before a command is run.
+func (h *Harness) Args(name string, arguments map[string]any) ([]string, error) {
+	tool := h.Tool(name)
+	if tool == nil {
+		return nil, fmt.Errorf("no tool named %q", name)
+	}
+
+	before := len(h.Executor.Invocations())
+	_, err := tool.Execute(context.Background(), Request(name, arguments))
+
+	invocations := h.Executor.Invocations()
+	switch {
+	case len(invocations) > before:
+		return invocations[len(invocations)-1].Args, nil
+	case err != nil:
+		return nil, err
+	default:
+		// The call was answered without running a command, e.g. from the cache or as a dry run
+		return nil, fmt.Errorf("tool %q did not run a command", name)
+	}
+}
@@ -0,0 +1,81 @@
+package toolstest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/njayp/ophis/tools"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRootCmd() *cobra.Command {
+	root := &cobra.Command{Use: "kubectl"}
+	get := &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}}
+	get.Flags().StringP("output", "o", "", "Output format")
+	root.AddCommand(get)
+	return root
+}
+
+// TestHarnessArgs tests that the built arguments are recorded without running a command
+func TestHarnessArgs(t *testing.T) {
+	h, err := New(newRootCmd())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		arguments map[string]any
+		expected  []string
+		err       string
+	}{
+		{"flags and args", map[string]any{"flags": map[string]any{"o": "json"}, "args": []any{"pods"}}, []string{"get", "--output=json", "--", "pods"}, ""},
+		{"no arguments", map[string]any{}, []string{"get"}, ""},
+		{"unknown flag", map[string]any{"flags": map[string]any{"force": true}}, nil, "unknown flag --force"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := h.Args("kubectl_get", tt.arguments)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, args)
+		})
+	}
+
+	_, err = h.Args("kubectl_delete", nil)
+	assert.Error(t, err)
+}
+
+// TestHarnessCall tests that calls return the canned output as the client would see it
+func TestHarnessCall(t *testing.T) {
+	h, err := New(newRootCmd(), tools.WithDryRun())
+	require.NoError(t, err)
+
+	h.Executor.Stdout = "nginx"
+	result, err := h.Call(context.Background(), "kubectl_get", map[string]any{"args": []any{"pods"}})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "nginx", result.Content[0].(mcp.TextContent).Text)
+	assert.Equal(t, 0, result.Meta.AdditionalFields[tools.MetaExitCode])
+
+	h.Executor.ExitCode = 3
+	result, err = h.Call(context.Background(), "kubectl_get", nil)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, 3, result.Meta.AdditionalFields[tools.MetaExitCode])
+	assert.Len(t, h.Executor.Invocations(), 2)
+
+	// A dry run does not run a command
+	_, err = h.Args("kubectl_get", map[string]any{tools.DryRunParam: true})
+	assert.ErrorContains(t, err, "did not run a command")
+
+	h.Executor.Reset()
+	assert.Empty(t, h.Executor.Invocations())
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the span of an execution started by the Tracer of WithTracer.
+const (
+	// SpanAttrTool is the name of the tool.
+	SpanAttrTool = "ophis.tool"
+	// SpanAttrArgs is the command line below the root command, with sensitive flags redacted.
+	SpanAttrArgs = "ophis.args"
+	// SpanAttrExitCode is the exit code of the process, or -1 if it did not exit normally.
+	SpanAttrExitCode = "process.exit.code"
+	// SpanAttrDuration is the number of milliseconds the command ran for.
+	SpanAttrDuration = "ophis.duration_ms"
+	// SpanAttrTimedOut is set to true if the command was killed because a deadline passed.
+	SpanAttrTimedOut = "ophis.timed_out"
+)
+
+// WithTracer returns a GeneratorOption that starts an OpenTelemetry span named after the tool for
+// every execution of a generated tool, as a child of the span in the context of the call. The
+// span records the redacted arguments, the exit code and the duration of the command, and ends
+// even if the execution panics or times out. The trace context is passed to the command in the
+// TRACEPARENT and TRACESTATE environment variables, so that its own spans join the trace.
+// Nothing is traced by default, and a nil tracer restores that default.
+//
+//	Example: NewGenerator(WithTracer(otel.Tracer("github.com/me/mycli")))
+func WithTracer(tracer trace.Tracer) GeneratorOption {
+	return func(g *Generator) {
+		g.tracer = tracer
+	}
+}
+
+// startSpan starts the span of an execution of c. The returned function ends it with the
+// outcome of the execution, or with the value of a panic that interrupted it if recovered is
+// not nil.
+func (c *Controller) startSpan(ctx context.Context) (context.Context, func(args []string, result *ExecResult, err error, recovered any)) {
+	ctx, span := c.tracer.Start(ctx, c.Tool.Name, trace.WithAttributes(attribute.String(SpanAttrTool, c.Tool.Name)))
+
+	return ctx, func(args []string, result *ExecResult, err error, recovered any) {
+		defer span.End()
+
+		if recovered != nil {
+			span.RecordError(fmt.Errorf("panic: %v", recovered))
+			span.SetStatus(codes.Error, "panic")
+			return
+		}
+
+		if args != nil {
+			span.SetAttributes(attribute.StringSlice(SpanAttrArgs, c.sensitive.args(args)))
+		}
+		if result.started() {
+			span.SetAttributes(
+				attribute.Int(SpanAttrExitCode, result.ExitCode),
+				attribute.Int64(SpanAttrDuration, result.Duration.Milliseconds()),
+			)
+		}
+		if result != nil && result.TimedOut {
+			span.SetAttributes(attribute.Bool(SpanAttrTimedOut, true))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+}
+
+// traceEnviron returns the trace context of ctx as TRACEPARENT and TRACESTATE environment
+// variables in key=value form, or nil if ctx holds no span.
+func traceEnviron(ctx context.Context) []string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	var env []string
+	for _, key := range []string{"traceparent", "tracestate"} {
+		if value := carrier.Get(key); value != "" {
+			env = append(env, strings.ToUpper(key)+"="+value)
+		}
+	}
+
+	return env
+}
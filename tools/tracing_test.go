@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingTracer starts recordingSpans with a fixed span context.
+type recordingTracer struct {
+	noop.Tracer
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{name: name, attrs: map[attribute.Key]attribute.Value{}}
+	span.context = trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	config := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(config.Attributes()...)
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// recordingSpan records its attributes, errors and status.
+type recordingSpan struct {
+	noop.Span
+	name    string
+	context trace.SpanContext
+	attrs   map[attribute.Key]attribute.Value
+	errors  []error
+	status  codes.Code
+	ended   bool
+}
+
+func (s *recordingSpan) SpanContext() trace.SpanContext { return s.context }
+func (s *recordingSpan) End(...trace.SpanEndOption)     { s.ended = true }
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) {
+	s.status = code
+}
+
+func (s *recordingSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.errors = append(s.errors, err)
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...attribute.KeyValue) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+// panickingExecutor panics on every run.
+type panickingExecutor struct{}
+
+func (panickingExecutor) Run(context.Context, Invocation) (*ExecResult, error) {
+	panic("executor bug")
+}
+
+// TestTracer tests that executions are traced and the trace context is passed to the command
+func TestTracer(t *testing.T) {
+	t.Run("failed execution", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		ctrl := helperController(t, "exit")
+		ctrl.tracer = tracer
+
+		request := helperRequest("3")
+		request.Params.Arguments.(map[string]any)[FlagsParam] = map[string]any{"token": "abc"}
+		_, err := ctrl.Execute(context.Background(), request)
+		require.Error(t, err)
+
+		require.Len(t, tracer.spans, 1)
+		span := tracer.spans[0]
+		assert.Equal(t, "helper_exit", span.name)
+		assert.True(t, span.ended)
+		assert.Equal(t, "helper_exit", span.attrs[SpanAttrTool].AsString())
+		assert.Equal(t, []string{"exit", "--token=***", "--", "3"}, span.attrs[SpanAttrArgs].AsStringSlice())
+		assert.Equal(t, int64(3), span.attrs[SpanAttrExitCode].AsInt64())
+		assert.Contains(t, span.attrs, attribute.Key(SpanAttrDuration))
+		assert.Equal(t, codes.Error, span.status)
+		assert.Len(t, span.errors, 1)
+	})
+
+	t.Run("trace context in the environment", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		executor := &recordingExecutor{result: NewExecResult(nil, nil, 0)}
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get"), path: []string{"get"}, executor: executor, tracer: tracer}
+
+		_, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		assert.Contains(t, executor.invocations[0].Env, "TRACEPARENT=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		assert.Equal(t, codes.Unset, tracer.spans[0].status)
+	})
+
+	t.Run("panic ends the span", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get"), executor: panickingExecutor{}, tracer: tracer}
+
+		assert.PanicsWithValue(t, "executor bug", func() {
+			_, _ = ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		})
+		require.Len(t, tracer.spans, 1)
+		assert.True(t, tracer.spans[0].ended)
+		assert.Equal(t, codes.Error, tracer.spans[0].status)
+	})
+
+	t.Run("no span without a tracer", func(t *testing.T) {
+		executor := &recordingExecutor{result: NewExecResult(nil, nil, 0)}
+		ctrl := &Controller{Tool: mcp.NewTool("cli_get"), executor: executor}
+
+		_, err := ctrl.Execute(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		assert.NotContains(t, envNames(executor.invocations[0].Env), "TRACEPARENT")
+	})
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// AnnotationRequiresTTY is a cobra command annotation marking a command that needs an
+// interactive terminal, e.g. because it always prompts for confirmation. Such commands are
+// not generated as tools, since clients cannot answer their prompts. Subcommands are not affected.
+//
+//	cmd.Annotations = map[string]string{tools.AnnotationRequiresTTY: "true"}
+const AnnotationRequiresTTY = "ophis.requiresTTY"
+
+// WithTTYCommands returns a GeneratorOption that marks the commands matched by selector as
+// requiring an interactive terminal, like AnnotationRequiresTTY, for commands that cannot be
+// annotated.
+//
+//	Example: NewGenerator(WithTTYCommands(Allow([]string{"login", "edit"})))
+func WithTTYCommands(selector Filter) GeneratorOption {
+	return func(g *Generator) {
+		g.ttyCommands = append(g.ttyCommands, selector)
+	}
+}
+
+// requiresTTY reports whether cmd is marked as requiring an interactive terminal.
+func (g *Generator) requiresTTY(cmd *cobra.Command) bool {
+	if value, ok := cmd.Annotations[AnnotationRequiresTTY]; ok {
+		requires, err := strconv.ParseBool(value)
+		if err == nil {
+			return requires
+		}
+		g.logger.Warn("ignoring invalid command annotation", "command", cmd.CommandPath(), "annotation", AnnotationRequiresTTY, "value", value)
+	}
+
+	for _, selector := range g.ttyCommands {
+		if selector(cmd) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,28 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequiresTTY tests that commands requiring a terminal are not generated
+func TestRequiresTTY(t *testing.T) {
+	run := func(*cobra.Command, []string) {}
+	root := &cobra.Command{Use: "cli"}
+	login := &cobra.Command{Use: "login", Run: run, Annotations: map[string]string{AnnotationRequiresTTY: "true"}}
+	login.AddCommand(&cobra.Command{Use: "status", Run: run})
+	root.AddCommand(
+		login,
+		&cobra.Command{Use: "edit", Run: run},
+		&cobra.Command{Use: "get", Run: run},
+		&cobra.Command{Use: "list", Run: run, Annotations: map[string]string{AnnotationRequiresTTY: "false"}},
+	)
+
+	var names []string
+	for _, tool := range NewGenerator(WithTTYCommands(Allow([]string{"edit"}))).FromRootCmd(root) {
+		names = append(names, tool.Tool.Name)
+	}
+	assert.ElementsMatch(t, []string{"cli_login_status", "cli_get", "cli_list"}, names)
+}
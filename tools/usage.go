@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// usageErrorPattern matches the errors cobra and pflag print for invalid flags and arguments,
+// e.g. "Error: unknown flag: --foo" or "Error: accepts 1 arg(s), received 2".
+var usageErrorPattern = regexp.MustCompile(`(?m)^Error: (unknown (shorthand )?flag|unknown command|flag needs an argument|invalid argument|bad flag syntax|accepts |requires at least|required flag\(s\)|if any flags in the group|at least one of the flags in the group)`)
+
+// isUsageError reports whether a failed execution was caused by invalid arguments, either
+// rejected before running the command, or by the command itself. A command that printed its
+// usage already is not given it again.
+func isUsageError(result *ExecResult, err error) bool {
+	if errors.Is(err, ErrInvalidArguments) {
+		return true
+	}
+	if result == nil || result.ExitCode <= 0 {
+		return false
+	}
+
+	stderr := string(result.Stderr)
+	return usageErrorPattern.MatchString(stderr) && !strings.Contains(stderr, "Usage:")
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsUsageError tests the detection of failures caused by invalid arguments
+func TestIsUsageError(t *testing.T) {
+	failed := func(stderr string) *ExecResult {
+		return &ExecResult{Stderr: []byte(stderr), ExitCode: 1}
+	}
+
+	tests := []struct {
+		name     string
+		result   *ExecResult
+		err      error
+		expected bool
+	}{
+		{"rejected arguments", nil, fmt.Errorf("%w: unknown flag --bogus", ErrInvalidArguments), true},
+		{"unknown flag", failed("Error: unknown flag: --bogus\n"), errors.New("exit status 1"), true},
+		{"unknown shorthand", failed("Error: unknown shorthand flag: 'x' in -x\n"), errors.New("exit status 1"), true},
+		{"argument count", failed("Error: accepts 1 arg(s), received 2\n"), errors.New("exit status 1"), true},
+		{"required flag", failed("Error: required flag(s) \"name\" not set\n"), errors.New("exit status 1"), true},
+		{"usage already printed", failed("Error: unknown flag: --bogus\nUsage:\n  cli get [flags]\n"), errors.New("exit status 1"), false},
+		{"other failure", failed("Error: connection refused\n"), errors.New("exit status 1"), false},
+		{"timeout", &ExecResult{ExitCode: -1, TimedOut: true}, ErrTimeout, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isUsageError(tt.result, tt.err))
+		})
+	}
+}
+
+// TestUsageOnArgumentErrors tests that argument errors are returned with the usage of the command
+func TestUsageOnArgumentErrors(t *testing.T) {
+	root := &cobra.Command{Use: "cli"}
+	get := &cobra.Command{Use: "get [resource]", Run: func(*cobra.Command, []string) {}}
+	get.Flags().String("output", "", "Output format")
+	root.AddCommand(get)
+
+	executor := &recordingExecutor{}
+	tools := NewGenerator(WithExecutor(executor)).FromRootCmd(root)
+	require.Len(t, tools, 1)
+	ctrl := tools[0]
+
+	call := func(arguments map[string]any) *mcp.CallToolResult {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = arguments
+		result, err := ctrl.Execute(context.Background(), request)
+		toolResult, err := ctrl.Handle(context.Background(), request, result, err)
+		require.NoError(t, err)
+		require.True(t, toolResult.IsError)
+		return toolResult
+	}
+
+	t.Run("rejected arguments", func(t *testing.T) {
+		toolResult := call(map[string]any{FlagsParam: map[string]any{"bogus": "x"}})
+		require.Len(t, toolResult.Content, 2)
+		usage := toolResult.Content[1].(mcp.TextContent).Text
+		assert.Contains(t, usage, "Usage:\n  cli get [resource] [flags]")
+		assert.Contains(t, usage, "--output string")
+	})
+
+	t.Run("rejected by the command", func(t *testing.T) {
+		executor.result = &ExecResult{Stderr: []byte("Error: accepts 1 arg(s), received 2\n"), ExitCode: 1}
+		executor.err = errors.New("exit status 1")
+		toolResult := call(map[string]any{PositionalArgsParam: []any{"a", "b"}})
+		require.Len(t, toolResult.Content, 2)
+		assert.Contains(t, toolResult.Content[1].(mcp.TextContent).Text, "Usage:")
+	})
+
+	t.Run("other failures", func(t *testing.T) {
+		executor.result = &ExecResult{Stderr: []byte("Error: connection refused\n"), ExitCode: 1}
+		toolResult := call(map[string]any{})
+		assert.Len(t, toolResult.Content, 1)
+	})
+}